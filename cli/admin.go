@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/laurawarren88/go_spa_backend.git/config"
+	"github.com/laurawarren88/go_spa_backend.git/database"
+)
+
+func createAdmin(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	flags.SetOutput(out)
+	username := flags.String("username", cfg.Admin.Username, "username of the new admin")
+	email := flags.String("email", cfg.Admin.Email, "email address of the new admin (default: ADMIN_EMAIL)")
+	fromStdin := flags.Bool("password-stdin", false, "read the password from stdin instead of ADMIN_PASSWORD")
+	force := flags.Bool("force", false, "make an existing user with this username or email an admin and replace their password")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 || *email == "" {
+		return errors.New("usage: create-admin -email address [-username name] [-password-stdin] [-force]")
+	}
+
+	password, err := readPassword(cfg, *fromStdin)
+	if err != nil {
+		return err
+	}
+	db, err := openMigrated(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	admin, err := database.CreateAdmin(db, *username, *email, password, *force)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Admin user %s <%s> is ready\n", admin.Username, admin.Email)
+	return nil
+}
+
+func resetPassword(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	flags.SetOutput(out)
+	fromStdin := flags.Bool("password-stdin", false, "read the password from stdin instead of ADMIN_PASSWORD")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: reset-password [-password-stdin] USERNAME|EMAIL")
+	}
+
+	password, err := readPassword(cfg, *fromStdin)
+	if err != nil {
+		return err
+	}
+	db, err := openMigrated(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	user, err := database.SetPassword(db, flags.Arg(0), password)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Password changed for %s <%s>\n", user.Username, user.Email)
+	return nil
+}
+
+// readPassword returns the first line of stdin, or ADMIN_PASSWORD. Passwords
+// are never taken as arguments, where they would show in the process list.
+func readPassword(cfg *config.Config, fromStdin bool) (string, error) {
+	password := cfg.Admin.Password
+	if fromStdin {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return "", errors.New("no password given: use -password-stdin or set ADMIN_PASSWORD")
+	}
+	return password, nil
+}
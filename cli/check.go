@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/laurawarren88/go_spa_backend.git/config"
+	"github.com/laurawarren88/go_spa_backend.git/migrate"
+)
+
+// checkConfig prints the configuration with its secrets redacted, then
+// reports every problem it can find with it, the media storage and the
+// database rather than stopping at the first.
+func checkConfig(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) > 0 {
+		return errors.New("usage: check-config")
+	}
+	fmt.Fprint(out, cfg.String())
+	fmt.Fprintln(out)
+
+	problems := 0
+	report := func(name string, err error) {
+		if err != nil {
+			problems++
+			fmt.Fprintf(out, "FAIL  %s:\n%v\n", name, err)
+			return
+		}
+		fmt.Fprintf(out, "ok    %s\n", name)
+	}
+
+	report("settings", cfg.Validate())
+	_, err := cfg.Storage.OpenStorage()
+	report("storage", err)
+	db, err := connect(cfg)
+	report("database", err)
+	if err == nil {
+		migrator, err := migrate.Default(db)
+		if err == nil {
+			err = migrator.Check(ctx)
+		}
+		report("migrations", err)
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("found %d configuration problems", problems)
+	}
+	return nil
+}
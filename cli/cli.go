@@ -0,0 +1,105 @@
+// Package cli implements the backend's subcommands. Every command loads its
+// configuration the same way, so the image that serves the API can also run
+// one-off jobs such as migrations, imports and purges.
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/laurawarren88/go_spa_backend.git/config"
+	"github.com/laurawarren88/go_spa_backend.git/database"
+	"github.com/laurawarren88/go_spa_backend.git/migrate"
+	"gorm.io/gorm"
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error
+}
+
+var commands = []command{
+	{"serve", "", "start the API server (the default)", serve},
+	{"migrate", "up | down [N] | status | to VERSION", "manage the database schema", runMigrate},
+	{"create-admin", "-email address [-username name] [-password-stdin] [-force]", "add an admin user", createAdmin},
+	{"reset-password", "[-password-stdin] USERNAME|EMAIL", "set a user's password", resetPassword},
+	{"import", "[flags] FILE", "import activities from CSV or GeoJSON", runImport},
+	{"import-osm", "[flags] FILE", "import fitness venues from OpenStreetMap", runImportOSM},
+	{"export", "[-format geojson|csv|kml] [-o FILE]", "export every activity", export},
+	{"purge", "[-retention duration] [-media=false]", "purge expired trash and orphaned media", purge},
+	{"backfill-slugs", "", "give a slug to activities saved before slugs existed", backfillSlugs},
+	{"check-config", "", "check the configuration, storage and database", checkConfig},
+}
+
+// openDB connects to the database. Tests replace it.
+var openDB = database.ConnectToDB
+
+// stdin is where -password-stdin reads from.
+var stdin io.Reader = os.Stdin
+
+// Run loads the configuration and runs the command named by args[0], or the
+// server when there are no arguments.
+func Run(ctx context.Context, args []string, out io.Writer) error {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		return usage(out)
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("loading configuration: %w", err)
+			}
+			return cmd.run(ctx, cfg, args, out)
+		}
+	}
+	usage(out)
+	return fmt.Errorf("unknown command %q", name)
+}
+
+func usage(out io.Writer) error {
+	fmt.Fprintln(out, "Usage: main [command] [arguments]")
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s %s\t%s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	return w.Flush()
+}
+
+// connect validates the database settings and connects.
+func connect(cfg *config.Config) (*gorm.DB, error) {
+	if err := cfg.Database.Validate(); err != nil {
+		return nil, err
+	}
+	db, err := openDB(cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the database: %w", err)
+	}
+	return db, nil
+}
+
+// openMigrated connects to the database and checks that its schema is up to
+// date, so one-off jobs never run against a schema they don't expect.
+func openMigrated(ctx context.Context, cfg *config.Config) (*gorm.DB, error) {
+	db, err := connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := migrate.Default(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrator.Check(ctx); err != nil {
+		return nil, fmt.Errorf("%w; run the migrate command first", err)
+	}
+	return db, nil
+}
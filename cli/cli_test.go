@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/laurawarren88/go_spa_backend.git/config"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "cli.db")), &gorm.Config{})
+	assert.NoError(t, err)
+	for key, value := range map[string]string{
+		"DB_HOST": "localhost", "DB_PORT": "5432", "DB_USER": "fitness",
+		"DB_PASSWORD": "db-secret", "DB_NAME": "fitness", "CONFIG_FILE": "",
+	} {
+		t.Setenv(key, value)
+	}
+	original := openDB
+	openDB = func(config.DatabaseConfig) (*gorm.DB, error) { return db, nil }
+	t.Cleanup(func() { openDB = original })
+	return db
+}
+
+func run(t *testing.T, input string, args ...string) (string, error) {
+	stdin = strings.NewReader(input)
+	var out bytes.Buffer
+	err := Run(context.Background(), args, &out)
+	return out.String(), err
+}
+
+func TestRun(t *testing.T) {
+	db := setupDB(t)
+	t.Setenv("STORAGE_LOCAL_DIR", t.TempDir())
+
+	out, err := run(t, "", "help")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "reset-password")
+	_, err = run(t, "", "frobnicate")
+	assert.EqualError(t, err, `unknown command "frobnicate"`)
+
+	// Jobs refuse to run until the schema has been migrated.
+	_, err = run(t, "long enough to pass\n", "create-admin", "-password-stdin", "-email", "ops@example.com")
+	assert.ErrorContains(t, err, "pending migrations")
+	out, err = run(t, "", "migrate", "up")
+	assert.NoError(t, err)
+	assert.Equal(t, "Applied 0001_baseline\nApplied 0002_activity_features\n", out)
+
+	_, err = run(t, "long enough to pass\n", "create-admin", "-password-stdin")
+	assert.ErrorContains(t, err, "usage")
+	_, err = run(t, "short\n", "create-admin", "-password-stdin", "-email", "ops@example.com")
+	assert.ErrorContains(t, err, "at least 12 characters")
+	out, err = run(t, "long enough to pass\n", "create-admin", "-password-stdin", "-email", "ops@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "Admin user admin <ops@example.com> is ready\n", out)
+	_, err = run(t, "another long password\n", "create-admin", "-password-stdin", "-email", "ops@example.com")
+	assert.ErrorContains(t, err, "already exists")
+	_, err = run(t, "\n", "create-admin", "-password-stdin", "-username", "empty", "-email", "empty@example.com")
+	assert.ErrorContains(t, err, "no password")
+
+	// -force promotes an existing member and replaces their password.
+	assert.NoError(t, db.Create(&models.User{Username: "sam", Email: "sam@example.com", Password: "x"}).Error)
+	t.Setenv("ADMIN_EMAIL", "sam@example.com")
+	_, err = run(t, "taken over by ops\n", "create-admin", "-password-stdin", "-username", "sam")
+	assert.ErrorContains(t, err, "already exists")
+	_, err = run(t, "taken over by ops\n", "create-admin", "-password-stdin", "-username", "sam", "-force")
+	assert.NoError(t, err)
+	var sam models.User
+	assert.NoError(t, db.First(&sam, "username = ?", "sam").Error)
+	assert.True(t, sam.IsAdmin)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(sam.Password), []byte("taken over by ops")))
+
+	t.Setenv("ADMIN_PASSWORD", "from the environment")
+	out, err = run(t, "", "reset-password", "ops@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "Password changed for admin <ops@example.com>\n", out)
+	var admin models.User
+	assert.NoError(t, db.First(&admin, "username = ?", "admin").Error)
+	assert.True(t, admin.IsAdmin)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte("from the environment")))
+	_, err = run(t, "", "reset-password", "nobody")
+	assert.ErrorContains(t, err, `no user named "nobody"`)
+	_, err = run(t, "admin-password-1\n", "reset-password", "-password-stdin", "admin")
+	assert.ErrorContains(t, err, "must not contain the username")
+
+	places := []models.Place{{Name: "Harbour Gym", City: "Manchester"}, {Name: "Binned"}}
+	assert.NoError(t, db.Create(&places).Error)
+	assert.NoError(t, db.Delete(&places[1]).Error)
+	out, err = run(t, "", "export", "-format", "csv")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Harbour Gym")
+	assert.NotContains(t, out, "Binned")
+
+	out, err = run(t, "", "backfill-slugs")
+	assert.NoError(t, err)
+	assert.Equal(t, "Assigned slugs to 2 activities\n", out)
+	var harbour models.Place
+	assert.NoError(t, db.First(&harbour, places[0].ID).Error)
+	assert.Equal(t, "harbour-gym-manchester", harbour.Slug)
+
+	out, err = run(t, "", "purge", "-retention", "0s")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Purged 1 deleted activities\n")
+	var remaining int64
+	db.Unscoped().Model(&models.Place{}).Count(&remaining)
+	assert.Equal(t, int64(1), remaining)
+
+	// check-config reports every problem without revealing secrets.
+	out, err = run(t, "", "check-config")
+	assert.ErrorContains(t, err, "1 configuration problems")
+	assert.Contains(t, out, "password: '[redacted]'")
+	assert.NotContains(t, out, "db-secret")
+	assert.Contains(t, out, "ACCESS_SECRET_KEY is required")
+	assert.Contains(t, out, "ok    migrations")
+}
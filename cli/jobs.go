@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/laurawarren88/go_spa_backend.git/config"
+	"github.com/laurawarren88/go_spa_backend.git/database"
+	"github.com/laurawarren88/go_spa_backend.git/exporter"
+	"github.com/laurawarren88/go_spa_backend.git/importer"
+	"github.com/laurawarren88/go_spa_backend.git/jobs"
+	"github.com/laurawarren88/go_spa_backend.git/migrate"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/slug"
+	"gorm.io/gorm"
+)
+
+func runMigrate(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	db, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	return migrate.Command(ctx, db, args, out)
+}
+
+func runImport(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	db, err := openMigrated(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	// Rows are tagged with the seeded amenities.
+	if err := database.SeedAmenities(db); err != nil {
+		return err
+	}
+	return importer.Command(ctx, db, args, out)
+}
+
+func runImportOSM(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	db, err := openMigrated(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	// Venues are tagged with the seeded amenities.
+	if err := database.SeedAmenities(db); err != nil {
+		return err
+	}
+	return importer.OSMCommand(ctx, db, args, out)
+}
+
+func export(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(out)
+	format := flags.String("format", exporter.FormatGeoJSON, "geojson, csv or kml")
+	path := flags.String("o", "", "file to write (default: standard output)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("usage: export [-format geojson|csv|kml] [-o FILE]")
+	}
+
+	w := out
+	if *path != "" {
+		file, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	writer, err := exporter.NewWriter(strings.ToLower(*format), w)
+	if err != nil {
+		return err
+	}
+	store, err := cfg.Storage.OpenStorage()
+	if err != nil {
+		return err
+	}
+	db, err := openMigrated(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	var batch []models.Place
+	err = db.WithContext(ctx).Preload("Amenities").Order("id").
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for _, place := range batch {
+				if err := writer.Write(exporter.FromPlace(place, store)); err != nil {
+					return err
+				}
+			}
+			return writer.Flush()
+		}).Error
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func purge(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	flags.SetOutput(out)
+	retention := flags.Duration("retention", cfg.Jobs.PlaceRetention, "purge activities deleted longer ago than this")
+	media := flags.Bool("media", true, "also delete uploads nothing refers to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("usage: purge [-retention duration] [-media=false]")
+	}
+
+	store, err := cfg.Storage.OpenStorage()
+	if err != nil {
+		return err
+	}
+	db, err := openMigrated(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	purged, err := jobs.PurgeDeletedPlaces(ctx, db, store, *retention)
+	fmt.Fprintf(out, "Purged %d deleted activities\n", purged)
+	if err != nil {
+		return err
+	}
+	if *media {
+		removed, err := jobs.SweepOrphanedMedia(ctx, db, store, time.Hour)
+		fmt.Fprintf(out, "Removed %d orphaned media files\n", removed)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillSlugs gives a slug to every activity saved before slugs existed.
+// Activities without one are still found by ID, and get a slug when they are
+// next edited, so this only needs to run once after upgrading.
+func backfillSlugs(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) > 0 {
+		return errors.New("usage: backfill-slugs")
+	}
+	db, err := openMigrated(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	count, err := slug.Backfill(db.WithContext(ctx))
+	fmt.Fprintf(out, "Assigned slugs to %d activities\n", count)
+	return err
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/laurawarren88/go_spa_backend.git/config"
+	"github.com/laurawarren88/go_spa_backend.git/database"
+	"github.com/laurawarren88/go_spa_backend.git/jobs"
+	"github.com/laurawarren88/go_spa_backend.git/migrate"
+	"github.com/laurawarren88/go_spa_backend.git/server"
+)
+
+func serve(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) > 0 {
+		return errors.New("usage: serve")
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	db, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+
+	migrator, err := migrate.Default(db)
+	if err != nil {
+		return err
+	}
+	if cfg.MigrateOnStart {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("migrating the database: %w", err)
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+		}
+	} else if err := migrator.Check(ctx); err != nil {
+		return fmt.Errorf("%w; run the migrate command first", err)
+	}
+
+	admin := cfg.Admin
+	if created, err := database.BootstrapAdmin(db, admin.Username, admin.Email, admin.Password); err != nil {
+		log.Printf("Warning: %v", err)
+	} else if created {
+		log.Printf("Created admin user %s <%s>", admin.Username, admin.Email)
+	}
+
+	if err := database.SeedAmenities(db); err != nil {
+		log.Printf("Error seeding amenities: %v", err)
+	}
+
+	store, err := cfg.Storage.OpenStorage()
+	if err != nil {
+		return fmt.Errorf("setting up media storage: %w", err)
+	}
+
+	if interval := cfg.Jobs.MediaSweepInterval; interval > 0 {
+		go jobs.StartMediaSweeper(ctx, db, store, interval, time.Hour)
+	}
+	if interval := cfg.Jobs.PlacePurgeInterval; interval > 0 {
+		go jobs.StartPlacePurger(ctx, db, store, interval, cfg.Jobs.PlaceRetention)
+	}
+
+	router := server.SetupServer(cfg, db, store)
+
+	server.SetupHandlers(router, cfg, db, store)
+
+	log.Printf("Starting the server on port %s", cfg.Port)
+
+	return router.Run(":" + cfg.Port)
+}
@@ -1,61 +1,373 @@
+// Package config loads the backend's settings once at startup into a typed
+// Config that is then passed to whatever needs it. Settings come from, in
+// increasing order of precedence: defaults, an optional YAML file named by
+// CONFIG_FILE, and environment variables, which may be set in the
+// .env.development or .env.production file for the current GO_ENV.
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	"github.com/laurawarren88/go_spa_backend.git/controllers"
-	"github.com/laurawarren88/go_spa_backend.git/middleware"
-	"github.com/laurawarren88/go_spa_backend.git/routes"
-	"gorm.io/gorm"
+	"gopkg.in/yaml.v3"
 )
 
-func LoadEnv() {
-	env := os.Getenv("GO_ENV")
-	var envFile string
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
 
-	if env == "production" {
-		envFile = ".env.production"
-	} else {
-		envFile = ".env.development"
+type Config struct {
+	// Env is "development" or "production", from GO_ENV.
+	Env            string         `yaml:"env"`
+	Port           string         `yaml:"port"`
+	MigrateOnStart bool           `yaml:"migrate_on_start"`
+	Database       DatabaseConfig `yaml:"database"`
+	Auth           AuthConfig     `yaml:"auth"`
+	Cookies        CookieConfig   `yaml:"cookies"`
+	CORS           CORSConfig     `yaml:"cors"`
+	Admin          AdminConfig    `yaml:"admin"`
+	Storage        StorageConfig  `yaml:"storage"`
+	Jobs           JobsConfig     `yaml:"jobs"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+}
+
+// AuthConfig holds the keys that sign access and refresh tokens.
+type AuthConfig struct {
+	AccessSecret  string `yaml:"access_secret"`
+	RefreshSecret string `yaml:"refresh_secret"`
+}
+
+// CookieConfig describes the auth cookies. Domain is a bare host name; a URL
+// such as http://localhost:5050 is reduced to one when loading.
+type CookieConfig struct {
+	Domain   string `yaml:"domain"`
+	Secure   bool   `yaml:"secure"`
+	HTTPOnly bool   `yaml:"http_only"`
+}
+
+// AdminConfig is the account created when the server starts without an admin.
+type AdminConfig struct {
+	Username string `yaml:"username"`
+	Email    string `yaml:"email"`
+	Password string `yaml:"password"`
+}
+
+type StorageConfig struct {
+	// Driver is "local" or "s3".
+	Driver    string   `yaml:"driver"`
+	LocalDir  string   `yaml:"local_dir"`
+	PublicURL string   `yaml:"public_url"`
+	S3        S3Config `yaml:"s3"`
+}
+
+type S3Config struct {
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	PathStyle       bool   `yaml:"path_style"`
+}
+
+// JobsConfig sets how often the background jobs run; an interval of 0
+// disables a job. Deleted activities stay restorable for PlaceRetention.
+type JobsConfig struct {
+	MediaSweepInterval time.Duration `yaml:"media_sweep_interval"`
+	PlacePurgeInterval time.Duration `yaml:"place_purge_interval"`
+	PlaceRetention     time.Duration `yaml:"place_retention"`
+}
+
+// Default returns the settings used when nothing overrides them.
+func Default() *Config {
+	return &Config{
+		Env:            EnvDevelopment,
+		Port:           "8081",
+		MigrateOnStart: true,
+		Cookies:        CookieConfig{HTTPOnly: true},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{
+				"Content-Type", "Content-Length", "Accept-Encoding", "Authorization",
+				"Accept", "Origin", "Cache-Control", "X-Requested-With", "If-Match",
+			},
+			AllowCredentials: true,
+			MaxAge:           12 * time.Hour,
+		},
+		Admin: AdminConfig{Username: "admin"},
+		Storage: StorageConfig{
+			Driver:   "local",
+			LocalDir: "./uploads",
+			S3: S3Config{
+				Endpoint: "https://s3.amazonaws.com",
+				Region:   "eu-west-2",
+			},
+		},
+		Jobs: JobsConfig{
+			MediaSweepInterval: 6 * time.Hour,
+			PlacePurgeInterval: 24 * time.Hour,
+			PlaceRetention:     30 * 24 * time.Hour,
+		},
 	}
+}
 
-	err := godotenv.Load(envFile)
-	if err != nil {
+// Load reads the configuration. It fails on values that cannot be parsed;
+// use Validate to check that the result is complete.
+func Load() (*Config, error) {
+	env := os.Getenv("GO_ENV")
+	if env == "" && os.Getenv("ENV") != "" {
+		env = os.Getenv("ENV")
+		log.Println("Warning: ENV is deprecated, set GO_ENV instead")
+	}
+	envFile := ".env.development"
+	if env == EnvProduction {
+		envFile = ".env.production"
+	}
+	if err := godotenv.Load(envFile); err != nil {
 		log.Printf("Warning: No %s file found, relying on system environment variables", envFile)
 	} else {
 		log.Printf("Loaded environment variables from %s", envFile)
 	}
+
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	// Without an allow-list, the frontend is expected at the cookie domain.
+	if len(cfg.CORS.AllowedOrigins) == 0 && strings.Contains(cfg.Cookies.Domain, "://") {
+		cfg.CORS.AllowedOrigins = []string{strings.TrimSuffix(cfg.Cookies.Domain, "/")}
+	}
+	cfg.Cookies.Domain = cookieDomain(cfg.Cookies.Domain)
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
 }
 
-func SetGinMode() {
-	gin.SetMode(gin.ReleaseMode)
+func (c *Config) loadEnv() error {
+	if env, ok := os.LookupEnv("GO_ENV"); ok {
+		c.Env = env
+	} else if env, ok := os.LookupEnv("ENV"); ok {
+		c.Env = env
+	}
+	// The cookie variables are kept per environment so one env file can
+	// describe both.
+	cookiePrefix := "PROD_"
+	if c.Env == EnvDevelopment {
+		cookiePrefix = "DEV_"
+	}
+
+	vars := []struct {
+		key string
+		dst interface{}
+	}{
+		{"PORT", &c.Port},
+		{"MIGRATE_ON_START", &c.MigrateOnStart},
+		{"DB_HOST", &c.Database.Host},
+		{"DB_PORT", &c.Database.Port},
+		{"DB_USER", &c.Database.User},
+		{"DB_PASSWORD", &c.Database.Password},
+		{"DB_NAME", &c.Database.Name},
+		{"DB_SSLMODE", &c.Database.SSLMode},
+		{"ACCESS_SECRET_KEY", &c.Auth.AccessSecret},
+		{"REFRESH_SECRET_KEY", &c.Auth.RefreshSecret},
+		{cookiePrefix + "DOMAIN", &c.Cookies.Domain},
+		{cookiePrefix + "SECURE_COOKIE", &c.Cookies.Secure},
+		{cookiePrefix + "HTTP_ONLY_COOKIE", &c.Cookies.HTTPOnly},
+		{"CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins},
+		{"CORS_ALLOWED_METHODS", &c.CORS.AllowedMethods},
+		{"CORS_ALLOWED_HEADERS", &c.CORS.AllowedHeaders},
+		{"CORS_ALLOW_CREDENTIALS", &c.CORS.AllowCredentials},
+		{"CORS_MAX_AGE", &c.CORS.MaxAge},
+		{"ADMIN_USERNAME", &c.Admin.Username},
+		{"ADMIN_EMAIL", &c.Admin.Email},
+		{"ADMIN_PASSWORD", &c.Admin.Password},
+		{"STORAGE_DRIVER", &c.Storage.Driver},
+		{"STORAGE_LOCAL_DIR", &c.Storage.LocalDir},
+		{"STORAGE_PUBLIC_URL", &c.Storage.PublicURL},
+		{"S3_ENDPOINT", &c.Storage.S3.Endpoint},
+		{"S3_REGION", &c.Storage.S3.Region},
+		{"S3_BUCKET", &c.Storage.S3.Bucket},
+		{"S3_ACCESS_KEY_ID", &c.Storage.S3.AccessKeyID},
+		{"S3_SECRET_ACCESS_KEY", &c.Storage.S3.SecretAccessKey},
+		{"S3_PATH_STYLE", &c.Storage.S3.PathStyle},
+		{"MEDIA_SWEEP_INTERVAL", &c.Jobs.MediaSweepInterval},
+		{"PLACE_PURGE_INTERVAL", &c.Jobs.PlacePurgeInterval},
+		{"PLACE_RETENTION", &c.Jobs.PlaceRetention},
+	}
+
+	var errs []error
+	for _, v := range vars {
+		value, ok := os.LookupEnv(v.key)
+		if !ok {
+			continue
+		}
+		switch dst := v.dst.(type) {
+		case *string:
+			*dst = value
+		case *[]string:
+			*dst = nil
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*dst = append(*dst, item)
+				}
+			}
+		case *bool:
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not true or false", v.key, value))
+			}
+			*dst = parsed
+		case *time.Duration:
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a duration such as 6h or 30m", v.key, value))
+			}
+			*dst = parsed
+		}
+	}
+	return errors.Join(errs...)
 }
 
-func GetEnv(key string, fallback string) string {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return fallback
+// cookieDomain reduces an origin such as http://localhost:5050 to its host.
+func cookieDomain(origin string) string {
+	if _, rest, ok := strings.Cut(origin, "//"); ok {
+		origin = rest
 	}
-	return value
+	host, _, _ := strings.Cut(origin, ":")
+	return strings.TrimSuffix(host, "/")
 }
 
-func SetupServer() *gin.Engine {
-	router := gin.Default()
-	router.Static("/images", "./images")
-	router.Use(middleware.DBMiddleware())
-	router.Use(middleware.CORSMiddleware())
-	return router
+// Validate reports every missing or invalid setting the server needs.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		errs = append(errs, fmt.Errorf("GO_ENV must be %q or %q, not %q", EnvDevelopment, EnvProduction, c.Env))
+	}
+	if _, err := strconv.ParseUint(c.Port, 10, 16); err != nil {
+		errs = append(errs, fmt.Errorf("PORT must be a port number, not %q", c.Port))
+	}
+	if c.Auth.AccessSecret == "" {
+		errs = append(errs, errors.New("ACCESS_SECRET_KEY is required to sign access tokens"))
+	}
+	if c.Auth.RefreshSecret == "" {
+		errs = append(errs, errors.New("REFRESH_SECRET_KEY is required to sign refresh tokens"))
+	} else if c.Auth.RefreshSecret == c.Auth.AccessSecret {
+		errs = append(errs, errors.New("REFRESH_SECRET_KEY must differ from ACCESS_SECRET_KEY"))
+	}
+	if c.Jobs.MediaSweepInterval < 0 || c.Jobs.PlacePurgeInterval < 0 {
+		errs = append(errs, errors.New("job intervals cannot be negative"))
+	}
+	if c.Jobs.PlaceRetention <= 0 {
+		errs = append(errs, errors.New("PLACE_RETENTION must be positive"))
+	}
+	errs = append(errs, c.Database.Validate(), c.Storage.Validate(), c.CORS.Validate())
+	return errors.Join(errs...)
 }
 
-func SetupHandlers(router *gin.Engine, db *gorm.DB) {
-	homeController := controllers.NewHomeController(db)
-	placeController := controllers.NewPlaceController(db)
-	userController := controllers.NewUserController(db)
+// Validate reports the connection settings that are missing.
+func (d DatabaseConfig) Validate() error {
+	var missing []string
+	for _, setting := range []struct{ key, value string }{
+		{"DB_HOST", d.Host}, {"DB_PORT", d.Port}, {"DB_USER", d.User},
+		{"DB_PASSWORD", d.Password}, {"DB_NAME", d.Name},
+	} {
+		if setting.value == "" {
+			missing = append(missing, setting.key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("database settings missing: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// DSN is the connection string for the postgres driver.
+func (d DatabaseConfig) DSN() string {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s", d.Host, d.User, d.Password, d.Name, d.Port)
+	if d.SSLMode != "" {
+		dsn += " sslmode=" + d.SSLMode
+	}
+	return dsn
+}
 
-	routes.RegisterHomeRoutes(router, homeController)
-	routes.RegisterPlaceRoutes(router, placeController)
-	routes.RegisterUserRoutes(router, userController)
+// Validate checks that the selected storage driver is fully configured.
+func (s StorageConfig) Validate() error {
+	switch s.Driver {
+	case "local":
+		if s.LocalDir == "" {
+			return errors.New("STORAGE_LOCAL_DIR is required for local storage")
+		}
+	case "s3":
+		var missing []string
+		for _, setting := range []struct{ key, value string }{
+			{"S3_BUCKET", s.S3.Bucket}, {"S3_ACCESS_KEY_ID", s.S3.AccessKeyID},
+			{"S3_SECRET_ACCESS_KEY", s.S3.SecretAccessKey},
+		} {
+			if setting.value == "" {
+				missing = append(missing, setting.key)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("s3 storage settings missing: %s", strings.Join(missing, ", "))
+		}
+	default:
+		return fmt.Errorf("STORAGE_DRIVER must be local or s3, not %q", s.Driver)
+	}
+	return nil
+}
+
+const redacted = "[redacted]"
+
+// Redacted returns a copy with passwords and keys masked.
+func (c Config) Redacted() Config {
+	for _, secret := range []*string{
+		&c.Database.Password, &c.Auth.AccessSecret, &c.Auth.RefreshSecret,
+		&c.Admin.Password, &c.Storage.S3.SecretAccessKey,
+	} {
+		if *secret != "" {
+			*secret = redacted
+		}
+	}
+	return c
+}
+
+// String renders the configuration as YAML with secrets redacted, in the
+// form CONFIG_FILE accepts.
+func (c Config) String() string {
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
 }
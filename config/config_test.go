@@ -0,0 +1,173 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/laurawarren88/go_spa_backend.git/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("GO_ENV", "production")
+	t.Setenv("CONFIG_FILE", writeFile(t, `
+port: "9000"
+database:
+  host: db.internal
+  name: fitness
+  password: from-the-file
+jobs:
+  place_retention: 168h
+cookies:
+  secure: true
+`))
+	// The environment wins over the file.
+	t.Setenv("DB_PASSWORD", "from-the-env")
+	t.Setenv("PROD_DOMAIN", "https://fitness.example.com:443")
+	t.Setenv("PROD_HTTP_ONLY_COOKIE", "false")
+	t.Setenv("MEDIA_SWEEP_INTERVAL", "0")
+
+	cfg, err := config.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "production", cfg.Env)
+	assert.Equal(t, "9000", cfg.Port)
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, "from-the-env", cfg.Database.Password)
+	assert.Equal(t, config.CookieConfig{Domain: "fitness.example.com", Secure: true, HTTPOnly: false}, cfg.Cookies)
+	assert.Equal(t, 7*24*time.Hour, cfg.Jobs.PlaceRetention)
+	assert.Equal(t, time.Duration(0), cfg.Jobs.MediaSweepInterval)
+	assert.Equal(t, 24*time.Hour, cfg.Jobs.PlacePurgeInterval)
+	assert.Equal(t, "local", cfg.Storage.Driver)
+	// Without an allow-list the cookie domain's origin is allowed.
+	assert.Equal(t, []string{"https://fitness.example.com:443"}, cfg.CORS.AllowedOrigins)
+	assert.True(t, cfg.CORS.AllowOrigin("https://fitness.example.com"))
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", " https://fitness.example.com, https://*.staging.example.com ,")
+	t.Setenv("CORS_ALLOWED_METHODS", "GET,POST")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "false")
+	t.Setenv("CORS_MAX_AGE", "10m")
+	cfg, err = config.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, config.CORSConfig{
+		AllowedOrigins: []string{"https://fitness.example.com", "https://*.staging.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: config.Default().CORS.AllowedHeaders,
+		MaxAge:         10 * time.Minute,
+	}, cfg.CORS)
+
+	t.Setenv("S3_PATH_STYLE", "sometimes")
+	t.Setenv("PLACE_RETENTION", "a month")
+	_, err = config.Load()
+	assert.ErrorContains(t, err, "S3_PATH_STYLE")
+	assert.ErrorContains(t, err, "PLACE_RETENTION")
+
+	t.Setenv("CONFIG_FILE", writeFile(t, "databse:\n  host: typo\n"))
+	_, err = config.Load()
+	assert.ErrorContains(t, err, "databse")
+}
+
+func TestValidate(t *testing.T) {
+	cfg := config.Default()
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "ACCESS_SECRET_KEY is required")
+	assert.ErrorContains(t, err, "REFRESH_SECRET_KEY is required")
+	assert.ErrorContains(t, err, "database settings missing: DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME")
+
+	cfg.Auth = config.AuthConfig{AccessSecret: "same", RefreshSecret: "same"}
+	cfg.Database = config.DatabaseConfig{Host: "db", Port: "5432", User: "u", Password: "p", Name: "n"}
+	cfg.Env = "staging"
+	cfg.Storage.Driver = "s3"
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, "must differ")
+	assert.ErrorContains(t, err, `GO_ENV must be "development" or "production", not "staging"`)
+	assert.ErrorContains(t, err, "S3_BUCKET, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY")
+
+	cfg.Auth.RefreshSecret = "different"
+	cfg.Env = config.EnvDevelopment
+	cfg.Storage.Driver = "local"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestCORSValidate(t *testing.T) {
+	cors := config.Default().CORS
+	cors.AllowedOrigins = []string{"http://localhost:5050", "https://*.example.com"}
+	assert.NoError(t, cors.Validate())
+
+	cors.AllowedOrigins = []string{"*"}
+	assert.ErrorContains(t, cors.Validate(), "cannot be * while CORS_ALLOW_CREDENTIALS is true")
+	cors.AllowCredentials = false
+	assert.NoError(t, cors.Validate())
+
+	cors.AllowedOrigins = []string{
+		"*",
+		"localhost:5050",
+		"https://example.com/app",
+		"https://*.com",
+		"https://app.*.example.com",
+	}
+	cors.AllowedMethods = []string{"GET", "FETCH"}
+	cors.AllowedHeaders = []string{"Authorization", "X Bad"}
+	cors.MaxAge = -time.Second
+	err := cors.Validate()
+	for _, want := range []string{
+		"cannot mix * with other origins",
+		`"localhost:5050" must look like`,
+		`"https://example.com/app" must not have a path`,
+		`"https://*.com" would match every subdomain`,
+		`"https://app.*.example.com" may only use *`,
+		`unknown method "FETCH"`,
+		`"X Bad" is not a header name`,
+		"CORS_MAX_AGE cannot be negative",
+	} {
+		assert.ErrorContains(t, err, want)
+	}
+
+	cors.AllowedMethods = nil
+	assert.ErrorContains(t, cors.Validate(), "CORS_ALLOWED_METHODS cannot be empty")
+}
+
+func TestCORSAllowOrigin(t *testing.T) {
+	cors := config.CORSConfig{AllowedOrigins: []string{"http://localhost:5050", "https://*.example.com", "https://app.test:443"}}
+	for origin, allowed := range map[string]bool{
+		"http://localhost:5050":      true,
+		"http://LOCALHOST:5050":      true,
+		"http://localhost:5051":      false,
+		"https://localhost:5050":     false,
+		"https://a.example.com":      true,
+		"https://a.b.example.com":    true,
+		"https://example.com":        false,
+		"https://.example.com":       false,
+		"https://evilexample.com":    false,
+		"https://a.example.com:8443": false,
+		"https://app.test":           true,
+		"https://a.example.com/path": false,
+		"null":                       false,
+	} {
+		assert.Equal(t, allowed, cors.AllowOrigin(origin), origin)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Password = "hunter2-db"
+	cfg.Auth.AccessSecret = "access-key"
+	cfg.Storage.S3.SecretAccessKey = "s3-key"
+
+	printed := cfg.String()
+	for _, secret := range []string{"hunter2-db", "access-key", "s3-key"} {
+		assert.NotContains(t, printed, secret)
+	}
+	assert.Equal(t, 3, strings.Count(printed, "[redacted]"))
+	assert.Contains(t, printed, "place_retention: 720h0m0s")
+	// Redacting works on a copy.
+	assert.Equal(t, "hunter2-db", cfg.Database.Password)
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CORSConfig lists who may call the API from a browser on another origin.
+// An origin is a scheme and host with an optional port, such as
+// https://fitness.example.com; https://*.example.com matches any subdomain
+// of example.com but not example.com itself. "*" allows every origin and
+// cannot be combined with credentials.
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+var corsMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true,
+	"PATCH": true, "DELETE": true, "OPTIONS": true,
+}
+
+type originPattern struct {
+	scheme   string
+	host     string
+	port     string
+	wildcard bool
+}
+
+func parseOriginPattern(value string) (originPattern, error) {
+	u, err := url.Parse(strings.ToLower(value))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return originPattern{}, fmt.Errorf("origin %q must look like https://app.example.com or https://*.example.com", value)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return originPattern{}, fmt.Errorf("origin %q must not have a path, query or user", value)
+	}
+
+	pattern := originPattern{scheme: u.Scheme, host: u.Hostname(), port: originPort(u)}
+	if rest, ok := strings.CutPrefix(pattern.host, "*."); ok {
+		pattern.host, pattern.wildcard = rest, true
+		if !strings.Contains(rest, ".") {
+			return originPattern{}, fmt.Errorf("origin %q would match every subdomain of a top-level domain", value)
+		}
+	}
+	if pattern.host == "" || strings.Contains(pattern.host, "*") {
+		return originPattern{}, fmt.Errorf("origin %q may only use * as its first label, as in https://*.example.com", value)
+	}
+	return pattern, nil
+}
+
+// originPort returns the port, leaving it empty when it is the scheme's
+// default, as browsers do in the Origin header.
+func originPort(u *url.URL) string {
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		return ""
+	}
+	return port
+}
+
+func (p originPattern) matches(origin *url.URL) bool {
+	if origin.Scheme != p.scheme || originPort(origin) != p.port {
+		return false
+	}
+	host := origin.Hostname()
+	if p.wildcard {
+		return len(host) > len(p.host)+1 && strings.HasSuffix(host, "."+p.host)
+	}
+	return host == p.host
+}
+
+// AllowsAnyOrigin reports whether the allow-list is "*".
+func (c CORSConfig) AllowsAnyOrigin() bool {
+	return len(c.AllowedOrigins) == 1 && c.AllowedOrigins[0] == "*"
+}
+
+// AllowOrigin reports whether a browser at origin may call the API. Call
+// Validate first; invalid patterns match nothing.
+func (c CORSConfig) AllowOrigin(origin string) bool {
+	if c.AllowsAnyOrigin() {
+		return true
+	}
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return false
+	}
+	for _, value := range c.AllowedOrigins {
+		pattern, err := parseOriginPattern(value)
+		if err == nil && pattern.matches(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks every origin pattern, method and header.
+func (c CORSConfig) Validate() error {
+	var errs []error
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if len(c.AllowedOrigins) > 1 {
+				errs = append(errs, errors.New("CORS_ALLOWED_ORIGINS cannot mix * with other origins"))
+			}
+			if c.AllowCredentials {
+				errs = append(errs, errors.New("CORS_ALLOWED_ORIGINS cannot be * while CORS_ALLOW_CREDENTIALS is true; list the origins instead"))
+			}
+			continue
+		}
+		if _, err := parseOriginPattern(origin); err != nil {
+			errs = append(errs, fmt.Errorf("CORS_ALLOWED_ORIGINS: %w", err))
+		}
+	}
+	if len(c.AllowedMethods) == 0 {
+		errs = append(errs, errors.New("CORS_ALLOWED_METHODS cannot be empty"))
+	}
+	for _, method := range c.AllowedMethods {
+		if !corsMethods[strings.ToUpper(method)] {
+			errs = append(errs, fmt.Errorf("CORS_ALLOWED_METHODS: unknown method %q", method))
+		}
+	}
+	for _, header := range c.AllowedHeaders {
+		if header == "" || strings.ContainsAny(header, " \t,:;\"()/<>?@[\\]{}=") {
+			errs = append(errs, fmt.Errorf("CORS_ALLOWED_HEADERS: %q is not a header name", header))
+		}
+	}
+	if c.MaxAge < 0 {
+		errs = append(errs, errors.New("CORS_MAX_AGE cannot be negative"))
+	}
+	return errors.Join(errs...)
+}
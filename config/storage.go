@@ -0,0 +1,29 @@
+package config
+
+import (
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+)
+
+// OpenStorage builds the media store selected by Driver: "local" keeps
+// uploads under LocalDir, "s3" uses an S3-compatible bucket.
+func (s StorageConfig) OpenStorage() (storage.BlobStore, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if s.Driver == "local" {
+		publicURL := s.PublicURL
+		if publicURL == "" {
+			publicURL = "/uploads"
+		}
+		return storage.NewLocal(s.LocalDir, publicURL), nil
+	}
+	return storage.NewS3(storage.S3Config{
+		Endpoint:  s.S3.Endpoint,
+		Region:    s.S3.Region,
+		Bucket:    s.S3.Bucket,
+		AccessKey: s.S3.AccessKeyID,
+		SecretKey: s.S3.SecretAccessKey,
+		PathStyle: s.S3.PathStyle,
+		PublicURL: s.PublicURL,
+	})
+}
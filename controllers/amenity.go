@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"gorm.io/gorm"
+)
+
+var amenitySlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type AmenityController struct {
+	DB *gorm.DB
+}
+
+func NewAmenityController(db *gorm.DB) *AmenityController {
+	return &AmenityController{DB: db}
+}
+
+// parseAmenityList flattens comma separated amenity slugs from one or more
+// values into a de-duplicated, lower-cased list.
+func parseAmenityList(values []string) []string {
+	seen := map[string]bool{}
+	slugs := []string{}
+	for _, value := range values {
+		for _, slug := range strings.Split(value, ",") {
+			slug = strings.ToLower(strings.TrimSpace(slug))
+			if slug == "" || seen[slug] {
+				continue
+			}
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs
+}
+
+// resolveAmenities looks up the vocabulary entries for slugs and fails if any
+// of them are not part of the managed vocabulary.
+func resolveAmenities(db *gorm.DB, slugs []string) ([]models.Amenity, error) {
+	amenities := []models.Amenity{}
+	if len(slugs) == 0 {
+		return amenities, nil
+	}
+	if err := db.Where("slug IN ?", slugs).Order("slug").Find(&amenities).Error; err != nil {
+		return nil, err
+	}
+	if len(amenities) == len(slugs) {
+		return amenities, nil
+	}
+
+	known := map[string]bool{}
+	for _, amenity := range amenities {
+		known[amenity.Slug] = true
+	}
+	var unknown []string
+	for _, slug := range slugs {
+		if !known[slug] {
+			unknown = append(unknown, slug)
+		}
+	}
+	return nil, fmt.Errorf("unknown amenities: %s", strings.Join(unknown, ", "))
+}
+
+// matchesAmenities reports whether place has all (or, with matchAny, at least
+// one) of the requested amenity slugs.
+func matchesAmenities(place models.Place, slugs []string, matchAny bool) bool {
+	if len(slugs) == 0 {
+		return true
+	}
+	has := map[string]bool{}
+	for _, amenity := range place.Amenities {
+		has[amenity.Slug] = true
+	}
+	for _, slug := range slugs {
+		if has[slug] && matchAny {
+			return true
+		}
+		if !has[slug] && !matchAny {
+			return false
+		}
+	}
+	return !matchAny
+}
+
+type amenityFacet struct {
+	Slug     string `json:"slug"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// amenityFacets counts how many of places offer each amenity in the
+// vocabulary, so clients can render filter chips with result counts.
+func amenityFacets(db *gorm.DB, places []models.Place) ([]amenityFacet, error) {
+	var vocabulary []models.Amenity
+	if err := db.Order("category, name").Find(&vocabulary).Error; err != nil {
+		return nil, err
+	}
+
+	counts := map[uint]int{}
+	for _, place := range places {
+		for _, amenity := range place.Amenities {
+			counts[amenity.ID]++
+		}
+	}
+
+	facets := make([]amenityFacet, 0, len(vocabulary))
+	for _, amenity := range vocabulary {
+		facets = append(facets, amenityFacet{
+			Slug:     amenity.Slug,
+			Name:     amenity.Name,
+			Category: amenity.Category,
+			Count:    counts[amenity.ID],
+		})
+	}
+	sort.SliceStable(facets, func(i, j int) bool {
+		return facets[i].Count > facets[j].Count
+	})
+	return facets, nil
+}
+
+func (ac *AmenityController) GetAmenities(ctx *gin.Context) {
+	var amenities []models.Amenity
+	if err := ac.DB.Order("category, name").Find(&amenities).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve amenities"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"amenities": amenities,
+		"total":     len(amenities),
+	})
+}
+
+type amenityInput struct {
+	Slug     string `json:"slug"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+func (input amenityInput) validate() string {
+	if !amenitySlugPattern.MatchString(input.Slug) || len(input.Slug) > 50 {
+		return "Slug must be lower-case words separated by hyphens"
+	}
+	if input.Name == "" || len(input.Name) > 100 {
+		return "Name is required and must be at most 100 characters"
+	}
+	if len(input.Category) > 50 {
+		return "Category must be at most 50 characters"
+	}
+	return ""
+}
+
+func (ac *AmenityController) CreateAmenity(ctx *gin.Context) {
+	var input amenityInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input: " + err.Error()})
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var count int64
+	if err := ac.DB.Model(&models.Amenity{}).Where("slug = ?", input.Slug).Count(&count).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create amenity"})
+		return
+	}
+	if count > 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "An amenity with that slug already exists"})
+		return
+	}
+
+	amenity := models.Amenity{Slug: input.Slug, Name: input.Name, Category: input.Category}
+	if err := ac.DB.Create(&amenity).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create amenity"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Amenity created successfully",
+		"amenity": amenity,
+	})
+}
+
+func (ac *AmenityController) UpdateAmenity(ctx *gin.Context) {
+	var amenity models.Amenity
+	if err := ac.DB.First(&amenity, "id = ?", ctx.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Amenity not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve amenity"})
+		}
+		return
+	}
+
+	var input amenityInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input: " + err.Error()})
+		return
+	}
+	if input.Slug == "" {
+		input.Slug = amenity.Slug
+	}
+	if msg := input.validate(); msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var count int64
+	if err := ac.DB.Model(&models.Amenity{}).Where("slug = ? AND id <> ?", input.Slug, amenity.ID).Count(&count).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update amenity"})
+		return
+	}
+	if count > 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "An amenity with that slug already exists"})
+		return
+	}
+
+	amenity.Slug = input.Slug
+	amenity.Name = input.Name
+	amenity.Category = input.Category
+	if err := ac.DB.Save(&amenity).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update amenity"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Amenity updated successfully",
+		"amenity": amenity,
+	})
+}
+
+// DeleteAmenity removes an amenity from the vocabulary and from every place
+// tagged with it. The row is deleted outright so its slug can be reused.
+func (ac *AmenityController) DeleteAmenity(ctx *gin.Context) {
+	var amenity models.Amenity
+	if err := ac.DB.First(&amenity, "id = ?", ctx.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Amenity not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve amenity"})
+		}
+		return
+	}
+
+	err := ac.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM place_amenities WHERE amenity_id = ?", amenity.ID).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&amenity).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete amenity"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Amenity deleted successfully"})
+}
@@ -0,0 +1,154 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestAmenityFiltersAndFacets(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	vocabulary := []models.Amenity{
+		{Slug: "pool", Name: "Swimming pool"},
+		{Slug: "parking", Name: "Parking"},
+		{Slug: "sauna", Name: "Sauna"},
+	}
+	assert.NoError(t, db.Create(&vocabulary).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, newTestStore(t))
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/activities/new", controller.CreateActivity)
+	r.PUT("/activities/:id/edit", controller.UpdateActivity)
+	r.GET("/locator", controller.GetPlaceLocator)
+
+	create := func(name string, amenities []string) int {
+		body, _ := json.Marshal(map[string]any{
+			"name": name, "phone": "0161 000 0000", "description": "Gym",
+			"latitude": 53.48, "longitude": -2.24, "amenities": amenities,
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/activities/new", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, create("Pool and Parking", []string{"pool", "parking"}))
+	assert.Equal(t, http.StatusCreated, create("Pool Only", []string{"POOL"}))
+	assert.Equal(t, http.StatusCreated, create("Sauna Only", []string{"sauna"}))
+	assert.Equal(t, http.StatusBadRequest, create("Unknown", []string{"helipad"}))
+
+	type locatorResponse struct {
+		Places []models.Place `json:"places"`
+		Facets struct {
+			Amenities []struct {
+				Slug  string `json:"slug"`
+				Count int    `json:"count"`
+			} `json:"amenities"`
+		} `json:"facets"`
+	}
+	locate := func(query string) locatorResponse {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/locator?lat=53.48&lng=-2.24&radius=1000"+query, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response locatorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	names := func(response locatorResponse) []string {
+		var result []string
+		for _, place := range response.Places {
+			result = append(result, place.Name)
+		}
+		return result
+	}
+
+	all := locate("&amenities=pool,parking")
+	assert.Equal(t, []string{"Pool and Parking"}, names(all))
+
+	any := locate("&amenities=pool,sauna&amenities_match=any")
+	assert.Equal(t, []string{"Pool and Parking", "Pool Only", "Sauna Only"}, names(any))
+	counts := map[string]int{}
+	for _, facet := range any.Facets.Amenities {
+		counts[facet.Slug] = facet.Count
+	}
+	assert.Equal(t, map[string]int{"pool": 2, "parking": 1, "sauna": 1}, counts)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("amenities", "sauna")
+	writer.Close()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/activities/3/edit", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("If-Match", "*")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Place 3 is "Pool Only", which now only offers a sauna.
+	assert.Equal(t, []string{"Pool Only", "Sauna Only"}, names(locate("&amenities=sauna")))
+	assert.Equal(t, []string{"Pool and Parking"}, names(locate("&amenities=pool")))
+}
+
+func TestUpdateAmenityRejectsDuplicateSlug(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	vocabulary := []models.Amenity{
+		{Slug: "pool", Name: "Swimming pool"},
+		{Slug: "sauna", Name: "Sauna"},
+	}
+	assert.NoError(t, db.Create(&vocabulary).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewAmenityController(db)
+	r.PUT("/admin/amenities/:id", controller.UpdateAmenity)
+
+	update := func(id uint, slug string) int {
+		body, _ := json.Marshal(map[string]any{"slug": slug, "name": "Sauna"})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/admin/amenities/%d", id), bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusConflict, update(vocabulary[1].ID, "pool"))
+	assert.Equal(t, http.StatusOK, update(vocabulary[1].ID, "sauna"))
+	assert.Equal(t, http.StatusOK, update(vocabulary[1].ID, "steam-room"))
+
+	var amenity models.Amenity
+	assert.NoError(t, db.First(&amenity, vocabulary[0].ID).Error)
+	assert.Equal(t, "pool", amenity.Slug)
+
+	// A failed slug check is not mistaken for a free slug.
+	db.Callback().Query().Before("gorm:query").Register("test:fail", func(tx *gorm.DB) {
+		if _, counting := tx.Statement.Dest.(*int64); counting {
+			tx.AddError(errors.New("database unavailable"))
+		}
+	})
+	assert.Equal(t, http.StatusInternalServerError, update(vocabulary[1].ID, "spa"))
+}
@@ -0,0 +1,404 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errSessionNotScheduled = errors.New("class has no session at that time")
+	errSessionStarted      = errors.New("session has already started")
+	errAlreadyBooked       = errors.New("already booked on this session")
+)
+
+type BookingController struct {
+	DB *gorm.DB
+}
+
+func NewBookingController(db *gorm.DB) *BookingController {
+	return &BookingController{DB: db}
+}
+
+func currentUserID(ctx *gin.Context) (uint, bool) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return 0, false
+	}
+	userIDUint, ok := userID.(uint)
+	if !ok {
+		log.Printf("Failed to convert userID to uint: %v", userID)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return 0, false
+	}
+	return userIDUint, true
+}
+
+// lockSession loads a session inside tx with a row lock so capacity checks and
+// waitlist promotion for the same session are serialised across requests.
+func lockSession(tx *gorm.DB, sessionID uint) (models.ClassSession, error) {
+	var session models.ClassSession
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&session, sessionID).Error
+	return session, err
+}
+
+// findOrCreateSession returns the stored session of class starting at
+// startsAt, creating it when this is the first booking for that occurrence.
+func findOrCreateSession(tx *gorm.DB, class models.Class, startsAt time.Time) (models.ClassSession, error) {
+	var session models.ClassSession
+	occurrences := expandClassSessions(class, startsAt, startsAt.Add(time.Second))
+	if len(occurrences) == 0 {
+		return session, errSessionNotScheduled
+	}
+
+	session = models.ClassSession{
+		ClassID:  class.ID,
+		StartsAt: occurrences[0].StartsAt.UTC(),
+		EndsAt:   occurrences[0].EndsAt.UTC(),
+		Capacity: class.Capacity,
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "class_id"}, {Name: "starts_at"}},
+		DoNothing: true,
+	}).Create(&session).Error
+	if err != nil {
+		return session, err
+	}
+
+	var stored models.ClassSession
+	err = tx.Where("class_id = ? AND starts_at = ?", class.ID, session.StartsAt).First(&stored).Error
+	return stored, err
+}
+
+func countConfirmed(tx *gorm.DB, sessionID uint) (int64, error) {
+	var count int64
+	err := tx.Model(&models.Booking{}).
+		Where("session_id = ? AND status = ?", sessionID, models.BookingConfirmed).
+		Count(&count).Error
+	return count, err
+}
+
+// bookSession reserves a place for userID on the session of class starting at
+// startsAt, or joins the waitlist when the session is full.
+func bookSession(db *gorm.DB, class models.Class, startsAt time.Time, userID uint) (models.Booking, error) {
+	var booking models.Booking
+	err := db.Transaction(func(tx *gorm.DB) error {
+		session, err := findOrCreateSession(tx, class, startsAt)
+		if err != nil {
+			return err
+		}
+		if !session.StartsAt.After(time.Now()) {
+			return errSessionStarted
+		}
+
+		session, err = lockSession(tx, session.ID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("session_id = ? AND user_id = ?", session.ID, userID).First(&booking).Error
+		if err == nil && booking.Status != models.BookingCancelled {
+			return errAlreadyBooked
+		}
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		confirmed, err := countConfirmed(tx, session.ID)
+		if err != nil {
+			return err
+		}
+
+		booking.SessionID = session.ID
+		booking.UserID = userID
+		booking.QueuedAt = time.Now()
+		booking.Status = models.BookingConfirmed
+		if confirmed >= int64(session.Capacity) {
+			booking.Status = models.BookingWaitlisted
+		}
+		if err := tx.Save(&booking).Error; err != nil {
+			return err
+		}
+		booking.Session = session
+		return nil
+	})
+	return booking, err
+}
+
+// cancelBooking cancels the booking and, when it held a confirmed place,
+// promotes the longest-waiting user on the waitlist into it.
+func cancelBooking(db *gorm.DB, booking *models.Booking) (*models.Booking, error) {
+	var promoted *models.Booking
+	err := db.Transaction(func(tx *gorm.DB) error {
+		session, err := lockSession(tx, booking.SessionID)
+		if err != nil {
+			return err
+		}
+		if err := tx.First(booking, booking.ID).Error; err != nil {
+			return err
+		}
+		if booking.Status == models.BookingCancelled {
+			return nil
+		}
+
+		wasConfirmed := booking.Status == models.BookingConfirmed
+		booking.Status = models.BookingCancelled
+		if err := tx.Save(booking).Error; err != nil {
+			return err
+		}
+		if !wasConfirmed {
+			return nil
+		}
+
+		confirmed, err := countConfirmed(tx, session.ID)
+		if err != nil {
+			return err
+		}
+		if confirmed >= int64(session.Capacity) {
+			return nil
+		}
+
+		var next models.Booking
+		err = tx.Where("session_id = ? AND status = ?", session.ID, models.BookingWaitlisted).
+			Order("queued_at, id").First(&next).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		next.Status = models.BookingConfirmed
+		if err := tx.Save(&next).Error; err != nil {
+			return err
+		}
+		promoted = &next
+		return nil
+	})
+	return promoted, err
+}
+
+type sessionKey struct {
+	classID  uint
+	startsAt int64
+}
+
+// sessionUsage is the capacity and booking counts of a stored session.
+type sessionUsage struct {
+	Capacity   int
+	Booked     int
+	Waitlisted int
+}
+
+// sessionUsages returns the usage of every stored session of the given
+// classes starting within [from, to). A session keeps the capacity its class
+// had when it was first booked.
+func sessionUsages(db *gorm.DB, classIDs []uint, from, to time.Time) (map[sessionKey]sessionUsage, error) {
+	usages := map[sessionKey]sessionUsage{}
+	if len(classIDs) == 0 {
+		return usages, nil
+	}
+
+	var rows []struct {
+		ClassID    uint
+		StartsAt   time.Time
+		Capacity   int
+		Booked     int
+		Waitlisted int
+	}
+	err := db.Model(&models.ClassSession{}).
+		Select("class_sessions.class_id, class_sessions.starts_at, class_sessions.capacity, "+
+			"COUNT(CASE WHEN bookings.status = ? THEN 1 END) AS booked, "+
+			"COUNT(CASE WHEN bookings.status = ? THEN 1 END) AS waitlisted",
+			models.BookingConfirmed, models.BookingWaitlisted).
+		Joins("LEFT JOIN bookings ON bookings.session_id = class_sessions.id AND bookings.deleted_at IS NULL").
+		Where("class_sessions.class_id IN ? AND class_sessions.starts_at >= ? AND class_sessions.starts_at < ?",
+			classIDs, from.UTC(), to.UTC()).
+		Group("class_sessions.id, class_sessions.class_id, class_sessions.starts_at, class_sessions.capacity").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		usages[sessionKey{row.ClassID, row.StartsAt.Unix()}] = sessionUsage{
+			Capacity: row.Capacity, Booked: row.Booked, Waitlisted: row.Waitlisted,
+		}
+	}
+	return usages, nil
+}
+
+// waitlistPositions returns the 1-based waitlist position of each of the
+// given waitlisted bookings.
+func waitlistPositions(db *gorm.DB, bookingIDs []uint) (map[uint]int64, error) {
+	positions := map[uint]int64{}
+	if len(bookingIDs) == 0 {
+		return positions, nil
+	}
+
+	var rows []struct {
+		ID    uint
+		Ahead int64
+	}
+	err := db.Table("bookings AS mine").
+		Select("mine.id, COUNT(ahead.id) AS ahead").
+		Joins("LEFT JOIN bookings AS ahead ON ahead.session_id = mine.session_id AND ahead.status = ? AND ahead.deleted_at IS NULL "+
+			"AND (ahead.queued_at < mine.queued_at OR (ahead.queued_at = mine.queued_at AND ahead.id < mine.id))",
+			models.BookingWaitlisted).
+		Where("mine.id IN ?", bookingIDs).
+		Group("mine.id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		positions[row.ID] = row.Ahead + 1
+	}
+	return positions, nil
+}
+
+func (bc *BookingController) CreateBooking(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		StartsAt time.Time `json:"starts_at" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input: " + err.Error()})
+		return
+	}
+
+	var class models.Class
+	if err := bc.DB.Preload("Schedules").First(&class, "id = ?", ctx.Param("classId")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Class not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve class"})
+		}
+		return
+	}
+
+	booking, err := bookSession(bc.DB, class, input.StartsAt, userID)
+	switch {
+	case errors.Is(err, errSessionNotScheduled):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No session scheduled at that time"})
+		return
+	case errors.Is(err, errSessionStarted):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Session has already started"})
+		return
+	case errors.Is(err, errAlreadyBooked):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "You have already booked this session"})
+		return
+	case err != nil:
+		log.Println("Error booking session:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to book session"})
+		return
+	}
+
+	message := "Booking confirmed"
+	if booking.Status == models.BookingWaitlisted {
+		message = "Session is full, you have been added to the waitlist"
+	}
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": message,
+		"booking": booking,
+	})
+}
+
+func (bc *BookingController) CancelBooking(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var booking models.Booking
+	if err := bc.DB.First(&booking, "id = ? AND user_id = ?", ctx.Param("id"), userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve booking"})
+		}
+		return
+	}
+
+	promoted, err := cancelBooking(bc.DB, &booking)
+	if err != nil {
+		log.Println("Error cancelling booking:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
+		return
+	}
+	if promoted != nil {
+		log.Printf("Promoted booking %d from the waitlist", promoted.ID)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Booking cancelled",
+		"booking": booking,
+	})
+}
+
+func (bc *BookingController) GetMyBookings(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var bookings []models.Booking
+	err := bc.DB.Preload("Session.Class.Place").
+		Joins("JOIN class_sessions ON class_sessions.id = bookings.session_id").
+		Where("bookings.user_id = ? AND bookings.status <> ?", userID, models.BookingCancelled).
+		Order("class_sessions.starts_at").
+		Find(&bookings).Error
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+
+	var waiting []uint
+	for _, booking := range bookings {
+		if booking.Status == models.BookingWaitlisted {
+			waiting = append(waiting, booking.ID)
+		}
+	}
+	positions, err := waitlistPositions(bc.DB, waiting)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+
+	results := make([]gin.H, 0, len(bookings))
+	for _, booking := range bookings {
+		result := gin.H{
+			"id":         booking.ID,
+			"status":     booking.Status,
+			"class_id":   booking.Session.ClassID,
+			"title":      booking.Session.Class.Title,
+			"instructor": booking.Session.Class.Instructor,
+			"place_id":   booking.Session.Class.PlaceID,
+			"place_name": booking.Session.Class.Place.Name,
+			"starts_at":  booking.Session.StartsAt.In(timetableLocation),
+			"ends_at":    booking.Session.EndsAt.In(timetableLocation),
+			"booked_at":  booking.QueuedAt,
+			"session_id": booking.SessionID,
+		}
+		if booking.Status == models.BookingWaitlisted {
+			result["waitlist_position"] = positions[booking.ID]
+		}
+		results = append(results, result)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"bookings": results,
+		"total":    len(results),
+	})
+}
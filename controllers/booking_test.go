@@ -0,0 +1,149 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookingCapacityAndWaitlist(t *testing.T) {
+	// The concurrent requests below need a database they can reach over
+	// separate connections. sqlite has no row locks, so immediate
+	// transactions stand in for the one postgres takes on the session.
+	db, err := openTestDB(filepath.Join(t.TempDir(), "bookings.db") + "?_txlock=immediate&_busy_timeout=5000")
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	london, err := time.LoadLocation("Europe/London")
+	assert.NoError(t, err)
+	day := time.Now().In(london).AddDate(0, 0, 3).Format("2006-01-02")
+
+	class := models.Class{
+		PlaceID:         1,
+		Title:           "HIIT",
+		Capacity:        2,
+		DurationMinutes: 30,
+		Schedules: []models.ClassSchedule{
+			{Kind: models.ScheduleDate, Date: day, StartTime: "07:00"},
+		},
+	}
+	assert.NoError(t, db.Create(&class).Error)
+
+	for i := 2; i <= 4; i++ {
+		user := models.User{Username: fmt.Sprintf("user%d", i), Email: fmt.Sprintf("user%d@example.com", i), Password: "x"}
+		assert.NoError(t, db.Create(&user).Error)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	controller := controllers.NewBookingController(db)
+	r.Use(func(c *gin.Context) {
+		var userID uint
+		fmt.Sscan(c.GetHeader("X-Test-User"), &userID)
+		c.Set("userID", userID)
+		c.Next()
+	})
+	r.POST("/classes/:classId/bookings", controller.CreateBooking)
+	r.DELETE("/bookings/:id", controller.CancelBooking)
+	r.GET("/users/me/bookings", controller.GetMyBookings)
+	r.GET("/activity/:id/timetable", controllers.NewClassController(db).GetTimetable)
+
+	startsAt, err := time.ParseInLocation("2006-01-02 15:04", day+" 07:00", london)
+	assert.NoError(t, err)
+	body, _ := json.Marshal(map[string]any{"starts_at": startsAt})
+
+	var wg sync.WaitGroup
+	codes := make([]int, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/classes/%d/bookings", class.ID), bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Test-User", fmt.Sprint(i+1))
+			r.ServeHTTP(w, req)
+			codes[i] = w.Code
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, []int{201, 201, 201, 201}, codes)
+
+	var confirmed, waitlisted int64
+	db.Model(&models.Booking{}).Where("status = ?", models.BookingConfirmed).Count(&confirmed)
+	db.Model(&models.Booking{}).Where("status = ?", models.BookingWaitlisted).Count(&waitlisted)
+	assert.Equal(t, int64(2), confirmed)
+	assert.Equal(t, int64(2), waitlisted)
+
+	myBookings := func(userID uint) []map[string]any {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/users/me/bookings", nil)
+		req.Header.Set("X-Test-User", fmt.Sprint(userID))
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Bookings []map[string]any `json:"bookings"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Bookings
+	}
+
+	var queue []models.Booking
+	assert.NoError(t, db.Where("status = ?", models.BookingWaitlisted).Order("queued_at, id").Find(&queue).Error)
+	for i, booking := range queue {
+		bookings := myBookings(booking.UserID)
+		assert.Len(t, bookings, 1)
+		assert.Equal(t, float64(i+1), bookings[0]["waitlist_position"])
+	}
+	firstWaiting := queue[0]
+
+	var cancelling models.Booking
+	assert.NoError(t, db.Where("status = ?", models.BookingConfirmed).First(&cancelling).Error)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/bookings/%d", cancelling.ID), nil)
+	req.Header.Set("X-Test-User", fmt.Sprint(cancelling.UserID))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.NoError(t, db.First(&firstWaiting, firstWaiting.ID).Error)
+	assert.Equal(t, models.BookingConfirmed, firstWaiting.Status)
+
+	bookings := myBookings(firstWaiting.UserID)
+	assert.Len(t, bookings, 1)
+	assert.Equal(t, "HIIT", bookings[0]["title"])
+	assert.Equal(t, "confirmed", bookings[0]["status"])
+	assert.NotContains(t, bookings[0], "waitlist_position")
+	assert.Equal(t, float64(1), myBookings(queue[1].UserID)[0]["waitlist_position"])
+
+	// A booked session keeps the capacity it was booked with.
+	assert.NoError(t, db.Model(&class).Update("capacity", 10).Error)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/activity/1/timetable?from="+day, nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var timetable struct {
+		Sessions []controllers.Session `json:"sessions"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &timetable))
+	assert.Len(t, timetable.Sessions, 1)
+	assert.Equal(t, 2, timetable.Sessions[0].Booked)
+	assert.Equal(t, 1, timetable.Sessions[0].Waitlisted)
+	assert.Equal(t, 2, timetable.Sessions[0].Capacity)
+	assert.Equal(t, 0, timetable.Sessions[0].SpacesLeft)
+}
@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/ical"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"gorm.io/gorm"
+)
+
+const calendarUIDDomain = "fitness-locator"
+
+type CalendarController struct {
+	DB *gorm.DB
+}
+
+func NewCalendarController(db *gorm.DB) *CalendarController {
+	return &CalendarController{DB: db}
+}
+
+func placeAddress(place models.Place) string {
+	var parts []string
+	for _, part := range []string{place.Name, place.Vicinity, place.City, place.Postcode} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// classCalendarEvents maps each schedule rule of class onto a VEVENT. Weekly
+// rules become RRULEs with their exceptions as EXDATEs so calendar clients
+// keep showing the series beyond any fixed window.
+func classCalendarEvents(class models.Class, place models.Place) []ical.Event {
+	duration := time.Duration(class.DurationMinutes) * time.Minute
+	description := class.Category
+	if class.Instructor != "" {
+		description = strings.TrimSpace(description + " with " + class.Instructor)
+	}
+
+	cancelled := func(date, clock string) bool {
+		for _, schedule := range class.Schedules {
+			if schedule.Kind == models.ScheduleException && schedule.Date == date &&
+				(schedule.StartTime == "" || schedule.StartTime == clock) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var events []ical.Event
+	for _, schedule := range class.Schedules {
+		event := ical.Event{
+			UID:         fmt.Sprintf("class-%d-schedule-%d@%s", class.ID, schedule.ID, calendarUIDDomain),
+			Summary:     class.Title,
+			Description: description,
+			Location:    placeAddress(place),
+			Stamp:       class.UpdatedAt,
+		}
+
+		switch schedule.Kind {
+		case models.ScheduleDate:
+			if cancelled(schedule.Date, schedule.StartTime) {
+				continue
+			}
+			start, err := localDateTime(schedule.Date, schedule.StartTime)
+			if err != nil {
+				continue
+			}
+			event.Start = start
+		case models.ScheduleWeekly:
+			day := firstWeeklyDay(schedule, weeklyCalendarStart(class, schedule))
+			start, err := localDateTime(day.Format(dateLayout), schedule.StartTime)
+			if err != nil {
+				continue
+			}
+			event.Start = start
+			event.RRule = "FREQ=WEEKLY;BYDAY=" + ical.Weekday(time.Weekday(schedule.Weekday))
+			if schedule.EndsOn != "" {
+				if until, err := localDateTime(schedule.EndsOn, schedule.StartTime); err == nil {
+					event.RRule += ";UNTIL=" + ical.UntilUTC(until)
+				}
+			}
+			for _, exception := range class.Schedules {
+				if exception.Kind != models.ScheduleException {
+					continue
+				}
+				if exception.StartTime != "" && exception.StartTime != schedule.StartTime {
+					continue
+				}
+				exdate, err := localDateTime(exception.Date, schedule.StartTime)
+				if err != nil || int(exdate.Weekday()) != schedule.Weekday || exdate.Before(start) {
+					continue
+				}
+				event.ExDates = append(event.ExDates, exdate)
+			}
+		default:
+			continue
+		}
+
+		event.End = event.Start.Add(duration)
+		events = append(events, event)
+	}
+	return events
+}
+
+// weeklyCalendarStart returns the day from which a weekly rule without a
+// StartsOn date is written to the feed: the day the rule, or failing that its
+// class, was added. The timetable shows such rules on any date, but starting
+// the feed further back would only make clients expand sessions that never
+// happened.
+func weeklyCalendarStart(class models.Class, schedule models.ClassSchedule) time.Time {
+	if schedule.StartsOn != "" {
+		// firstWeeklyDay starts from StartsOn.
+		return time.Time{}
+	}
+	added := schedule.CreatedAt
+	if added.IsZero() {
+		added = class.CreatedAt
+	}
+	added = added.In(timetableLocation)
+	return time.Date(added.Year(), added.Month(), added.Day(), 0, 0, 0, 0, timetableLocation)
+}
+
+func writeCalendar(ctx *gin.Context, filename string, calendar ical.Calendar) {
+	ctx.Header("Content-Type", "text/calendar; charset=utf-8")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	ctx.Status(http.StatusOK)
+	if err := calendar.Write(ctx.Writer); err != nil {
+		ctx.Error(err)
+	}
+}
+
+func (cc *CalendarController) GetPlaceCalendar(ctx *gin.Context) {
+	var place models.Place
+	if err := cc.DB.First(&place, "id = ?", ctx.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		}
+		return
+	}
+
+	var classes []models.Class
+	if err := cc.DB.Preload("Schedules").Where("place_id = ?", place.ID).Order("id").Find(&classes).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve classes"})
+		return
+	}
+
+	calendar := ical.Calendar{
+		Name:     place.Name + " timetable",
+		Location: timetableLocation,
+	}
+	for _, class := range classes {
+		calendar.Events = append(calendar.Events, classCalendarEvents(class, place)...)
+	}
+
+	writeCalendar(ctx, fmt.Sprintf("activity-%d.ics", place.ID), calendar)
+}
+
+func (cc *CalendarController) GetUserCalendar(ctx *gin.Context) {
+	token := ctx.Param("token")
+	var user models.User
+	if token == "" || cc.DB.First(&user, "calendar_token = ?", token).Error != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+
+	var bookings []models.Booking
+	err := cc.DB.Preload("Session.Class.Place").
+		Where("user_id = ? AND status <> ?", user.ID, models.BookingCancelled).
+		Order("id").
+		Find(&bookings).Error
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+
+	calendar := ical.Calendar{
+		Name:     "My fitness bookings",
+		Location: timetableLocation,
+	}
+	for _, booking := range bookings {
+		class := booking.Session.Class
+		summary := class.Title + " at " + class.Place.Name
+		if booking.Status == models.BookingWaitlisted {
+			summary += " (waitlist)"
+		}
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:         fmt.Sprintf("booking-%d@%s", booking.ID, calendarUIDDomain),
+			Summary:     summary,
+			Description: class.Instructor,
+			Location:    placeAddress(class.Place),
+			Start:       booking.Session.StartsAt,
+			End:         booking.Session.EndsAt,
+			Tentative:   booking.Status == models.BookingWaitlisted,
+			Stamp:       booking.UpdatedAt,
+		})
+	}
+
+	writeCalendar(ctx, "bookings.ics", calendar)
+}
+
+// CreateCalendarToken issues a new private feed token for the current user,
+// revoking any previous one.
+func (cc *CalendarController) CreateCalendarToken(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := cc.DB.Model(&models.User{}).Where("id = ?", userID).Update("calendar_token", token).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save token"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"token": token,
+		"url":   "/api/calendar/" + token + "/bookings.ics",
+	})
+}
+
+func (cc *CalendarController) DeleteCalendarToken(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := cc.DB.Model(&models.User{}).Where("id = ?", userID).Update("calendar_token", nil).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Calendar feed disabled"})
+}
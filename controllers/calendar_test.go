@@ -0,0 +1,181 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaceCalendarFeed(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	class := models.Class{
+		PlaceID:         1,
+		Title:           "Circuits",
+		Capacity:        10,
+		DurationMinutes: 60,
+		Schedules: []models.ClassSchedule{
+			{Kind: models.ScheduleWeekly, Weekday: 2, StartTime: "19:00", StartsOn: "2025-01-01", EndsOn: "2025-12-31"},
+			{Kind: models.ScheduleException, Date: "2025-12-23"},
+		},
+	}
+	assert.NoError(t, db.Create(&class).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewCalendarController(db)
+	r.GET("/activity/:id/timetable.ics", controller.GetPlaceCalendar)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/activity/1/timetable.ics", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.Contains(t, body, "UID:class-1-schedule-1@fitness-locator\r\n")
+	assert.Contains(t, body, "DTSTART;TZID=Europe/London:20250107T190000\r\n")
+	assert.Contains(t, body, "RRULE:FREQ=WEEKLY;BYDAY=TU;UNTIL=20251231T190000Z\r\n")
+	assert.Contains(t, body, "EXDATE;TZID=Europe/London:20251223T190000\r\n")
+	assert.Equal(t, 1, strings.Count(body, "BEGIN:VEVENT"))
+}
+
+func TestPlaceCalendarFeedStartsWhenScheduleWasAdded(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	class := models.Class{PlaceID: 1, Title: "Circuits", Capacity: 10, DurationMinutes: 60}
+	class.CreatedAt = time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, db.Create(&class).Error)
+	// 23:30 UTC on 1 June is already Monday 2 June in London, so the feed
+	// starts on Tuesday 3 June.
+	schedule := models.ClassSchedule{ClassID: class.ID, Kind: models.ScheduleWeekly, Weekday: 2, StartTime: "19:00"}
+	schedule.CreatedAt = time.Date(2025, time.June, 1, 23, 30, 0, 0, time.UTC)
+	assert.NoError(t, db.Create(&schedule).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/activity/:id/timetable", controllers.NewClassController(db).GetTimetable)
+	r.GET("/activity/:id/timetable.ics", controllers.NewCalendarController(db).GetPlaceCalendar)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/activity/1/timetable.ics", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "DTSTART;TZID=Europe/London:20250603T190000\r\n")
+	assert.Contains(t, w.Body.String(), "RRULE:FREQ=WEEKLY;BYDAY=TU\r\n")
+
+	// The timetable itself puts no lower bound on the rule.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/activity/1/timetable?from=2025-01-06&to=2025-01-13", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"starts_at":"2025-01-07T19:00:00Z"`)
+}
+
+func TestPlaceCalendarFeedKeepsUIDsAcrossClassEdits(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	class := models.Class{
+		PlaceID:         1,
+		Title:           "Circuits",
+		Capacity:        10,
+		DurationMinutes: 60,
+		Schedules: []models.ClassSchedule{
+			{Kind: models.ScheduleWeekly, Weekday: 2, StartTime: "19:00", StartsOn: "2025-01-01"},
+			{Kind: models.ScheduleDate, Date: "2025-02-01", StartTime: "10:00"},
+		},
+	}
+	assert.NoError(t, db.Create(&class).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/activity/:id/classes/:classId", controllers.NewClassController(db).UpdateClass)
+	r.GET("/activity/:id/timetable.ics", controllers.NewCalendarController(db).GetPlaceCalendar)
+
+	body, _ := json.Marshal(map[string]any{
+		"title":            "Evening Circuits",
+		"capacity":         12,
+		"duration_minutes": 45,
+		"schedules": []map[string]any{
+			{"kind": "weekly", "weekday": 2, "start_time": "19:00", "starts_on": "2025-01-01", "ends_on": "2025-12-31"},
+			{"kind": "date", "date": "2025-03-01", "start_time": "10:00"},
+		},
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/activity/1/classes/%d", class.ID), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/activity/1/timetable.ics", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	feed := w.Body.String()
+	// The weekly rule survived the edit and keeps its UID; the moved one-off
+	// session is a new event.
+	assert.Contains(t, feed, fmt.Sprintf("UID:class-%d-schedule-%d@fitness-locator\r\n", class.ID, class.Schedules[0].ID))
+	assert.NotContains(t, feed, fmt.Sprintf("UID:class-%d-schedule-%d@fitness-locator\r\n", class.ID, class.Schedules[1].ID))
+	assert.Contains(t, feed, "SUMMARY:Evening Circuits\r\n")
+	assert.Contains(t, feed, "UNTIL=20251231T190000Z")
+	assert.Equal(t, 2, strings.Count(feed, "BEGIN:VEVENT"))
+}
+
+func TestUserCalendarFeedRequiresToken(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewCalendarController(db)
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/users/me/calendar-token", controller.CreateCalendarToken)
+	r.GET("/calendar/:token/bookings.ics", controller.GetUserCalendar)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/calendar/not-a-token/bookings.ics", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/users/me/calendar-token", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/calendar/"+response["token"]+"/bookings.ics", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "X-WR-CALNAME:My fitness bookings\r\n")
+}
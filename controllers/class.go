@@ -0,0 +1,298 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"gorm.io/gorm"
+)
+
+type ClassController struct {
+	DB *gorm.DB
+}
+
+func NewClassController(db *gorm.DB) *ClassController {
+	return &ClassController{DB: db}
+}
+
+type classInput struct {
+	Title           string                 `json:"title"`
+	Instructor      string                 `json:"instructor"`
+	Category        string                 `json:"category"`
+	Capacity        int                    `json:"capacity"`
+	DurationMinutes int                    `json:"duration_minutes"`
+	PricePence      int                    `json:"price_pence"`
+	Schedules       []models.ClassSchedule `json:"schedules"`
+}
+
+func (input classInput) validate() string {
+	if input.Title == "" {
+		return "Title is required"
+	}
+	if input.Capacity <= 0 {
+		return "Capacity must be greater than zero"
+	}
+	if input.DurationMinutes <= 0 {
+		return "Duration must be greater than zero"
+	}
+	if input.PricePence < 0 {
+		return "Price cannot be negative"
+	}
+	for _, schedule := range input.Schedules {
+		if err := validateSchedule(schedule); err != nil {
+			return "Invalid schedule: " + err.Error()
+		}
+	}
+	return ""
+}
+
+func (input classInput) schedules() []models.ClassSchedule {
+	schedules := make([]models.ClassSchedule, 0, len(input.Schedules))
+	for _, schedule := range input.Schedules {
+		schedules = append(schedules, models.ClassSchedule{
+			Kind:      schedule.Kind,
+			Weekday:   schedule.Weekday,
+			StartTime: schedule.StartTime,
+			Date:      schedule.Date,
+			StartsOn:  schedule.StartsOn,
+			EndsOn:    schedule.EndsOn,
+		})
+	}
+	return schedules
+}
+
+func (cc *ClassController) findPlace(ctx *gin.Context) (models.Place, bool) {
+	var place models.Place
+	if err := cc.DB.First(&place, "id = ?", ctx.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		}
+		return place, false
+	}
+	return place, true
+}
+
+func (cc *ClassController) findClass(ctx *gin.Context) (models.Class, bool) {
+	var class models.Class
+	err := cc.DB.Preload("Schedules").
+		First(&class, "id = ? AND place_id = ?", ctx.Param("classId"), ctx.Param("id")).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Class not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve class"})
+		}
+		return class, false
+	}
+	return class, true
+}
+
+func (cc *ClassController) GetClasses(ctx *gin.Context) {
+	place, ok := cc.findPlace(ctx)
+	if !ok {
+		return
+	}
+
+	var classes []models.Class
+	if err := cc.DB.Preload("Schedules").Where("place_id = ?", place.ID).Order("id").Find(&classes).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve classes"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"classes": classes,
+		"total":   len(classes),
+	})
+}
+
+func (cc *ClassController) CreateClass(ctx *gin.Context) {
+	place, ok := cc.findPlace(ctx)
+	if !ok {
+		return
+	}
+
+	var input classInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input: " + err.Error()})
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	class := models.Class{
+		PlaceID:         place.ID,
+		Title:           input.Title,
+		Instructor:      input.Instructor,
+		Category:        input.Category,
+		Capacity:        input.Capacity,
+		DurationMinutes: input.DurationMinutes,
+		PricePence:      input.PricePence,
+		Schedules:       input.schedules(),
+	}
+
+	if err := cc.DB.Create(&class).Error; err != nil {
+		log.Println("Error saving class:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create class"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Class created successfully",
+		"class":   class,
+	})
+}
+
+func (cc *ClassController) UpdateClass(ctx *gin.Context) {
+	class, ok := cc.findClass(ctx)
+	if !ok {
+		return
+	}
+
+	var input classInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input: " + err.Error()})
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	class.Title = input.Title
+	class.Instructor = input.Instructor
+	class.Category = input.Category
+	class.Capacity = input.Capacity
+	class.DurationMinutes = input.DurationMinutes
+	class.PricePence = input.PricePence
+
+	err := cc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Schedules").Save(&class).Error; err != nil {
+			return err
+		}
+		return replaceSchedules(tx, &class, input.schedules())
+	})
+	if err != nil {
+		log.Println("Error updating class:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update class"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Class updated successfully",
+		"class":   class,
+	})
+}
+
+// replaceSchedules makes the schedules of class match schedules. Rules that
+// are still there keep their rows, so calendar feeds keep giving them the same
+// UID and start date.
+func replaceSchedules(tx *gorm.DB, class *models.Class, schedules []models.ClassSchedule) error {
+	var existing []models.ClassSchedule
+	if err := tx.Where("class_id = ?", class.ID).Find(&existing).Error; err != nil {
+		return err
+	}
+
+	kept := map[uint]bool{}
+	for i := range schedules {
+		schedule := &schedules[i]
+		schedule.ClassID = class.ID
+		for _, old := range existing {
+			if !kept[old.ID] && old.Kind == schedule.Kind && old.Weekday == schedule.Weekday &&
+				old.StartTime == schedule.StartTime && old.Date == schedule.Date {
+				kept[old.ID] = true
+				schedule.Model = old.Model
+				break
+			}
+		}
+		if err := tx.Save(schedule).Error; err != nil {
+			return err
+		}
+	}
+	for _, old := range existing {
+		if !kept[old.ID] {
+			if err := tx.Delete(&old).Error; err != nil {
+				return err
+			}
+		}
+	}
+	class.Schedules = schedules
+	return nil
+}
+
+func (cc *ClassController) DeleteClass(ctx *gin.Context) {
+	class, ok := cc.findClass(ctx)
+	if !ok {
+		return
+	}
+
+	err := cc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("class_id = ?", class.ID).Delete(&models.ClassSchedule{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&class).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete class"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Class deleted successfully"})
+}
+
+func (cc *ClassController) GetTimetable(ctx *gin.Context) {
+	place, ok := cc.findPlace(ctx)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseTimetableRange(ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var classes []models.Class
+	if err := cc.DB.Preload("Schedules").Where("place_id = ?", place.ID).Find(&classes).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve classes"})
+		return
+	}
+
+	classIDs := make([]uint, 0, len(classes))
+	for _, class := range classes {
+		classIDs = append(classIDs, class.ID)
+	}
+	usages, err := sessionUsages(cc.DB, classIDs, from, to)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+
+	sessions := []Session{}
+	for _, class := range classes {
+		for _, session := range expandClassSessions(class, from, to) {
+			if usage, ok := usages[sessionKey{session.ClassID, session.StartsAt.Unix()}]; ok {
+				session.Capacity = usage.Capacity
+				session.Booked = usage.Booked
+				session.Waitlisted = usage.Waitlisted
+			}
+			session.SpacesLeft = max(session.Capacity-session.Booked, 0)
+			sessions = append(sessions, session)
+		}
+	}
+	sortSessions(sessions)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"place_id": place.ID,
+		"from":     from,
+		"to":       to,
+		"timezone": timetableLocation.String(),
+		"sessions": sessions,
+		"total":    len(sessions),
+	})
+}
@@ -0,0 +1,108 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateClassAndTimetable(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	var place models.Place
+	err = db.First(&place).Error
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewClassController(db)
+
+	r.POST("/activity/:id/classes", controller.CreateClass)
+	r.GET("/activity/:id/timetable", controller.GetTimetable)
+
+	body, _ := json.Marshal(map[string]any{
+		"title":            "Spin",
+		"instructor":       "Sam",
+		"capacity":         12,
+		"duration_minutes": 45,
+		"schedules": []map[string]any{
+			{"kind": "weekly", "weekday": 1, "start_time": "18:30"},
+			{"kind": "date", "date": "2025-03-29", "start_time": "09:00"},
+			{"kind": "exception", "date": "2025-03-24"},
+		},
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/activity/%d/classes", place.ID), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// The range crosses the switch to British Summer Time on 30 March 2025.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/activity/%d/timetable?from=2025-03-17&to=2025-04-01", place.ID), nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Sessions []struct {
+			Title    string `json:"title"`
+			StartsAt string `json:"starts_at"`
+			EndsAt   string `json:"ends_at"`
+		} `json:"sessions"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	var starts []string
+	for _, session := range response.Sessions {
+		starts = append(starts, session.StartsAt)
+	}
+	assert.Equal(t, []string{
+		"2025-03-17T18:30:00Z",
+		"2025-03-29T09:00:00Z",
+		"2025-03-31T18:30:00+01:00",
+	}, starts)
+	assert.Equal(t, "2025-03-31T19:15:00+01:00", response.Sessions[2].EndsAt)
+}
+
+func TestCreateClassRejectsInvalidSchedule(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewClassController(db)
+	r.POST("/activity/:id/classes", controller.CreateClass)
+
+	body, _ := json.Marshal(map[string]any{
+		"title":            "Yoga",
+		"capacity":         10,
+		"duration_minutes": 60,
+		"schedules": []map[string]any{
+			{"kind": "weekly", "weekday": 9, "start_time": "07:00"},
+		},
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/activity/1/classes", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
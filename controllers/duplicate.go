@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/laurawarren88/go_spa_backend.git/geo"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"gorm.io/gorm"
+)
+
+// Thresholds for warning that a new activity may already exist.
+const (
+	duplicateRadius     = 250.0 // metres
+	duplicateSimilarity = 0.8
+	maxDuplicates       = 5
+)
+
+// nameStopWords are left out when comparing names.
+var nameStopWords = map[string]bool{"the": true, "and": true, "ltd": true, "limited": true}
+
+type possibleDuplicate struct {
+	ID         uint     `json:"id"`
+	Name       string   `json:"name"`
+	Vicinity   string   `json:"vicinity"`
+	Postcode   string   `json:"postcode"`
+	Distance   float64  `json:"distance"`
+	Similarity float64  `json:"similarity"`
+	Reasons    []string `json:"reasons"`
+}
+
+// normalizeName lower-cases a name and drops punctuation, spaces and stop
+// words, so that "The Pure Gym" and "PureGym" compare equal.
+func normalizeName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, word := range words {
+		if !nameStopWords[word] {
+			b.WriteString(word)
+		}
+	}
+	return b.String()
+}
+
+// nameSimilarity returns how alike two names are, from 0 to 1, based on the
+// edit distance between their normalised forms.
+func nameSimilarity(a, b string) float64 {
+	x, y := []rune(normalizeName(a)), []rune(normalizeName(b))
+	longest := max(len(x), len(y))
+	if longest == 0 {
+		return 0
+	}
+
+	previous := make([]int, len(y)+1)
+	current := make([]int, len(y)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		current[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(y)])/float64(longest)
+}
+
+// findPossibleDuplicates returns the places that place most likely
+// duplicates: those with a similar name nearby or at the same postcode or
+// phone number, and those sharing its phone number nearby.
+func findPossibleDuplicates(db *gorm.DB, place models.Place) ([]possibleDuplicate, error) {
+	latDelta := duplicateRadius / (geo.EarthRadius * math.Pi / 180)
+	lngDelta := latDelta / math.Max(math.Cos(place.Latitude*math.Pi/180), 0.01)
+
+	query := db.Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?",
+		place.Latitude-latDelta, place.Latitude+latDelta, place.Longitude-lngDelta, place.Longitude+lngDelta)
+	if place.Postcode != "" {
+		query = query.Or("postcode = ?", place.Postcode)
+	}
+	if place.Phone != "" {
+		query = query.Or("phone = ?", place.Phone)
+	}
+
+	var candidates []models.Place
+	err := db.Select("id", "name", "vicinity", "postcode", "phone", "latitude", "longitude").
+		Where(query).Where("id <> ?", place.ID).Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := []possibleDuplicate{}
+	for _, candidate := range candidates {
+		distance := geo.Distance(place.Latitude, place.Longitude, candidate.Latitude, candidate.Longitude)
+		similarity := nameSimilarity(place.Name, candidate.Name)
+		nearby := distance <= duplicateRadius
+		samePostcode := place.Postcode != "" && candidate.Postcode == place.Postcode
+		samePhone := place.Phone != "" && candidate.Phone == place.Phone
+		similar := similarity >= duplicateSimilarity
+
+		if !(similar && (nearby || samePostcode || samePhone)) && !(samePhone && nearby) {
+			continue
+		}
+
+		var reasons []string
+		if similar {
+			reasons = append(reasons, "name")
+		}
+		if nearby {
+			reasons = append(reasons, "distance")
+		}
+		if samePostcode {
+			reasons = append(reasons, "postcode")
+		}
+		if samePhone {
+			reasons = append(reasons, "phone")
+		}
+		duplicates = append(duplicates, possibleDuplicate{
+			ID:         candidate.ID,
+			Name:       candidate.Name,
+			Vicinity:   candidate.Vicinity,
+			Postcode:   candidate.Postcode,
+			Distance:   math.Round(distance),
+			Similarity: math.Round(similarity*100) / 100,
+			Reasons:    reasons,
+		})
+	}
+
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		if len(duplicates[i].Reasons) != len(duplicates[j].Reasons) {
+			return len(duplicates[i].Reasons) > len(duplicates[j].Reasons)
+		}
+		return duplicates[i].Distance < duplicates[j].Distance
+	})
+	if len(duplicates) > maxDuplicates {
+		duplicates = duplicates[:maxDuplicates]
+	}
+	return duplicates, nil
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+)
+
+var errVersionConflict = errors.New("activity was modified by someone else")
+
+// placeETag identifies the current version of a place. It changes every time
+// the place is edited.
+func placeETag(place models.Place) string {
+	return fmt.Sprintf(`"%d-%d"`, place.ID, place.Version)
+}
+
+// ifMatches reports whether an If-Match header value matches etag, using the
+// strong comparison RFC 9110 requires for If-Match.
+func ifMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch makes sure the client is changing the version of place it last
+// saw. It responds and returns false when the If-Match header is missing or
+// stale.
+func (pc *PlaceController) checkIfMatch(ctx *gin.Context, place models.Place) bool {
+	header := ctx.GetHeader("If-Match")
+	if header == "" {
+		ctx.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+		return false
+	}
+	if !ifMatches(header, placeETag(place)) {
+		pc.respondVersionConflict(ctx, place.ID)
+		return false
+	}
+	return true
+}
+
+// respondVersionConflict answers 412 Precondition Failed with the current
+// representation of the place so the client can merge and retry.
+func (pc *PlaceController) respondVersionConflict(ctx *gin.Context, id uint) {
+	place, err := pc.loadActivity(id)
+	if err != nil {
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": errVersionConflict.Error()})
+		return
+	}
+	details, err := pc.activityDetails(place)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve images"})
+		return
+	}
+	ctx.Header("ETag", placeETag(place))
+	ctx.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":    errVersionConflict.Error(),
+		"activity": details,
+	})
+}
@@ -0,0 +1,80 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivityEditsRequireCurrentETag(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Model(&models.Place{}).Where("id = ?", 1).Updates(map[string]interface{}{
+		"latitude": 53.48, "longitude": -2.24,
+	}).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, newTestStore(t))
+	r.GET("/activity/:id", controller.GetActivityById)
+	r.GET("/activity/:id/edit", controller.RenderEditActivityForm)
+	r.PATCH("/activity/:id", controller.UpdateActivity)
+	r.DELETE("/activity/:id", controller.DeleteActivity)
+
+	send := func(method, body, ifMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/activity/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("GET", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"1-1"`, etag)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/activity/1/edit", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	assert.Equal(t, http.StatusPreconditionRequired, send("PATCH", `{"city": "Leeds"}`, "").Code)
+	assert.Equal(t, http.StatusPreconditionRequired, send("DELETE", "", "").Code)
+
+	// The first manager saves; the second is still holding the old ETag.
+	w = send("PATCH", `{"city": "Leeds"}`, etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	newETag := w.Header().Get("ETag")
+	assert.Equal(t, `"1-2"`, newETag)
+
+	w = send("PATCH", `{"city": "York"}`, etag)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, newETag, w.Header().Get("ETag"))
+	var conflict struct {
+		Activity struct {
+			City    string `json:"city"`
+			Version uint   `json:"version"`
+		} `json:"activity"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &conflict))
+	assert.Equal(t, "Leeds", conflict.Activity.City)
+	assert.Equal(t, uint(2), conflict.Activity.Version)
+
+	assert.Equal(t, http.StatusPreconditionFailed, send("DELETE", "", etag).Code)
+	assert.Equal(t, http.StatusOK, send("PATCH", `{"city": "York"}`, `"0-9", `+newETag).Code)
+	assert.Equal(t, http.StatusOK, send("DELETE", "", `"1-3"`).Code)
+}
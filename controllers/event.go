@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/geo"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultEventWindow = 30 * 24 * time.Hour
+	maxEventWindow     = 366 * 24 * time.Hour
+)
+
+type EventController struct {
+	DB    *gorm.DB
+	Store storage.BlobStore
+}
+
+func NewEventController(db *gorm.DB, store storage.BlobStore) *EventController {
+	return &EventController{DB: db, Store: store}
+}
+
+type eventInput struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Type        string    `json:"type"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	PlaceID     *uint     `json:"place_id"`
+	Vicinity    string    `json:"vicinity"`
+	Latitude    *float64  `json:"latitude"`
+	Longitude   *float64  `json:"longitude"`
+	Website     string    `json:"website"`
+}
+
+func (input eventInput) validate() string {
+	if input.Title == "" {
+		return "Title is required"
+	}
+	if input.StartsAt.IsZero() || input.EndsAt.IsZero() {
+		return "Start and end times are required"
+	}
+	if !input.EndsAt.After(input.StartsAt) {
+		return "End time must be after start time"
+	}
+	if input.PlaceID == nil && (input.Latitude == nil || input.Longitude == nil) {
+		return "Either a place or coordinates are required"
+	}
+	if input.Latitude != nil && (*input.Latitude < -90 || *input.Latitude > 90) {
+		return "Latitude must be between -90 and 90"
+	}
+	if input.Longitude != nil && (*input.Longitude < -180 || *input.Longitude > 180) {
+		return "Longitude must be between -180 and 180"
+	}
+	return ""
+}
+
+// parseEventWindow reads the from/to query values used to select events. It
+// defaults to the next 30 days and never reaches back before now, so events
+// that have finished drop out of results on their own.
+func parseEventWindow(fromParam, toParam string) (time.Time, time.Time, error) {
+	parse := func(value string) (time.Time, error) {
+		if t, err := time.ParseInLocation(dateLayout, value, timetableLocation); err == nil {
+			return t, nil
+		}
+		return time.Parse(time.RFC3339, value)
+	}
+
+	now := time.Now()
+	from := now
+	if fromParam != "" {
+		t, err := parse(fromParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from value")
+		}
+		if t.After(now) {
+			from = t
+		}
+	}
+
+	to := from.Add(defaultEventWindow)
+	if toParam != "" {
+		t, err := parse(toParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to value")
+		}
+		to = t
+	}
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to must be after from")
+	}
+	if to.Sub(from) > maxEventWindow {
+		return time.Time{}, time.Time{}, fmt.Errorf("range cannot exceed 366 days")
+	}
+	return from, to, nil
+}
+
+// eventsInWindow returns events overlapping [from, to) that have not ended.
+func eventsInWindow(db *gorm.DB, from, to time.Time) ([]models.Event, error) {
+	var events []models.Event
+	err := db.Preload("Place").
+		Where("ends_at > ? AND ends_at > ? AND starts_at < ?", time.Now(), from, to).
+		Order("starts_at").
+		Find(&events).Error
+	return events, err
+}
+
+func (ec *EventController) userCanManagePlace(userID uint, placeID uint) (bool, error) {
+	var place models.Place
+	if err := ec.DB.First(&place, placeID).Error; err != nil {
+		return false, err
+	}
+	if place.UserID == userID {
+		return true, nil
+	}
+	var user models.User
+	if err := ec.DB.First(&user, userID).Error; err != nil {
+		return false, err
+	}
+	return user.IsAdmin, nil
+}
+
+// applyInput copies input onto event, taking the location from the linked
+// place when there is one.
+func (ec *EventController) applyInput(ctx *gin.Context, userID uint, event *models.Event, input eventInput) bool {
+	event.Title = input.Title
+	event.Description = input.Description
+	event.Type = input.Type
+	event.StartsAt = input.StartsAt
+	event.EndsAt = input.EndsAt
+	event.Website = input.Website
+	event.PlaceID = input.PlaceID
+	event.Place = nil
+	event.Vicinity = input.Vicinity
+	event.Latitude, event.Longitude = 0, 0
+	if input.Latitude != nil && input.Longitude != nil {
+		event.Latitude, event.Longitude = *input.Latitude, *input.Longitude
+	}
+
+	if input.PlaceID == nil {
+		return true
+	}
+
+	allowed, err := ec.userCanManagePlace(userID, *input.PlaceID)
+	if err == gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Activity not found"})
+		return false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		return false
+	}
+	if !allowed {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You can only add events to your own activities"})
+		return false
+	}
+
+	var place models.Place
+	if err := ec.DB.First(&place, *input.PlaceID).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		return false
+	}
+	event.Latitude = place.Latitude
+	event.Longitude = place.Longitude
+	if event.Vicinity == "" {
+		event.Vicinity = placeAddress(place)
+	}
+	return true
+}
+
+func (ec *EventController) GetEvents(ctx *gin.Context) {
+	from, to, err := parseEventWindow(ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := eventsInWindow(ec.DB, from, to)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		return
+	}
+
+	latParam := ctx.Query("lat")
+	lngParam := ctx.Query("lng")
+	radiusParam := ctx.Query("radius")
+	if latParam != "" && lngParam != "" && radiusParam != "" {
+		lat, _ := strconv.ParseFloat(latParam, 64)
+		lng, _ := strconv.ParseFloat(lngParam, 64)
+		radius, _ := strconv.ParseFloat(radiusParam, 64)
+		events = filterEventsByDistance(events, lat, lng, radius)
+	}
+
+	if events == nil {
+		events = []models.Event{}
+	}
+	for i := range events {
+		events[i] = eventWithURLs(ec.Store, events[i])
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"from":   from,
+		"to":     to,
+		"total":  len(events),
+	})
+}
+
+func filterEventsByDistance(events []models.Event, lat, lng, radius float64) []models.Event {
+	var filtered []models.Event
+	for _, event := range events {
+		if geo.Distance(lat, lng, event.Latitude, event.Longitude) <= radius {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+func (ec *EventController) GetEventById(ctx *gin.Context) {
+	var event models.Event
+	if err := ec.DB.Preload("Place").First(&event, "id = ?", ctx.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"event":   eventWithURLs(ec.Store, event),
+		"expired": !event.EndsAt.After(time.Now()),
+	})
+}
+
+func (ec *EventController) CreateEvent(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var input eventInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input: " + err.Error()})
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	event := models.Event{UserID: userID}
+	if !ec.applyInput(ctx, userID, &event, input) {
+		return
+	}
+
+	if err := ec.DB.Create(&event).Error; err != nil {
+		log.Println("Error saving event:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Event created successfully",
+		"event":   eventWithURLs(ec.Store, event),
+	})
+}
+
+func (ec *EventController) UpdateEvent(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var event models.Event
+	if err := ec.DB.First(&event, "id = ?", ctx.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		}
+		return
+	}
+
+	var input eventInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input: " + err.Error()})
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if !ec.applyInput(ctx, userID, &event, input) {
+		return
+	}
+
+	if err := ec.DB.Save(&event).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Event updated successfully",
+		"event":   eventWithURLs(ec.Store, event),
+	})
+}
+
+func (ec *EventController) DeleteEvent(ctx *gin.Context) {
+	if err := ec.DB.Delete(&models.Event{}, "id = ?", ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Event deleted successfully"})
+}
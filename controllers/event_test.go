@@ -0,0 +1,100 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocatorIncludesUpcomingEvents(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	events := []models.Event{
+		{Title: "Park Run", StartsAt: now.Add(24 * time.Hour), EndsAt: now.Add(26 * time.Hour), Latitude: 53.48, Longitude: -2.24},
+		{Title: "Finished Bootcamp", StartsAt: now.Add(-3 * time.Hour), EndsAt: now.Add(-time.Hour), Latitude: 53.48, Longitude: -2.24},
+		{Title: "Open Day", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), Latitude: 53.48, Longitude: -2.24},
+		{Title: "Far Away Fun Run", StartsAt: now.Add(24 * time.Hour), EndsAt: now.Add(26 * time.Hour), Latitude: 51.5, Longitude: -0.12},
+		{Title: "Next Year", StartsAt: now.AddDate(1, 0, 0), EndsAt: now.AddDate(1, 0, 1), Latitude: 53.48, Longitude: -2.24},
+	}
+	assert.NoError(t, db.Create(&events).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, newTestStore(t))
+	r.GET("/activities/locator", controller.GetPlaceLocator)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/activities/locator?lat=53.48&lng=-2.24&radius=5000&include=events", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Events []models.Event `json:"events"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	var titles []string
+	for _, event := range response.Events {
+		titles = append(titles, event.Title)
+	}
+	assert.Equal(t, []string{"Open Day", "Park Run"}, titles)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/activities/locator?lat=53.48&lng=-2.24&radius=5000", nil)
+	r.ServeHTTP(w, req)
+	assert.NotContains(t, w.Body.String(), `"events"`)
+}
+
+func TestCreateEventNeedsALocation(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+	assert.NoError(t, createTestData(db))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/events", controllers.NewEventController(db, newTestStore(t)).CreateEvent)
+
+	post := func(location string) *httptest.ResponseRecorder {
+		start := time.Now().Add(24 * time.Hour).UTC()
+		body := `{"title": "Meridian Run", "starts_at": "` + start.Format(time.RFC3339) +
+			`", "ends_at": "` + start.Add(time.Hour).Format(time.RFC3339) + `"` + location + `}`
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The Greenwich meridian is a real place.
+	w := post(`, "latitude": 51.4779, "longitude": 0`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var event models.Event
+	assert.NoError(t, db.Where("title = ?", "Meridian Run").First(&event).Error)
+	assert.Equal(t, 51.4779, event.Latitude)
+	assert.Equal(t, 0.0, event.Longitude)
+
+	w = post(`, "latitude": 51.4779`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Either a place or coordinates are required")
+
+	w = post(`, "latitude": 91, "longitude": 0`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Latitude must be between -90 and 90")
+}
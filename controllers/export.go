@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/exporter"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"gorm.io/gorm"
+)
+
+const exportBatchSize = 500
+
+// ExportActivities streams the activities matching the locator filters as
+// GeoJSON, CSV or KML. Unlike the locator, every activity is exported when no
+// location is given. Places are read in batches so the table is never held
+// in memory.
+func (pc *PlaceController) ExportActivities(ctx *gin.Context) {
+	format := strings.ToLower(ctx.DefaultQuery("format", exporter.FormatGeoJSON))
+	filter, err := parsePlaceFilter(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	writer, err := exporter.NewWriter(format, ctx.Writer)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format must be geojson, csv or kml"})
+		return
+	}
+
+	ctx.Header("Content-Type", exporter.ContentType(format))
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="activities.%s"`, format))
+
+	var batch []models.Place
+	result := pc.DB.Preload("Prices").Preload("Amenities").
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, place := range batch {
+				if !filter.matches(place) {
+					continue
+				}
+				if err := writer.Write(exporter.FromPlace(place, pc.Store)); err != nil {
+					return err
+				}
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			ctx.Writer.Flush()
+			return nil
+		})
+	if result.Error != nil {
+		log.Println("Error exporting activities:", result.Error)
+		if !ctx.Writer.Written() {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export activities"})
+		}
+		// Otherwise the client is left with a truncated document.
+		return
+	}
+	if err := writer.Close(); err != nil {
+		log.Println("Error exporting activities:", err)
+	}
+}
@@ -0,0 +1,71 @@
+package controllers_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportActivities(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Model(&models.Place{}).Where("id = ?", 1).Updates(map[string]interface{}{
+		"latitude": 53.48, "longitude": -2.24, "type": "gym",
+	}).Error)
+	assert.NoError(t, db.Create(&[]models.Place{
+		{Name: "Leeds Yoga", Type: "yoga", Latitude: 53.80, Longitude: -1.55, UserID: 1},
+		{Name: "Salford Gym", Type: "gym", Latitude: 53.49, Longitude: -2.29, UserID: 1},
+	}).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, newTestStore(t))
+	r.GET("/export", controller.ExportActivities)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/export"+query, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/geo+json", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `filename="activities.geojson"`)
+	var collection struct {
+		Features []struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &collection))
+	assert.Len(t, collection.Features, 3)
+	assert.NotContains(t, collection.Features[0].Properties, "user")
+
+	w = get("?format=csv&type=GYM&lat=53.48&lng=-2.24&radius=10000")
+	assert.Equal(t, http.StatusOK, w.Code)
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, "Test Place", records[1][1])
+	assert.Equal(t, "Salford Gym", records[2][1])
+
+	w = get("?format=kml&type=yoga")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "<name>Leeds Yoga</name>")
+	assert.NotContains(t, w.Body.String(), "Salford Gym")
+
+	assert.Equal(t, http.StatusBadRequest, get("?format=xlsx").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?lat=north&lng=-2.24&radius=100").Code)
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"gorm.io/gorm"
+)
+
+const galleryPrefix = "gallery"
+
+func placeImages(db *gorm.DB, placeID uint) ([]models.PlaceImage, error) {
+	images := []models.PlaceImage{}
+	err := db.Where("place_id = ?", placeID).Order("position, id").Find(&images).Error
+	return images, err
+}
+
+func coverImage(images []models.PlaceImage) *models.PlaceImage {
+	for i := range images {
+		if images[i].IsCover {
+			return &images[i]
+		}
+	}
+	return nil
+}
+
+// ensureCover makes the first image of a place its cover when none is set.
+func ensureCover(tx *gorm.DB, placeID uint) error {
+	images, err := placeImages(tx, placeID)
+	if err != nil || len(images) == 0 || coverImage(images) != nil {
+		return err
+	}
+	return tx.Model(&images[0]).Update("is_cover", true).Error
+}
+
+func (pc *PlaceController) findPlaceForImages(ctx *gin.Context) (models.Place, bool) {
+	var place models.Place
+	if err := pc.DB.First(&place, "id = ?", ctx.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		}
+		return place, false
+	}
+	return place, true
+}
+
+func (pc *PlaceController) findImage(ctx *gin.Context) (models.PlaceImage, bool) {
+	var image models.PlaceImage
+	err := pc.DB.First(&image, "id = ? AND place_id = ?", ctx.Param("imageId"), ctx.Param("id")).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve image"})
+		}
+		return image, false
+	}
+	return image, true
+}
+
+func (pc *PlaceController) GetImages(ctx *gin.Context) {
+	place, ok := pc.findPlaceForImages(ctx)
+	if !ok {
+		return
+	}
+
+	images, err := placeImages(pc.DB, place.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve images"})
+		return
+	}
+	images = imagesWithURLs(pc.Store, images)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"images":      images,
+		"cover_image": coverImage(images),
+		"total":       len(images),
+	})
+}
+
+// UploadImages appends the files sent in the "images" form field to the
+// gallery. Optional "captions" and "alt_texts" values are matched to the files
+// by position.
+func (pc *PlaceController) UploadImages(ctx *gin.Context) {
+	place, ok := pc.findPlaceForImages(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ctx.Request.ParseMultipartForm(32 << 20); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form: " + err.Error()})
+		return
+	}
+
+	form := ctx.Request.MultipartForm
+	files := form.File["images"]
+	if len(files) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "No images uploaded"})
+		return
+	}
+	captions := form.Value["captions"]
+	altTexts := form.Value["alt_texts"]
+
+	var last models.PlaceImage
+	if err := pc.DB.Where("place_id = ?", place.ID).Order("position DESC").Limit(1).Find(&last).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve images"})
+		return
+	}
+	position := last.Position + 1
+
+	staged := newMediaChanges(pc.Store)
+	defer staged.Rollback(ctx)
+
+	var images []models.PlaceImage
+	for i, file := range files {
+		imagePath, err := staged.Upload(ctx, file, galleryPrefix)
+		if err != nil {
+			respondUploadError(ctx, fmt.Sprintf("Failed to save image %d", i+1), err)
+			return
+		}
+
+		image := models.PlaceImage{PlaceID: place.ID, Path: imagePath, Position: position + i}
+		if i < len(captions) {
+			image.Caption = captions[i]
+		}
+		if i < len(altTexts) {
+			image.AltText = altTexts[i]
+		}
+		images = append(images, image)
+	}
+
+	err := pc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&images).Error; err != nil {
+			return err
+		}
+		return ensureCover(tx, place.ID)
+	})
+	if err != nil {
+		log.Println("Error saving images:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save images"})
+		return
+	}
+	staged.Commit(ctx)
+
+	gallery, err := placeImages(pc.DB, place.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve images"})
+		return
+	}
+	gallery = imagesWithURLs(pc.Store, gallery)
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message":     "Images uploaded successfully",
+		"images":      gallery,
+		"cover_image": coverImage(gallery),
+	})
+}
+
+func (pc *PlaceController) UpdateImage(ctx *gin.Context) {
+	image, ok := pc.findImage(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Caption *string `json:"caption"`
+		AltText *string `json:"alt_text"`
+		IsCover *bool   `json:"is_cover"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input: " + err.Error()})
+		return
+	}
+	if input.Caption != nil {
+		image.Caption = *input.Caption
+	}
+	if input.AltText != nil {
+		image.AltText = *input.AltText
+	}
+	if len(image.Caption) > 255 || len(image.AltText) > 255 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Caption and alt text must be at most 255 characters"})
+		return
+	}
+
+	err := pc.DB.Transaction(func(tx *gorm.DB) error {
+		if input.IsCover != nil && *input.IsCover {
+			if err := tx.Model(&models.PlaceImage{}).Where("place_id = ?", image.PlaceID).Update("is_cover", false).Error; err != nil {
+				return err
+			}
+			image.IsCover = true
+		}
+		if input.IsCover != nil && !*input.IsCover {
+			image.IsCover = false
+		}
+		if err := tx.Save(&image).Error; err != nil {
+			return err
+		}
+		return ensureCover(tx, image.PlaceID)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update image"})
+		return
+	}
+
+	pc.DB.First(&image, image.ID)
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Image updated successfully",
+		"image":   imagesWithURLs(pc.Store, []models.PlaceImage{image})[0],
+	})
+}
+
+// ReorderImages sets the gallery order. The request must list every image of
+// the place exactly once.
+func (pc *PlaceController) ReorderImages(ctx *gin.Context) {
+	place, ok := pc.findPlaceForImages(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ImageIDs []uint `json:"image_ids" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input: " + err.Error()})
+		return
+	}
+
+	images, err := placeImages(pc.DB, place.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve images"})
+		return
+	}
+
+	remaining := map[uint]bool{}
+	for _, image := range images {
+		remaining[image.ID] = true
+	}
+	for _, id := range input.ImageIDs {
+		if !remaining[id] {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "image_ids must list every image of the activity exactly once"})
+			return
+		}
+		delete(remaining, id)
+	}
+	if len(remaining) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "image_ids must list every image of the activity exactly once"})
+		return
+	}
+
+	err = pc.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range input.ImageIDs {
+			if err := tx.Model(&models.PlaceImage{}).Where("id = ?", id).Update("position", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder images"})
+		return
+	}
+
+	gallery, err := placeImages(pc.DB, place.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve images"})
+		return
+	}
+	gallery = imagesWithURLs(pc.Store, gallery)
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Images reordered successfully",
+		"images":  gallery,
+	})
+}
+
+func (pc *PlaceController) DeleteImage(ctx *gin.Context) {
+	image, ok := pc.findImage(ctx)
+	if !ok {
+		return
+	}
+
+	err := pc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+		return ensureCover(tx, image.PlaceID)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete image"})
+		return
+	}
+
+	removeImageFiles(ctx, pc.Store, image.Path)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Image deleted successfully"})
+}
@@ -0,0 +1,220 @@
+package controllers_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/media"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func testPNG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for x := 0; x < 32; x++ {
+		img.Set(x, x, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestImageGallery(t *testing.T) {
+	store := newTestStore(t)
+
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, store)
+	r.POST("/activity/:id/images", controller.UploadImages)
+	r.PUT("/activity/:id/images/order", controller.ReorderImages)
+	r.PUT("/activity/:id/images/:imageId", controller.UpdateImage)
+	r.DELETE("/activity/:id/images/:imageId", controller.DeleteImage)
+	r.GET("/activity/:id", controller.GetActivityById)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for i, name := range []string{"front.jpg", "pool.jpg", "gym.jpg"} {
+		part, _ := writer.CreateFormFile("images", name)
+		part.Write(testPNG(t))
+		writer.WriteField("captions", fmt.Sprintf("Caption %d", i+1))
+	}
+	writer.WriteField("alt_texts", "Front entrance")
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/activity/1/images", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	type galleryResponse struct {
+		Images     []models.PlaceImage `json:"images"`
+		CoverImage *models.PlaceImage  `json:"cover_image"`
+	}
+	getGallery := func() galleryResponse {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/activity/1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response galleryResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	gallery := getGallery()
+	assert.Len(t, gallery.Images, 3)
+	assert.Equal(t, "Caption 2", gallery.Images[1].Caption)
+	assert.Equal(t, "Front entrance", gallery.Images[0].AltText)
+	assert.Equal(t, gallery.Images[0].ID, gallery.CoverImage.ID)
+	assert.NotEqual(t, gallery.Images[0].Path, gallery.Images[1].Path)
+	assert.Equal(t, "/uploads/"+gallery.Images[0].Path, gallery.Images[0].URL)
+	assert.Equal(t, "/uploads/"+media.ThumbnailName(gallery.Images[0].Path, "small"), gallery.Images[0].Thumbnails["small"])
+	_, err = os.Stat(filepath.Join(store.Root, media.ThumbnailName(gallery.Images[0].Path, "small")))
+	assert.NoError(t, err)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/activity/1/images/order", bytes.NewBufferString(`{"image_ids":[3,1,2]}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/activity/1/images/order", bytes.NewBufferString(`{"image_ids":[3,1]}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/activity/1/images/2", bytes.NewBufferString(`{"is_cover":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	gallery = getGallery()
+	var order []uint
+	for _, image := range gallery.Images {
+		order = append(order, image.ID)
+	}
+	assert.Equal(t, []uint{3, 1, 2}, order)
+	assert.Equal(t, uint(2), gallery.CoverImage.ID)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/activity/1/images/2", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, err = os.Stat(filepath.Join(store.Root, gallery.Images[2].Path))
+	assert.True(t, os.IsNotExist(err))
+
+	gallery = getGallery()
+	assert.Len(t, gallery.Images, 2)
+	assert.Equal(t, uint(3), gallery.CoverImage.ID)
+}
+
+func TestUploadImagesRejectsNonImages(t *testing.T) {
+	store := newTestStore(t)
+
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, store)
+	r.POST("/activity/:id/images", controller.UploadImages)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("images", "photo.jpg")
+	part.Write([]byte("<html><script>alert(1)</script></html>"))
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/activity/1/images", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var count int64
+	db.Model(&models.PlaceImage{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	_, err = os.Stat(filepath.Join(store.Root, "gallery"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUploadImagesFailsWhenGalleryCannotBeRead(t *testing.T) {
+	store := newTestStore(t)
+
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, store)
+	r.POST("/activity/:id/images", controller.UploadImages)
+
+	upload := func() int {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("images", "front.jpg")
+		part.Write(testPNG(t))
+		writer.Close()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/activity/1/images", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	images := func() int64 {
+		var count int64
+		db.Model(&models.PlaceImage{}).Count(&count)
+		return count
+	}
+
+	// Reading the last position fails before anything is saved.
+	db.Callback().Query().Before("gorm:query").Register("test:fail", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*models.PlaceImage); ok {
+			tx.AddError(errors.New("database unavailable"))
+		}
+	})
+	assert.Equal(t, http.StatusInternalServerError, upload())
+	db.Callback().Query().Remove("test:fail")
+	assert.Equal(t, int64(0), images())
+
+	// Reading the gallery back fails after the images are saved.
+	db.Callback().Query().Before("gorm:query").Register("test:fail", func(tx *gorm.DB) {
+		_, inTransaction := tx.Statement.ConnPool.(*sql.Tx)
+		if _, ok := tx.Statement.Dest.(*[]models.PlaceImage); ok && !inTransaction {
+			tx.AddError(errors.New("database unavailable"))
+		}
+	})
+	assert.Equal(t, http.StatusInternalServerError, upload())
+	db.Callback().Query().Remove("test:fail")
+	assert.Equal(t, int64(1), images())
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/importer"
+	"gorm.io/gorm"
+)
+
+const maxImportSize = 20 << 20
+
+// ImportController lets admins load activities in bulk from CSV or GeoJSON.
+type ImportController struct {
+	DB *gorm.DB
+}
+
+func NewImportController(db *gorm.DB) *ImportController {
+	return &ImportController{DB: db}
+}
+
+// ImportActivities accepts the file either as the "file" field of a multipart
+// form or as the raw request body. The format is taken from the format query
+// parameter, the file name or the content type, in that order.
+func (ic *ImportController) ImportActivities(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportSize)
+
+	format := ctx.Query("format")
+	var body io.Reader = ctx.Request.Body
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		header, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "A file is required"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+			return
+		}
+		defer file.Close()
+		body = file
+		if format == "" {
+			format = importer.FormatFromName(header.Filename)
+		}
+	}
+	if format == "" {
+		switch ctx.ContentType() {
+		case "text/csv":
+			format = importer.FormatCSV
+		case "application/geo+json", "application/json":
+			format = importer.FormatGeoJSON
+		}
+	}
+
+	rows, err := importer.Parse(format, body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(ctx.Query("dry_run"))
+	opts := importer.Options{
+		DryRun:       dryRun,
+		Mode:         importer.Mode(ctx.DefaultQuery("mode", string(importer.ModeAtomic))),
+		AuthorID:     revisionAuthorID(ctx),
+		DedupeRadius: importer.DefaultDedupeRadius,
+	}
+	if opts.AuthorID != nil {
+		opts.OwnerID = *opts.AuthorID
+	}
+	for name, target := range map[string]*int{"batch_size": &opts.BatchSize, "start": &opts.Start} {
+		if value := ctx.Query(name); value != "" {
+			if *target, err = strconv.Atoi(value); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+				return
+			}
+		}
+	}
+	if value := ctx.Query("owner_id"); value != "" {
+		owner, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner_id"})
+			return
+		}
+		opts.OwnerID = uint(owner)
+	}
+	if value := ctx.Query("radius"); value != "" {
+		if opts.DedupeRadius, err = strconv.ParseFloat(value, 64); err != nil || opts.DedupeRadius < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid radius"})
+			return
+		}
+	}
+
+	report, err := importer.Run(ctx, ic.DB, rows, opts)
+	switch {
+	case errors.Is(err, importer.ErrInvalidRows):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Import has invalid rows; nothing was imported", "report": report})
+	case errors.Is(err, importer.ErrInvalidOptions):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		log.Println("Error importing activities:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Import failed", "report": report})
+	default:
+		ctx.JSON(http.StatusOK, gin.H{"report": report})
+	}
+}
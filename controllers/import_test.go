@@ -0,0 +1,109 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportActivities(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewImportController(db)
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/import", controller.ImportActivities)
+
+	type response struct {
+		Error  string `json:"error"`
+		Report struct {
+			Created int `json:"created"`
+			Valid   int `json:"valid"`
+			Invalid int `json:"invalid"`
+			Rows    []struct {
+				Status  string `json:"status"`
+				PlaceID uint   `json:"place_id"`
+				Errors  []struct {
+					Field string `json:"field"`
+				} `json:"errors"`
+			} `json:"rows"`
+		} `json:"report"`
+	}
+	send := func(req *http.Request) (int, response) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var body response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	csv := "name,phone,description,latitude,longitude\n" +
+		"Harbour Gym,0161 496 0001,Gym,53.49,-2.25\n" +
+		"Missing Phone,,Gym,53.50,-2.26\n"
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, _ := writer.CreateFormFile("file", "places.csv")
+	part.Write([]byte(csv))
+	writer.Close()
+	req, _ := http.NewRequest("POST", "/import?dry_run=true", &form)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	code, body := send(req)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, body.Report.Valid)
+	assert.Equal(t, 1, body.Report.Invalid)
+	assert.Equal(t, "phone", body.Report.Rows[1].Errors[0].Field)
+
+	req, _ = http.NewRequest("POST", "/import", strings.NewReader(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	code, body = send(req)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, 1, body.Report.Invalid)
+
+	req, _ = http.NewRequest("POST", "/import?mode=batched", strings.NewReader(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	code, body = send(req)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, body.Report.Created)
+
+	var place models.Place
+	assert.NoError(t, db.First(&place, body.Report.Rows[0].PlaceID).Error)
+	assert.Equal(t, "Harbour Gym", place.Name)
+	assert.Equal(t, uint(1), place.UserID)
+
+	req, _ = http.NewRequest("POST", "/import", strings.NewReader("not,a,known\nformat"))
+	code, body = send(req)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body.Error, "unsupported import format")
+
+	req, _ = http.NewRequest("POST", "/import?mode=sideways", strings.NewReader(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	code, body = send(req)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body.Error, `unknown import mode "sideways"`)
+
+	// Database failures are server errors and do not leak their details.
+	assert.NoError(t, db.Migrator().DropTable(&models.Amenity{}))
+	req, _ = http.NewRequest("POST", "/import", strings.NewReader(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	code, body = send(req)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, "Import failed", body.Error)
+}
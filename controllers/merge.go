@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/slug"
+	"gorm.io/gorm"
+)
+
+// mergePlaces folds source into target: fields target leaves empty are taken
+// from source, and amenities are combined.
+func mergePlaces(target, source models.Place) (models.Place, []models.Amenity) {
+	merged := target
+	for _, field := range placeTextFields {
+		if *field(&merged) == "" {
+			*field(&merged) = *field(&source)
+		}
+	}
+	for _, field := range placeMediaFields {
+		if *field(&merged) == "" {
+			*field(&merged) = *field(&source)
+		}
+	}
+	if merged.OSMID == nil {
+		merged.OSMID, merged.OSMSnapshot = source.OSMID, source.OSMSnapshot
+	}
+
+	amenities := append([]models.Amenity{}, target.Amenities...)
+	seen := map[uint]bool{}
+	for _, amenity := range amenities {
+		seen[amenity.ID] = true
+	}
+	for _, amenity := range source.Amenities {
+		if !seen[amenity.ID] {
+			amenities = append(amenities, amenity)
+			seen[amenity.ID] = true
+		}
+	}
+	return merged, amenities
+}
+
+// MergeActivity merges the activity in the URL into the one named by "into".
+// Its gallery, classes and events move across, along with its prices when the
+// target has none. The merged activity goes to the trash and its ID redirects
+// to the target from then on. If-Match must carry the target's ETag.
+func (pc *PlaceController) MergeActivity(ctx *gin.Context) {
+	var input struct {
+		Into uint `json:"into" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input: " + err.Error()})
+		return
+	}
+
+	var source, target models.Place
+	if err := pc.DB.Preload("Amenities").First(&source, "id = ?", ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
+		return
+	}
+	if source.ID == input.Into {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "An activity cannot be merged into itself"})
+		return
+	}
+	if err := pc.DB.Preload("Amenities").First(&target, input.Into).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Target activity not found"})
+		return
+	}
+	if !pc.checkIfMatch(ctx, target) {
+		return
+	}
+
+	merged, amenities := mergePlaces(target, source)
+	err := pc.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&source).Where("version = ?", source.Version).
+			Updates(map[string]interface{}{"osm_id": nil, "osm_snapshot": "", "slug": ""})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errVersionConflict
+		}
+		err := savePlace(tx, target, &merged, amenities, true, models.PlaceRevision{
+			Action:   models.RevisionMerge,
+			AuthorID: revisionAuthorID(ctx),
+		})
+		if err != nil {
+			return err
+		}
+
+		images, err := placeImages(tx, target.ID)
+		if err != nil {
+			return err
+		}
+		moved := map[string]interface{}{"place_id": target.ID, "position": gorm.Expr("position + ?", len(images))}
+		if coverImage(images) != nil {
+			moved["is_cover"] = false
+		}
+		if err := tx.Model(&models.PlaceImage{}).Where("place_id = ?", source.ID).Updates(moved).Error; err != nil {
+			return err
+		}
+		if err := ensureCover(tx, target.ID); err != nil {
+			return err
+		}
+
+		var prices int64
+		if err := tx.Model(&models.PlacePrice{}).Where("place_id = ?", target.ID).Count(&prices).Error; err != nil {
+			return err
+		}
+		if prices == 0 {
+			if err := tx.Model(&models.PlacePrice{}).Where("place_id = ?", source.ID).Update("place_id", target.ID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Class{}).Where("place_id = ?", source.ID).Update("place_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Event{}).Where("place_id = ?", source.ID).Update("place_id", target.ID).Error; err != nil {
+			return err
+		}
+
+		// Places merged into source earlier, and its slugs, now lead to
+		// target too.
+		if err := tx.Model(&models.PlaceRedirect{}).Where("to_id = ?", source.ID).Update("to_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PlaceSlug{}).Where("place_id = ?", source.ID).Update("place_id", target.ID).Error; err != nil {
+			return err
+		}
+		if source.Slug != "" {
+			if err := tx.Create(&models.PlaceSlug{PlaceID: target.ID, Slug: source.Slug}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&models.PlaceRedirect{FromID: source.ID, ToID: target.ID}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&source).Error
+	})
+	if err == errVersionConflict {
+		pc.respondVersionConflict(ctx, target.ID)
+		return
+	}
+	if err == slug.ErrTaken {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println("Error merging activities:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge activities"})
+		return
+	}
+
+	place, err := pc.loadActivity(target.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		return
+	}
+	details, err := pc.activityDetails(place)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve images"})
+		return
+	}
+	ctx.Header("ETag", placeETag(place))
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":   "Activities merged successfully",
+		"merged_id": source.ID,
+		"activity":  details,
+	})
+}
@@ -0,0 +1,136 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/middleware"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateWarningAndMerge(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(&[]models.Amenity{{Slug: "pool", Name: "Pool"}, {Slug: "sauna", Name: "Sauna"}}).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, newTestStore(t))
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Set("db", db)
+		c.Next()
+	})
+	r.POST("/api/activities/new", controller.CreateActivity)
+	r.GET("/api/activities/:id", middleware.ResolveActivity(), controller.GetActivityById)
+	r.GET("/api/activities/:id/prices", middleware.ResolveActivity(), controller.GetPrices)
+	r.POST("/api/admin/activities/:id/merge", controller.MergeActivity)
+
+	send := func(method, url, body, ifMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	type created struct {
+		Activity struct {
+			ID uint `json:"ID"`
+		} `json:"activity"`
+		PossibleDuplicates []struct {
+			ID      uint     `json:"id"`
+			Reasons []string `json:"reasons"`
+		} `json:"possible_duplicates"`
+	}
+	create := func(body string) created {
+		w := send("POST", "/api/activities/new", body, "")
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response created
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	original := create(`{"name": "Harbour Gym", "phone": "0161 496 0001", "postcode": "M1 1AA",
+		"description": "Gym", "latitude": 53.48, "longitude": -2.24, "amenities": ["pool"]}`)
+	assert.Empty(t, original.PossibleDuplicates)
+
+	duplicate := create(`{"name": "The Harbour-Gym", "phone": "0161 496 0002", "city": "Manchester",
+		"description": "Gym", "latitude": 53.4801, "longitude": -2.2401, "amenities": ["sauna"]}`)
+	assert.Len(t, duplicate.PossibleDuplicates, 1)
+	assert.Equal(t, original.Activity.ID, duplicate.PossibleDuplicates[0].ID)
+	assert.Equal(t, []string{"name", "distance"}, duplicate.PossibleDuplicates[0].Reasons)
+
+	samePhone := create(`{"name": "Quay Studio", "phone": "0161 496 0001", "description": "Studio",
+		"latitude": 53.4802, "longitude": -2.2402}`)
+	assert.Len(t, samePhone.PossibleDuplicates, 1)
+	assert.Contains(t, samePhone.PossibleDuplicates[0].Reasons, "phone")
+
+	elsewhere := create(`{"name": "Harbour Gym", "phone": "0161 496 0003", "description": "Gym",
+		"latitude": 51.5, "longitude": -0.1}`)
+	assert.Empty(t, elsewhere.PossibleDuplicates)
+
+	targetID, sourceID := original.Activity.ID, duplicate.Activity.ID
+	assert.NoError(t, db.Create(&models.PlaceImage{PlaceID: targetID, Path: "gallery/a.jpg", IsCover: true}).Error)
+	assert.NoError(t, db.Create(&models.PlaceImage{PlaceID: sourceID, Path: "gallery/b.jpg", IsCover: true}).Error)
+	assert.NoError(t, db.Create(&models.PlacePrice{PlaceID: sourceID, Kind: models.PriceDayPass, AmountPence: 800}).Error)
+	class := models.Class{PlaceID: sourceID, Title: "Spin"}
+	assert.NoError(t, db.Create(&class).Error)
+
+	merge := "/api/admin/activities/" + fmt.Sprint(sourceID) + "/merge"
+	into := `{"into": ` + fmt.Sprint(targetID) + `}`
+	assert.Equal(t, http.StatusPreconditionRequired, send("POST", merge, into, "").Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", merge, `{"into": `+fmt.Sprint(sourceID)+`}`, "*").Code)
+	assert.Equal(t, http.StatusNotFound, send("POST", merge, `{"into": 999}`, "*").Code)
+
+	w := send("POST", merge, into, `"`+fmt.Sprint(targetID)+`-1"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"`+fmt.Sprint(targetID)+`-2"`, w.Header().Get("ETag"))
+	var merged struct {
+		Activity struct {
+			City      string              `json:"city"`
+			Phone     string              `json:"phone"`
+			Amenities []models.Amenity    `json:"amenities"`
+			Images    []models.PlaceImage `json:"images"`
+		} `json:"activity"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &merged))
+	assert.Equal(t, "Manchester", merged.Activity.City)
+	assert.Equal(t, "+441614960001", merged.Activity.Phone)
+	assert.Len(t, merged.Activity.Amenities, 2)
+	assert.Len(t, merged.Activity.Images, 2)
+	assert.True(t, merged.Activity.Images[0].IsCover)
+	assert.False(t, merged.Activity.Images[1].IsCover)
+
+	assert.NoError(t, db.First(&class, class.ID).Error)
+	assert.Equal(t, targetID, class.PlaceID)
+	var revision models.PlaceRevision
+	assert.NoError(t, db.Where("place_id = ?", targetID).Order("id DESC").First(&revision).Error)
+	assert.Equal(t, models.RevisionMerge, revision.Action)
+
+	w = send("GET", "/api/activities/"+fmt.Sprint(sourceID)+"/prices", "", "")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/api/activities/"+fmt.Sprint(targetID)+"/prices", w.Header().Get("Location"))
+	w = send("GET", "/api/activities/"+fmt.Sprint(targetID)+"/prices", "", "")
+	assert.Contains(t, w.Body.String(), `"amount_pence":800`)
+
+	// Merging the target on again carries the old redirect along.
+	w = send("POST", "/api/admin/activities/"+fmt.Sprint(targetID)+"/merge", `{"into": `+fmt.Sprint(elsewhere.Activity.ID)+`}`, "*")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send("GET", "/api/activities/"+fmt.Sprint(sourceID), "", "")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/api/activities/"+fmt.Sprint(elsewhere.Activity.ID), w.Header().Get("Location"))
+	assert.Equal(t, http.StatusNotFound, send("GET", "/api/activities/999", "", "").Code)
+}
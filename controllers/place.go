@@ -4,26 +4,25 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"math"
-	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/slug"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+	"github.com/laurawarren88/go_spa_backend.git/validation"
 	"gorm.io/gorm"
 )
 
 type PlaceController struct {
-	DB *gorm.DB
+	DB    *gorm.DB
+	Store storage.BlobStore
 }
 
-func NewPlaceController(db *gorm.DB) *PlaceController {
-	return &PlaceController{DB: db}
+func NewPlaceController(db *gorm.DB, store storage.BlobStore) *PlaceController {
+	return &PlaceController{DB: db, Store: store}
 }
 
 func (pc *PlaceController) CheckActivityOwnership(ctx *gin.Context) {
@@ -80,24 +79,28 @@ func (pc *PlaceController) CreateActivity(ctx *gin.Context) {
 	log.Printf("User found: %+v", user)
 
 	type PlaceTextFields struct {
-		Name            string  `form:"name" json:"name"`
-		Vicinity        string  `form:"vicinity" json:"vicinity"`
-		City            string  `form:"city" json:"city"`
-		Postcode        string  `form:"postcode" json:"postcode"`
-		Phone           string  `form:"phone" json:"phone"`
-		Email           string  `form:"email" json:"email"`
-		Website         string  `form:"website" json:"website"`
-		OpeningHours    string  `form:"opening_hours" json:"opening_hours"`
-		Description     string  `form:"description" json:"description"`
-		Type            string  `form:"type" json:"type"`
-		Latitude        float64 `form:"latitude" json:"latitude"`
-		Longitude       float64 `form:"longitude" json:"longitude"`
-		Logo            string  `json:"logo" form:"logo" gorm:"size:255"`
-		FacilitiesImage string  `json:"facilities_image" form:"facilities_image" gorm:"size:255"`
+		Name            string   `form:"name" json:"name"`
+		Vicinity        string   `form:"vicinity" json:"vicinity"`
+		City            string   `form:"city" json:"city"`
+		Postcode        string   `form:"postcode" json:"postcode"`
+		Phone           string   `form:"phone" json:"phone"`
+		Email           string   `form:"email" json:"email"`
+		Website         string   `form:"website" json:"website"`
+		OpeningHours    string   `form:"opening_hours" json:"opening_hours"`
+		Description     string   `form:"description" json:"description"`
+		Type            string   `form:"type" json:"type"`
+		Latitude        *float64 `form:"latitude" json:"latitude"`
+		Longitude       *float64 `form:"longitude" json:"longitude"`
+		Logo            string   `json:"logo" form:"logo" gorm:"size:255"`
+		FacilitiesImage string   `json:"facilities_image" form:"facilities_image" gorm:"size:255"`
+		Amenities       []string `json:"amenities" form:"amenities"`
 	}
 
 	var placeFields PlaceTextFields
+	var errs validation.Errors
 	contentType := ctx.GetHeader("Content-Type")
+	staged := newMediaChanges(pc.Store)
+	defer staged.Rollback(ctx)
 
 	if strings.HasPrefix(contentType, "application/json") {
 		if err := ctx.ShouldBindJSON(&placeFields); err != nil {
@@ -120,35 +123,18 @@ func (pc *PlaceController) CreateActivity(ctx *gin.Context) {
 		placeFields.OpeningHours = ctx.Request.FormValue("opening_hours")
 		placeFields.Description = ctx.Request.FormValue("description")
 		placeFields.Type = ctx.Request.FormValue("type")
+		placeFields.Amenities = ctx.Request.MultipartForm.Value["amenities"]
 
-		latitude, err := strconv.ParseFloat(ctx.Request.FormValue("latitude"), 64)
-		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid latitude value"})
-			return
-		}
-		placeFields.Latitude = latitude
-
-		longitude, err := strconv.ParseFloat(ctx.Request.FormValue("longitude"), 64)
-		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid longitude value"})
-			return
-		}
-		placeFields.Longitude = longitude
+		placeFields.Latitude = parseFormCoordinate(&errs, "latitude", ctx.Request.FormValue("latitude"))
+		placeFields.Longitude = parseFormCoordinate(&errs, "longitude", ctx.Request.FormValue("longitude"))
 
 		placeFields.Logo = ctx.Request.FormValue("logo")
 		placeFields.FacilitiesImage = ctx.Request.FormValue("facilities_image")
 
 		if logoFile, err := ctx.FormFile("logo"); err == nil {
-			sanitizedFilename := fmt.Sprintf("%d_%s", time.Now().Unix(), filepath.Base(logoFile.Filename))
-			logoFilePath := "./uploads/logos/" + sanitizedFilename
-
-			if err := os.MkdirAll("./uploads/logos", os.ModePerm); err != nil {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create directory"})
-				return
-			}
-
-			if err := ctx.SaveUploadedFile(logoFile, logoFilePath); err != nil {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save logo file"})
+			logoFilePath, err := staged.Upload(ctx, logoFile, "logos")
+			if err != nil {
+				respondUploadError(ctx, "Failed to save logo file", err)
 				return
 			}
 
@@ -158,16 +144,9 @@ func (pc *PlaceController) CreateActivity(ctx *gin.Context) {
 		}
 
 		if facilitiesImageFile, err := ctx.FormFile("facilities_image"); err == nil {
-			sanitizedFilename := fmt.Sprintf("%d_%s", time.Now().Unix(), filepath.Base(facilitiesImageFile.Filename))
-			facilitiesImageFilePath := "./uploads/facilities/" + sanitizedFilename
-
-			if err := os.MkdirAll("./uploads/facilities", os.ModePerm); err != nil {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create directory"})
-				return
-			}
-
-			if err := ctx.SaveUploadedFile(facilitiesImageFile, facilitiesImageFilePath); err != nil {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save facilities image"})
+			facilitiesImageFilePath, err := staged.Upload(ctx, facilitiesImageFile, "facilities")
+			if err != nil {
+				respondUploadError(ctx, "Failed to save facilities image", err)
 				return
 			}
 
@@ -177,11 +156,6 @@ func (pc *PlaceController) CreateActivity(ctx *gin.Context) {
 		}
 	}
 
-	if placeFields.Name == "" || placeFields.Latitude == 0 || placeFields.Longitude == 0 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields"})
-		return
-	}
-
 	log.Printf("Activity Text Fields: %+v\n", placeFields)
 
 	activity := models.Place{
@@ -195,29 +169,79 @@ func (pc *PlaceController) CreateActivity(ctx *gin.Context) {
 		OpeningHours:    placeFields.OpeningHours,
 		Description:     placeFields.Description,
 		Type:            placeFields.Type,
-		Latitude:        placeFields.Latitude,
-		Longitude:       placeFields.Longitude,
 		Logo:            placeFields.Logo,
 		FacilitiesImage: placeFields.FacilitiesImage,
 		UserID:          userIDUint,
 	}
 
-	if err := pc.DB.Create(&activity).Error; err != nil {
+	if placeFields.Latitude == nil && !errs.Has("latitude") {
+		errs.Add("latitude", validation.CodeRequired, "latitude is required")
+	}
+	if placeFields.Longitude == nil && !errs.Has("longitude") {
+		errs.Add("longitude", validation.CodeRequired, "longitude is required")
+	}
+	if placeFields.Latitude != nil && placeFields.Longitude != nil {
+		activity.Latitude, activity.Longitude = *placeFields.Latitude, *placeFields.Longitude
+	}
+	if err := validation.Place(&activity); err != nil {
+		errs = append(errs, err.(validation.Errors)...)
+	}
+	if len(errs) > 0 {
+		respondInvalidPlace(ctx, errs)
+		return
+	}
+
+	amenities, err := resolveAmenities(pc.DB, parseAmenityList(placeFields.Amenities))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	activity.Amenities = amenities
+
+	// Likely duplicates are reported to the client but do not stop the
+	// activity being created.
+	duplicates, err := findPossibleDuplicates(pc.DB, activity)
+	if err != nil {
+		log.Println("Error checking for duplicate activities:", err)
+	}
+
+	err = pc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := slug.Assign(tx, &activity); err != nil {
+			return err
+		}
+		if err := tx.Create(&activity).Error; err != nil {
+			return slug.Conflict(tx, err)
+		}
+		return recordRevision(tx, activity, models.PlaceRevision{
+			Action:   models.RevisionCreate,
+			AuthorID: revisionAuthorID(ctx),
+		})
+	})
+	if err == slug.ErrTaken {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		log.Println("Error saving to database:", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create activity"})
 		return
 	}
+	staged.Commit(ctx)
 
 	// After creating the activity
-	if err := pc.DB.Preload("User").First(&activity, activity.ID).Error; err != nil {
+	if err := pc.DB.Preload("User").Preload("Amenities").First(&activity, activity.ID).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity with user details"})
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{
+	response := gin.H{
 		"message":  "Activity created successfully",
-		"activity": activity,
-	})
+		"activity": placeWithURLs(pc.Store, activity),
+	}
+	if len(duplicates) > 0 {
+		response["possible_duplicates"] = duplicates
+	}
+	ctx.JSON(http.StatusCreated, response)
 }
 
 func (pc *PlaceController) GetPlaceLocator(ctx *gin.Context) {
@@ -227,7 +251,13 @@ func (pc *PlaceController) GetPlaceLocator(ctx *gin.Context) {
 	// Add debug logging for initial query
 	log.Printf("Starting place lookup")
 
-	result := pc.DB.Preload("User").Find(&places)
+	filter, err := parsePlaceFilter(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := pc.DB.Preload("User").Preload("Prices").Preload("Amenities").Find(&places)
 	if result.Error != nil {
 		log.Printf("Database error: %v", result.Error)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
@@ -236,59 +266,91 @@ func (pc *PlaceController) GetPlaceLocator(ctx *gin.Context) {
 
 	log.Printf("Found %d places in database", len(places))
 
-	typeParam := ctx.Query("type")
-	latParam := ctx.Query("lat")
-	lngParam := ctx.Query("lng")
-	radiusParam := ctx.Query("radius")
-
 	// Only filter if we have coordinates and radius
-	if latParam != "" && lngParam != "" && radiusParam != "" {
-		lat, _ := strconv.ParseFloat(latParam, 64)
-		lng, _ := strconv.ParseFloat(lngParam, 64)
-		radius, _ := strconv.ParseFloat(radiusParam, 64)
-
+	if filter.HasLocation {
 		for _, place := range places {
-			// Type filter (case insensitive)
-			if typeParam != "" && !strings.EqualFold(place.Type, typeParam) {
-				continue
-			}
-
-			distance := calculateDistance(lat, lng, place.Latitude, place.Longitude)
-			log.Printf("Distance for place %s: %f meters", place.Name, distance)
-
-			if distance <= radius {
-				filteredPlaces = append(filteredPlaces, place)
+			if filter.matches(place) {
+				filteredPlaces = append(filteredPlaces, placeWithURLs(pc.Store, place))
 			}
 		}
 	}
 
 	log.Printf("Filtered to %d places", len(filteredPlaces))
 
-	ctx.JSON(http.StatusOK, gin.H{
+	facets, err := amenityFacets(pc.DB, filteredPlaces)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	response := gin.H{
 		"places":  filteredPlaces,
 		"message": "Locator Page",
 		"total":   len(filteredPlaces),
-	})
+		"facets":  gin.H{"amenities": facets},
+	}
+
+	if includes(ctx.Query("include"), "events") {
+		from, to, err := parseEventWindow(ctx.Query("from"), ctx.Query("to"))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var filteredEvents []models.Event
+		if filter.HasLocation {
+			events, err := eventsInWindow(pc.DB, from, to)
+			if err != nil {
+				log.Printf("Database error: %v", err)
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+				return
+			}
+
+			for _, event := range filterEventsByDistance(events, filter.Lat, filter.Lng, filter.Radius) {
+				if filter.Type != "" && !strings.EqualFold(event.Type, filter.Type) {
+					continue
+				}
+				filteredEvents = append(filteredEvents, eventWithURLs(pc.Store, event))
+			}
+		}
+
+		response["events"] = filteredEvents
+		response["events_total"] = len(filteredEvents)
+	}
+
+	ctx.JSON(http.StatusOK, response)
 }
 
-func (pc *PlaceController) GetActivityById(ctx *gin.Context) {
-	id := ctx.Param("id")
+// includes reports whether name appears in a comma separated include list.
+func includes(list, name string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), name) {
+			return true
+		}
+	}
+	return false
+}
 
-	// Ensure the ID is a valid integer
+func (pc *PlaceController) loadActivity(id interface{}) (models.Place, error) {
 	var place models.Place
-	if err := pc.DB.Preload("User").First(&place, "id = ?", id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
-		} else {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
-		}
-		return
+	err := pc.DB.Preload("User").Preload("Prices").Preload("Amenities").First(&place, "id = ?", id).Error
+	return place, err
+}
+
+// activityDetails builds the full representation of a place returned by
+// GetActivityById.
+func (pc *PlaceController) activityDetails(place models.Place) (gin.H, error) {
+	images, err := placeImages(pc.DB, place.ID)
+	if err != nil {
+		return nil, err
 	}
+	images = imagesWithURLs(pc.Store, images)
 
-	// Return the activity as JSON
-	ctx.JSON(http.StatusOK, gin.H{
+	return gin.H{
 		"id":               place.ID,
 		"name":             place.Name,
+		"slug":             place.Slug,
 		"vicinity":         place.Vicinity,
 		"city":             place.City,
 		"postcode":         place.Postcode,
@@ -300,11 +362,37 @@ func (pc *PlaceController) GetActivityById(ctx *gin.Context) {
 		"type":             place.Type,
 		"latitude":         place.Latitude,
 		"longitude":        place.Longitude,
-		"logo":             place.Logo,
-		"facilities_image": place.FacilitiesImage,
+		"logo":             storage.PublicURL(pc.Store, place.Logo),
+		"facilities_image": storage.PublicURL(pc.Store, place.FacilitiesImage),
+		"version":          place.Version,
 		"userID":           place.UserID,
 		"user":             place.User,
-	})
+		"prices":           place.Prices,
+		"amenities":        place.Amenities,
+		"images":           images,
+		"cover_image":      coverImage(images),
+	}, nil
+}
+
+func (pc *PlaceController) GetActivityById(ctx *gin.Context) {
+	place, err := pc.loadActivity(ctx.Param("id"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		}
+		return
+	}
+
+	details, err := pc.activityDetails(place)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve images"})
+		return
+	}
+
+	ctx.Header("ETag", placeETag(place))
+	ctx.JSON(http.StatusOK, details)
 }
 
 func (pc *PlaceController) RenderEditActivityForm(ctx *gin.Context) {
@@ -321,9 +409,10 @@ func (pc *PlaceController) RenderEditActivityForm(ctx *gin.Context) {
 		return
 	}
 
+	ctx.Header("ETag", placeETag(existingPlace))
 	ctx.JSON(http.StatusOK, gin.H{
 		"title":    "Update Activity Form",
-		"activity": existingPlace,
+		"activity": placeWithURLs(pc.Store, existingPlace),
 	})
 }
 
@@ -344,109 +433,154 @@ func (pc *PlaceController) UpdateActivity(ctx *gin.Context) {
 		}
 		return
 	}
-
-	if err := ctx.Request.ParseMultipartForm(32 << 20); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form data"})
+	if !pc.checkIfMatch(ctx, existingPlace) {
 		return
 	}
+	original := existingPlace
 
-	form := ctx.Request.MultipartForm
+	staged := newMediaChanges(pc.Store)
+	defer staged.Rollback(ctx)
 
-	if name := form.Value["name"]; len(name) > 0 {
-		existingPlace.Name = name[0]
-	}
-	if vicinity := form.Value["vicinity"]; len(vicinity) > 0 {
-		existingPlace.Vicinity = vicinity[0]
-	}
-	if city := form.Value["city"]; len(city) > 0 {
-		existingPlace.City = city[0]
-	}
-	if postcode := form.Value["postcode"]; len(postcode) > 0 {
-		existingPlace.Postcode = postcode[0]
-	}
-	if phone := form.Value["phone"]; len(phone) > 0 {
-		existingPlace.Phone = phone[0]
-	}
-	if email := form.Value["email"]; len(email) > 0 {
-		existingPlace.Email = email[0]
-	}
-	if website := form.Value["website"]; len(website) > 0 {
-		existingPlace.Website = website[0]
-	}
-	if openingHours := form.Value["opening_hours"]; len(openingHours) > 0 {
-		existingPlace.OpeningHours = openingHours[0]
-	}
-	if description := form.Value["description"]; len(description) > 0 {
-		existingPlace.Description = description[0]
-	}
-	if typeField := form.Value["type"]; len(typeField) > 0 {
-		existingPlace.Type = typeField[0]
-	}
-	if latitude := form.Value["latitude"]; len(latitude) > 0 {
-		lat, err := strconv.ParseFloat(latitude[0], 64)
-		if err == nil {
-			existingPlace.Latitude = lat
+	var amenitySlugs []string
+	updateAmenities := false
+	var errs validation.Errors
+
+	if isJSONRequest(ctx) {
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
 		}
-	}
-	if longitude := form.Value["longitude"]; len(longitude) > 0 {
-		lon, err := strconv.ParseFloat(longitude[0], 64)
-		if err == nil {
-			existingPlace.Longitude = lon
+		patch, err := applyPlacePatch(&existingPlace, body)
+		if err != nil {
+			respondInvalidPlace(ctx, err)
+			return
 		}
-	}
-	if files, ok := form.File["logo"]; ok && len(files) > 0 {
-		if existingPlace.Logo != "" {
-			if err := os.Remove(existingPlace.Logo); err != nil {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete existing logo"})
-				return
+		amenitySlugs, updateAmenities = patch.Amenities, patch.UpdateAmenities
+	} else {
+		if err := ctx.Request.ParseMultipartForm(32 << 20); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form data"})
+			return
+		}
+
+		form := ctx.Request.MultipartForm
+
+		if name := form.Value["name"]; len(name) > 0 {
+			existingPlace.Name = name[0]
+		}
+		if vicinity := form.Value["vicinity"]; len(vicinity) > 0 {
+			existingPlace.Vicinity = vicinity[0]
+		}
+		if city := form.Value["city"]; len(city) > 0 {
+			existingPlace.City = city[0]
+		}
+		if postcode := form.Value["postcode"]; len(postcode) > 0 {
+			existingPlace.Postcode = postcode[0]
+		}
+		if phone := form.Value["phone"]; len(phone) > 0 {
+			existingPlace.Phone = phone[0]
+		}
+		if email := form.Value["email"]; len(email) > 0 {
+			existingPlace.Email = email[0]
+		}
+		if website := form.Value["website"]; len(website) > 0 {
+			existingPlace.Website = website[0]
+		}
+		if openingHours := form.Value["opening_hours"]; len(openingHours) > 0 {
+			existingPlace.OpeningHours = openingHours[0]
+		}
+		if description := form.Value["description"]; len(description) > 0 {
+			existingPlace.Description = description[0]
+		}
+		if typeField := form.Value["type"]; len(typeField) > 0 {
+			existingPlace.Type = typeField[0]
+		}
+		if latitude := form.Value["latitude"]; len(latitude) > 0 {
+			if lat := parseFormCoordinate(&errs, "latitude", latitude[0]); lat != nil {
+				existingPlace.Latitude = *lat
+			} else if !errs.Has("latitude") {
+				errs.Add("latitude", validation.CodeRequired, "latitude cannot be cleared")
 			}
 		}
-		file := files[0]
-		sanitizedFilename := fmt.Sprintf("%d_%s", time.Now().Unix(), filepath.Base(file.Filename))
-		sanitizedFilename = filepath.Clean(sanitizedFilename)
-		logoFilePath := "./uploads/logos/" + sanitizedFilename
-		if err := saveUploadedFile(file, logoFilePath); err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save logo"})
-			return
+		if longitude := form.Value["longitude"]; len(longitude) > 0 {
+			if lon := parseFormCoordinate(&errs, "longitude", longitude[0]); lon != nil {
+				existingPlace.Longitude = *lon
+			} else if !errs.Has("longitude") {
+				errs.Add("longitude", validation.CodeRequired, "longitude cannot be cleared")
+			}
 		}
-		existingPlace.Logo = logoFilePath
-	}
-	if files, ok := form.File["facilities_image"]; ok && len(files) > 0 {
-		if existingPlace.FacilitiesImage != "" {
-			if err := os.Remove(existingPlace.FacilitiesImage); err != nil {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete existing facilities image"})
+		if files, ok := form.File["logo"]; ok && len(files) > 0 {
+			logoFilePath, err := staged.Upload(ctx, files[0], "logos")
+			if err != nil {
+				respondUploadError(ctx, "Failed to save logo", err)
 				return
 			}
+			existingPlace.Logo = logoFilePath
 		}
-		file := files[0]
-		sanitizedFilename := fmt.Sprintf("%d_%s", time.Now().Unix(), filepath.Base(file.Filename))
-		sanitizedFilename = filepath.Clean(sanitizedFilename)
-		facilitiesImageFilePath := "./uploads/facilities/" + sanitizedFilename
-		if err := saveUploadedFile(file, facilitiesImageFilePath); err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save facilities image"})
-			return
+		if files, ok := form.File["facilities_image"]; ok && len(files) > 0 {
+			facilitiesImageFilePath, err := staged.Upload(ctx, files[0], "facilities")
+			if err != nil {
+				respondUploadError(ctx, "Failed to save facilities image", err)
+				return
+			}
+			existingPlace.FacilitiesImage = facilitiesImageFilePath
+		}
+		if values, ok := form.Value["amenities"]; ok {
+			amenitySlugs, updateAmenities = values, true
 		}
-		existingPlace.FacilitiesImage = facilitiesImageFilePath
+		if UserID := form.Value["userID"]; len(UserID) > 0 {
+			userID, err := strconv.Atoi(UserID[0])
+			if err == nil {
+				existingPlace.UserID = uint(userID)
+			}
+		}
+	}
+
+	if err := validation.PlaceChange(original, &existingPlace); err != nil {
+		errs = append(errs, err.(validation.Errors)...)
+	}
+	if len(errs) > 0 {
+		respondInvalidPlace(ctx, errs)
+		return
 	}
 
-	if UserID := form.Value["userID"]; len(UserID) > 0 {
-		userID, err := strconv.Atoi(UserID[0])
-		if err == nil {
-			existingPlace.UserID = uint(userID)
+	var amenities []models.Amenity
+	if updateAmenities {
+		var err error
+		amenities, err = resolveAmenities(pc.DB, parseAmenityList(amenitySlugs))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
 	}
 
-	if err := pc.DB.Save(&existingPlace).Error; err != nil {
+	err := pc.DB.Transaction(func(tx *gorm.DB) error {
+		return savePlace(tx, original, &existingPlace, amenities, updateAmenities, models.PlaceRevision{
+			Action:   models.RevisionUpdate,
+			AuthorID: revisionAuthorID(ctx),
+		})
+	})
+	if err == errVersionConflict {
+		pc.respondVersionConflict(ctx, existingPlace.ID)
+		return
+	}
+	if err == slug.ErrTaken {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println("Error updating activity:", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update activity"})
 		return
 	}
+	staged.RemoveReplaced(original, existingPlace)
+	staged.Commit(ctx)
+	pc.DB.Preload("Amenities").First(&existingPlace, existingPlace.ID)
 
-	log.Printf("Received Form Values: %+v", form.Value)
-	log.Printf("Received Files: %+v", form.File)
-
+	ctx.Header("ETag", placeETag(existingPlace))
 	ctx.JSON(http.StatusOK, gin.H{
 		"message":  "Activity updated successfully",
-		"activity": existingPlace,
+		"activity": placeWithURLs(pc.Store, existingPlace),
 	})
 }
 
@@ -465,7 +599,7 @@ func (pc *PlaceController) RenderDeleteActivityForm(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"title":    "Delete Activity Form",
-		"activity": existingPlace,
+		"activity": placeWithURLs(pc.Store, existingPlace),
 	})
 }
 
@@ -488,30 +622,24 @@ func (pc *PlaceController) DeleteActivity(ctx *gin.Context) {
 		return
 	}
 
-	if place.Logo != "" {
-		logoPath := place.Logo
-		// fmt.Println("Logo Path:", logoPath)
-		if fileExists(logoPath) {
-			// fmt.Println("File exists:", logoPath)
-			if err := os.Remove(logoPath); err != nil {
-				fmt.Printf("Failed to delete logo file: %s: %s\n", logoPath, err)
-			}
-		}
+	if !pc.checkIfMatch(ctx, place) {
+		return
 	}
 
-	// Handle facilities image deletion
-	if place.FacilitiesImage != "" {
-		facilitiesPath := place.FacilitiesImage
-		// fmt.Println("Facilities Path:", facilitiesPath)
-		if fileExists(facilitiesPath) {
-			// fmt.Println("File exists:", facilitiesPath)
-			if err := os.Remove(facilitiesPath); err != nil {
-				fmt.Printf("Failed to delete facilities image file: %s: %s\n", facilitiesPath, err)
-			}
-		}
+	// The row is only soft-deleted; its media stays until the place is purged
+	// from the trash so that it can still be restored.
+	err := pc.DB.Transaction(func(tx *gorm.DB) error {
+		return trashPlace(tx, &place, true, models.PlaceRevision{
+			Action:   models.RevisionDelete,
+			AuthorID: revisionAuthorID(ctx),
+		})
+	})
+	if err == errVersionConflict {
+		pc.respondVersionConflict(ctx, place.ID)
+		return
 	}
-
-	if err := pc.DB.Delete(&place).Error; err != nil {
+	if err != nil {
+		log.Println("Error deleting activity:", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete activity"})
 		return
 	}
@@ -519,43 +647,3 @@ func (pc *PlaceController) DeleteActivity(ctx *gin.Context) {
 	fmt.Println("Request Method:", ctx.Request.Method)
 	ctx.JSON(http.StatusOK, gin.H{"message": "Activity deleted successfully"})
 }
-
-func fileExists(filePath string) bool {
-	info, err := os.Stat(filePath)
-	if os.IsNotExist(err) {
-		return false
-	}
-	return !info.IsDir()
-}
-
-func saveUploadedFile(file *multipart.FileHeader, path string) error {
-	src, err := file.Open()
-	if err != nil {
-		return err
-	}
-	defer src.Close()
-
-	dst, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer dst.Close()
-
-	_, err = io.Copy(dst, src)
-	return err
-}
-
-func calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
-	const earthRadius = 6371e3 // Earth radius in meters
-	lat1Rad := lat1 * math.Pi / 180
-	lat2Rad := lat2 * math.Pi / 180
-	deltaLat := (lat2 - lat1) * math.Pi / 180
-	deltaLon := (lon2 - lon1) * math.Pi / 180
-
-	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
-		math.Cos(lat1Rad)*math.Cos(lat2Rad)*
-			math.Sin(deltaLon/2)*math.Sin(deltaLon/2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-
-	return earthRadius * c
-}
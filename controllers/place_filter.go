@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/geo"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+)
+
+// placeFilter holds the search parameters shared by the locator and the
+// export. Places must be loaded with their Prices and Amenities to be
+// matched.
+type placeFilter struct {
+	Type            string
+	HasLocation     bool
+	Lat, Lng        float64
+	Radius          float64
+	Currency        string
+	MaxPence        *int
+	HasDayPass      bool
+	Amenities       []string
+	MatchAnyAmenity bool
+}
+
+func parsePlaceFilter(ctx *gin.Context) (placeFilter, error) {
+	filter := placeFilter{
+		Type:      ctx.Query("type"),
+		Currency:  strings.ToUpper(ctx.DefaultQuery("currency", "GBP")),
+		Amenities: parseAmenityList([]string{ctx.Query("amenities")}),
+	}
+
+	switch ctx.DefaultQuery("amenities_match", "all") {
+	case "all":
+	case "any":
+		filter.MatchAnyAmenity = true
+	default:
+		return filter, fmt.Errorf("amenities_match must be all or any")
+	}
+
+	if maxPriceParam := ctx.Query("max_price"); maxPriceParam != "" {
+		pence, err := parseMaxPrice(maxPriceParam)
+		if err != nil {
+			return filter, err
+		}
+		filter.MaxPence = &pence
+	}
+
+	if hasDayPassParam := ctx.Query("has_day_pass"); hasDayPassParam != "" {
+		value, err := strconv.ParseBool(hasDayPassParam)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid has_day_pass value")
+		}
+		filter.HasDayPass = value
+	}
+
+	latParam, lngParam, radiusParam := ctx.Query("lat"), ctx.Query("lng"), ctx.Query("radius")
+	if latParam != "" && lngParam != "" && radiusParam != "" {
+		var err error
+		if filter.Lat, err = strconv.ParseFloat(latParam, 64); err != nil {
+			return filter, fmt.Errorf("Invalid lat value")
+		}
+		if filter.Lng, err = strconv.ParseFloat(lngParam, 64); err != nil {
+			return filter, fmt.Errorf("Invalid lng value")
+		}
+		if filter.Radius, err = strconv.ParseFloat(radiusParam, 64); err != nil {
+			return filter, fmt.Errorf("Invalid radius value")
+		}
+		filter.HasLocation = true
+	}
+	return filter, nil
+}
+
+// matches reports whether place passes every filter. Without a location any
+// distance is accepted.
+func (f placeFilter) matches(place models.Place) bool {
+	// Type filter (case insensitive)
+	if f.Type != "" && !strings.EqualFold(place.Type, f.Type) {
+		return false
+	}
+	if !matchesPriceFilters(place, f.MaxPence, f.HasDayPass, f.Currency) {
+		return false
+	}
+	if !matchesAmenities(place, f.Amenities, f.MatchAnyAmenity) {
+		return false
+	}
+	if f.HasLocation {
+		return geo.Distance(f.Lat, f.Lng, place.Latitude, place.Longitude) <= f.Radius
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+	"github.com/laurawarren88/go_spa_backend.git/validation"
+)
+
+// isJSONRequest reports whether the request body is JSON, including JSON
+// Merge Patch documents.
+func isJSONRequest(ctx *gin.Context) bool {
+	switch ctx.ContentType() {
+	case "application/json", "application/merge-patch+json":
+		return true
+	}
+	return false
+}
+
+// placeTextFields are the string fields of a place that a JSON merge patch
+// may set, or clear by sending null.
+var placeTextFields = map[string]func(*models.Place) *string{
+	"name":          func(p *models.Place) *string { return &p.Name },
+	"vicinity":      func(p *models.Place) *string { return &p.Vicinity },
+	"city":          func(p *models.Place) *string { return &p.City },
+	"postcode":      func(p *models.Place) *string { return &p.Postcode },
+	"phone":         func(p *models.Place) *string { return &p.Phone },
+	"email":         func(p *models.Place) *string { return &p.Email },
+	"website":       func(p *models.Place) *string { return &p.Website },
+	"opening_hours": func(p *models.Place) *string { return &p.OpeningHours },
+	"description":   func(p *models.Place) *string { return &p.Description },
+	"type":          func(p *models.Place) *string { return &p.Type },
+}
+
+// placeMediaFields can only be pointed at an image hosted elsewhere or cleared
+// through JSON; uploads go through multipart requests.
+var placeMediaFields = map[string]func(*models.Place) *string{
+	"logo":             func(p *models.Place) *string { return &p.Logo },
+	"facilities_image": func(p *models.Place) *string { return &p.FacilitiesImage },
+}
+
+type placePatchResult struct {
+	// Amenities is the new list of amenity slugs when UpdateAmenities is set.
+	Amenities       []string
+	UpdateAmenities bool
+}
+
+// applyPlacePatch applies a JSON Merge Patch (RFC 7396) to place. Members that
+// are absent are left unchanged and members set to null are cleared. Members
+// of the wrong type are reported as validation.Errors.
+func applyPlacePatch(place *models.Place, body []byte) (placePatchResult, error) {
+	var result placePatchResult
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		return result, fmt.Errorf("request body must be a JSON object")
+	}
+
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs validation.Errors
+	for _, key := range keys {
+		value := patch[key]
+		isNull := bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+
+		if field, ok := placeTextFields[key]; ok {
+			*field(place) = ""
+			if !isNull && json.Unmarshal(value, field(place)) != nil {
+				errs.Add(key, validation.CodeInvalidType, "%s must be a string or null", key)
+			}
+			continue
+		}
+
+		if field, ok := placeMediaFields[key]; ok {
+			var ref string
+			if !isNull && (json.Unmarshal(value, &ref) != nil || (ref != "" && !storage.IsExternal(ref))) {
+				errs.Add(key, validation.CodeInvalidURL, "%s must be an http(s) URL or null", key)
+				continue
+			}
+			*field(place) = ref
+			continue
+		}
+
+		switch key {
+		case "latitude", "longitude":
+			coordinate := &place.Latitude
+			if key == "longitude" {
+				coordinate = &place.Longitude
+			}
+			if isNull {
+				errs.Add(key, validation.CodeRequired, "%s cannot be cleared", key)
+			} else if json.Unmarshal(value, coordinate) != nil {
+				errs.Add(key, validation.CodeInvalidType, "%s must be a number", key)
+			}
+		case "amenities":
+			result.UpdateAmenities = true
+			result.Amenities = nil
+			if !isNull && json.Unmarshal(value, &result.Amenities) != nil {
+				errs.Add(key, validation.CodeInvalidType, "amenities must be a list of slugs or null")
+			}
+		default:
+			errs.Add(key, validation.CodeReadOnly, "%s cannot be updated", key)
+		}
+	}
+	return result, errs.Err()
+}
+
+// respondInvalidPlace answers 400 with the field-level problems in err, or
+// with err's message when it is not a validation error.
+func respondInvalidPlace(ctx *gin.Context, err error) {
+	var errs validation.Errors
+	if errors.As(err, &errs) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "errors": errs})
+		return
+	}
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input: " + err.Error()})
+}
+
+// parseFormCoordinate parses a latitude or longitude form value. It returns
+// nil when the value is empty or, after recording a field error, not a number.
+func parseFormCoordinate(errs *validation.Errors, field, value string) *float64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	coordinate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		errs.Add(field, validation.CodeInvalidNumber, "%s must be a number", field)
+		return nil
+	}
+	return &coordinate
+}
@@ -0,0 +1,132 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchActivityMergeSemantics(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(&[]models.Amenity{{Slug: "pool", Name: "Pool"}, {Slug: "sauna", Name: "Sauna"}}).Error)
+	assert.NoError(t, store.Put(ctx, "logos/old.jpg", strings.NewReader("old"), "image/jpeg"))
+	assert.NoError(t, db.Model(&models.Place{}).Where("id = ?", 1).Updates(map[string]interface{}{
+		"website": "https://example.com", "city": "Manchester", "logo": "logos/old.jpg",
+		"latitude": 53.48, "longitude": -2.24,
+	}).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, store)
+	r.PATCH("/activity/:id", controller.UpdateActivity)
+
+	patch := func(body string) (int, models.Place) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PATCH", "/activity/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", "*")
+		r.ServeHTTP(w, req)
+
+		var place models.Place
+		db.Preload("Amenities").First(&place, 1)
+		return w.Code, place
+	}
+
+	code, place := patch(`{"name": "Renamed", "website": null, "amenities": ["pool", "sauna"]}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Renamed", place.Name)
+	assert.Equal(t, "", place.Website)
+	assert.Equal(t, "Manchester", place.City)
+	assert.Equal(t, "Test Description", place.Description)
+	assert.Len(t, place.Amenities, 2)
+
+	code, place = patch(`{"amenities": null, "logo": null}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, place.Amenities)
+	assert.Equal(t, "", place.Logo)
+	objects, err := store.List(ctx, "logos/")
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+
+	for _, body := range []string{
+		`{"latitude": null}`,
+		`{"name": ""}`,
+		`{"name": 42}`,
+		`{"user_id": 7}`,
+		`{"logo": "logos/someone-elses.jpg"}`,
+		`{"amenities": ["helipad"]}`,
+		`[1, 2]`,
+	} {
+		code, place = patch(body)
+		assert.Equal(t, http.StatusBadRequest, code, body)
+		assert.Equal(t, "Renamed", place.Name, body)
+		assert.Equal(t, 53.48, place.Latitude, body)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/activity/1", bytes.NewBufferString(`{"city": "Salford"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", "*")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Activity models.Place `json:"activity"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Salford", response.Activity.City)
+	assert.Equal(t, "Renamed", response.Activity.Name)
+}
+
+func TestPatchImportedActivityWithoutPhone(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+	osmID := "node/42"
+	imported := models.Place{Name: "Harbour Gym", Description: "Imported from OpenStreetMap",
+		Latitude: 53.48, Longitude: -2.24, OSMID: &osmID, UserID: 1}
+	assert.NoError(t, db.Create(&imported).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, newTestStore(t))
+	r.PATCH("/activity/:id", controller.UpdateActivity)
+
+	patch := func(body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PATCH", fmt.Sprintf("/activity/%d", imported.ID), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", "*")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// The owner can edit other fields without inventing a phone number.
+	assert.Equal(t, http.StatusOK, patch(`{"opening_hours": "06:00-22:00"}`))
+	assert.Equal(t, http.StatusBadRequest, patch(`{"phone": "not a phone"}`))
+	assert.Equal(t, http.StatusOK, patch(`{"phone": "0161 496 0000"}`))
+	assert.Equal(t, http.StatusBadRequest, patch(`{"phone": null}`))
+
+	var place models.Place
+	assert.NoError(t, db.First(&place, imported.ID).Error)
+	assert.Equal(t, "06:00-22:00", place.OpeningHours)
+	assert.Equal(t, "+441614960000", place.Phone)
+}
@@ -1,6 +1,7 @@
 package controllers_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/laurawarren88/go_spa_backend.git/controllers"
 	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -17,13 +19,31 @@ import (
 
 // Setup test DB
 func setupTestDB() (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	return openTestDB(":memory:")
+}
+
+func openTestDB(dsn string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
 
 	// Auto migrate the test database
-	err = db.AutoMigrate(&models.Place{}, &models.User{})
+	err = db.AutoMigrate(
+		&models.Place{},
+		&models.User{},
+		&models.Class{},
+		&models.ClassSchedule{},
+		&models.ClassSession{},
+		&models.Booking{},
+		&models.Event{},
+		&models.PlacePrice{},
+		&models.Amenity{},
+		&models.PlaceImage{},
+		&models.PlaceRevision{},
+		&models.PlaceRedirect{},
+		&models.PlaceSlug{},
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -48,11 +68,18 @@ func createTestData(db *gorm.DB) error {
 	place := models.Place{
 		Name:        "Test Place",
 		Description: "Test Description",
-		Phone:       "1234567890",
+		Phone:       "0161 496 0000",
 		UserID:      user.ID,
 	}
 	return db.Create(&place).Error
 }
+
+// newTestStore keeps uploads in a temporary directory so tests do not write
+// into the source tree.
+func newTestStore(t *testing.T) *storage.Local {
+	return storage.NewLocal(t.TempDir(), "/uploads")
+}
+
 func TestGetActivityById(t *testing.T) {
 	db, err := setupTestDB()
 	assert.NoError(t, err)
@@ -66,7 +93,7 @@ func TestGetActivityById(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
-	controller := controllers.NewPlaceController(db)
+	controller := controllers.NewPlaceController(db, newTestStore(t))
 
 	r.GET("/activity/:id", controller.GetActivityById)
 
@@ -96,7 +123,7 @@ func TestCheckActivityOwnership(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
-	controller := controllers.NewPlaceController(db)
+	controller := controllers.NewPlaceController(db, newTestStore(t))
 
 	r.Use(func(c *gin.Context) {
 		c.Set("userID", uint(1))
@@ -117,3 +144,62 @@ func TestCheckActivityOwnership(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, response["isOwner"].(bool))
 }
+
+func TestCreateActivityValidation(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, newTestStore(t))
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/activities/new", controller.CreateActivity)
+
+	create := func(fields map[string]any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(fields)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/activities/new", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Greenwich sits on longitude 0, which used to be rejected as missing.
+	w := create(map[string]any{
+		"name": "Meridian Gym", "phone": "020 7946 0000", "description": "Gym",
+		"postcode": "se10 8xj", "latitude": 51.4779, "longitude": 0,
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Activity models.Place `json:"activity"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "+442079460000", created.Activity.Phone)
+	assert.Equal(t, "SE10 8XJ", created.Activity.Postcode)
+
+	w = create(map[string]any{"name": "No Phone", "email": "not-an-email", "latitude": 51.5})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response struct {
+		Errors []struct {
+			Field string `json:"field"`
+			Code  string `json:"code"`
+		} `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	found := map[string]string{}
+	for _, e := range response.Errors {
+		found[e.Field] = e.Code
+	}
+	assert.Equal(t, map[string]string{
+		"longitude":   "required",
+		"phone":       "required",
+		"description": "required",
+		"email":       "invalid_email",
+	}, found)
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"gorm.io/gorm"
+)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+func validatePrice(price models.PlacePrice) error {
+	switch price.Kind {
+	case models.PriceMembership, models.PriceDayPass, models.PriceClassPack, models.PricePayAsYouGo:
+	default:
+		return fmt.Errorf("kind must be one of %s, %s, %s or %s",
+			models.PriceMembership, models.PriceDayPass, models.PriceClassPack, models.PricePayAsYouGo)
+	}
+	switch price.BillingPeriod {
+	case "", models.BillingOnce, models.BillingWeek, models.BillingMonth, models.BillingYear:
+	default:
+		return fmt.Errorf("billing_period must be one of %s, %s, %s or %s",
+			models.BillingOnce, models.BillingWeek, models.BillingMonth, models.BillingYear)
+	}
+	if price.Kind == models.PriceMembership && price.BillingPeriod == "" {
+		return fmt.Errorf("memberships need a billing_period")
+	}
+	if price.Kind == models.PriceClassPack && price.Sessions <= 0 {
+		return fmt.Errorf("class packs need a number of sessions")
+	}
+	if price.AmountPence < 0 {
+		return fmt.Errorf("amount_pence cannot be negative")
+	}
+	if price.Currency != "" && !currencyPattern.MatchString(price.Currency) {
+		return fmt.Errorf("currency must be a three letter ISO 4217 code")
+	}
+	if len(price.Name) > 100 || len(price.Concession) > 50 || len(price.Notes) > 255 {
+		return fmt.Errorf("name, concession or notes is too long")
+	}
+	return nil
+}
+
+func (pc *PlaceController) GetPrices(ctx *gin.Context) {
+	var place models.Place
+	if err := pc.DB.Preload("Prices").First(&place, "id = ?", ctx.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"prices": place.Prices,
+		"total":  len(place.Prices),
+	})
+}
+
+// UpdatePrices replaces the full list of pricing options for a place.
+func (pc *PlaceController) UpdatePrices(ctx *gin.Context) {
+	var place models.Place
+	if err := pc.DB.First(&place, "id = ?", ctx.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		}
+		return
+	}
+
+	var input struct {
+		Prices []models.PlacePrice `json:"prices"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input: " + err.Error()})
+		return
+	}
+
+	prices := make([]models.PlacePrice, 0, len(input.Prices))
+	for i, price := range input.Prices {
+		price.Currency = strings.ToUpper(price.Currency)
+		if err := validatePrice(price); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid price %d: %s", i+1, err.Error())})
+			return
+		}
+		if price.Currency == "" {
+			price.Currency = "GBP"
+		}
+		if price.Kind == models.PriceDayPass && price.BillingPeriod == "" {
+			price.BillingPeriod = models.BillingOnce
+		}
+		prices = append(prices, models.PlacePrice{
+			PlaceID:       place.ID,
+			Kind:          price.Kind,
+			Name:          price.Name,
+			AmountPence:   price.AmountPence,
+			Currency:      price.Currency,
+			BillingPeriod: price.BillingPeriod,
+			Sessions:      price.Sessions,
+			Concession:    price.Concession,
+			Notes:         price.Notes,
+		})
+	}
+
+	err := pc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("place_id = ?", place.ID).Delete(&models.PlacePrice{}).Error; err != nil {
+			return err
+		}
+		if len(prices) > 0 {
+			return tx.Create(&prices).Error
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("Error saving prices:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prices"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Prices updated successfully",
+		"prices":  prices,
+	})
+}
+
+// parseMaxPrice converts a max_price query value in major units (e.g. "25" or
+// "19.99") into pence. Amounts whose pence would not fit in 32 bits are
+// rejected rather than overflowing.
+func parseMaxPrice(value string) (int, error) {
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || amount < 0 || math.IsNaN(amount) || amount > math.MaxInt32/100 {
+		return 0, fmt.Errorf("invalid max_price value")
+	}
+	return int(math.Round(amount * 100)), nil
+}
+
+// perVisitKinds are the prices paid for a single visit, which max_price is
+// compared against. Memberships and class packs cover many visits.
+var perVisitKinds = map[string]bool{
+	models.PriceDayPass:    true,
+	models.PricePayAsYouGo: true,
+}
+
+// matchesPriceFilters reports whether place has a per-visit price in currency
+// at or below maxPence (when set), and a day pass when hasDayPass is requested.
+func matchesPriceFilters(place models.Place, maxPence *int, hasDayPass bool, currency string) bool {
+	if hasDayPass {
+		found := false
+		for _, price := range place.Prices {
+			if price.Kind == models.PriceDayPass {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if maxPence != nil {
+		for _, price := range place.Prices {
+			if perVisitKinds[price.Kind] && strings.EqualFold(price.Currency, currency) && price.AmountPence <= *maxPence {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
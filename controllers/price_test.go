@@ -0,0 +1,102 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdatePricesAndLocatorFilters(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Model(&models.Place{}).Where("id = ?", 1).
+		Updates(map[string]any{"latitude": 53.48, "longitude": -2.24}).Error)
+	cheap := models.Place{Name: "Cheap Gym", Latitude: 53.481, Longitude: -2.241, UserID: 1,
+		Prices: []models.PlacePrice{{Kind: models.PriceMembership, AmountPence: 1499, Currency: "GBP", BillingPeriod: models.BillingMonth}}}
+	assert.NoError(t, db.Create(&cheap).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, newTestStore(t))
+	r.PUT("/activity/:id/prices", controller.UpdatePrices)
+	r.GET("/activity/:id", controller.GetActivityById)
+	r.GET("/locator", controller.GetPlaceLocator)
+
+	body, _ := json.Marshal(map[string]any{
+		"prices": []map[string]any{
+			{"kind": "membership", "name": "Peak", "amount_pence": 3500, "billing_period": "month"},
+			{"kind": "membership", "name": "Peak", "amount_pence": 2500, "billing_period": "month", "concession": "student"},
+			{"kind": "day_pass", "amount_pence": 800},
+			{"kind": "class_pack", "amount_pence": 4000, "sessions": 10, "currency": "gbp"},
+		},
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/activity/1/prices", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/activity/1", nil)
+	r.ServeHTTP(w, req)
+	var activity struct {
+		Prices []models.PlacePrice `json:"prices"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &activity))
+	assert.Len(t, activity.Prices, 4)
+	assert.Equal(t, models.BillingOnce, activity.Prices[2].BillingPeriod)
+	assert.Equal(t, "GBP", activity.Prices[3].Currency)
+
+	names := func(query string) []string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/locator?lat=53.48&lng=-2.24&radius=1000"+query, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Places []models.Place `json:"places"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		var result []string
+		for _, place := range response.Places {
+			result = append(result, place.Name)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"Test Place", "Cheap Gym"}, names(""))
+	assert.Equal(t, []string{"Test Place"}, names("&has_day_pass=true"))
+	// Only day passes and pay-as-you-go prices count: Cheap Gym's £14.99
+	// monthly membership does not make it a visit under £15.
+	assert.Equal(t, []string{"Test Place"}, names("&max_price=15"))
+	assert.Equal(t, []string{"Test Place"}, names("&max_price=8"))
+	assert.Empty(t, names("&max_price=7.99"))
+	assert.NoError(t, db.Create(&models.PlacePrice{PlaceID: cheap.ID, Kind: models.PricePayAsYouGo, AmountPence: 500, Currency: "GBP"}).Error)
+	assert.Equal(t, []string{"Cheap Gym"}, names("&max_price=5"))
+	assert.Len(t, names("&max_price=21474836"), 2)
+	for _, value := range []string{"NaN", "-1", "Inf", "1e20", "21474837", "cheap"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/locator?lat=53.48&lng=-2.24&radius=1000&max_price="+value, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, value)
+	}
+
+	body, _ = json.Marshal(map[string]any{
+		"prices": []map[string]any{{"kind": "class_pack", "amount_pence": 4000}},
+	})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/activity/1/prices", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/slug"
+	"github.com/laurawarren88/go_spa_backend.git/validation"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type fieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type revisionAuthor struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+// revisionAuthorID returns the authenticated user, if any, as a revision author.
+func revisionAuthorID(ctx *gin.Context) *uint {
+	if userID, ok := ctx.Get("userID"); ok {
+		if id, ok := userID.(uint); ok {
+			return &id
+		}
+	}
+	return nil
+}
+
+// recordRevision stores the current state of place, amenities included, as a
+// new revision.
+func recordRevision(tx *gorm.DB, place models.Place, revision models.PlaceRevision) error {
+	var amenities []models.Amenity
+	if err := tx.Model(&place).Association("Amenities").Find(&amenities); err != nil {
+		return err
+	}
+	record, err := models.NewPlaceRevision(place, amenities, revision.Action, revision.AuthorID)
+	if err != nil {
+		return err
+	}
+	record.RevertOf = revision.RevertOf
+	return tx.Create(&record).Error
+}
+
+// recordBaseline gives a place saved before history was kept a baseline
+// revision of its current state, so the change about to be made can be
+// reverted.
+func recordBaseline(tx *gorm.DB, place models.Place) error {
+	var count int64
+	if err := tx.Model(&models.PlaceRevision{}).Where("place_id = ?", place.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return recordRevision(tx, place, models.PlaceRevision{Action: models.RevisionBaseline})
+}
+
+// savePlace writes place provided nobody has saved it since original was
+// loaded, replaces its amenities when updateAmenities is set and records the
+// change in the revision history. Losing a race for the new slug returns
+// slug.ErrTaken.
+func savePlace(tx *gorm.DB, original models.Place, place *models.Place, amenities []models.Amenity, updateAmenities bool, revision models.PlaceRevision) error {
+	if err := recordBaseline(tx, original); err != nil {
+		return err
+	}
+
+	if err := slug.Assign(tx, place); err != nil {
+		return slug.Conflict(tx, err)
+	}
+	place.Version = original.Version + 1
+	result := tx.Model(place).Where("version = ?", original.Version).
+		Select("*").Omit("created_at", clause.Associations).Updates(place)
+	if result.Error != nil {
+		return slug.Conflict(tx, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errVersionConflict
+	}
+	if updateAmenities {
+		if err := tx.Model(place).Association("Amenities").Replace(amenities); err != nil {
+			return err
+		}
+	}
+	return recordRevision(tx, *place, revision)
+}
+
+// trashPlace moves place into the trash, or out of it when deleted is false,
+// and records the change in the revision history. Like savePlace it bumps the
+// version, so an If-Match from before the change no longer matches, and fails
+// with errVersionConflict if someone saved the place since it was loaded. A
+// restored place that had been merged into another needs a slug of its own
+// again.
+func trashPlace(tx *gorm.DB, place *models.Place, deleted bool, revision models.PlaceRevision) error {
+	if err := recordBaseline(tx, *place); err != nil {
+		return err
+	}
+
+	version := place.Version
+	changes := map[string]interface{}{"version": version + 1}
+	if deleted {
+		place.DeletedAt = gorm.DeletedAt{Time: tx.NowFunc(), Valid: true}
+		changes["deleted_at"] = place.DeletedAt.Time
+	} else {
+		if err := slug.Assign(tx, place); err != nil {
+			return slug.Conflict(tx, err)
+		}
+		place.DeletedAt = gorm.DeletedAt{}
+		changes["deleted_at"] = nil
+		changes["slug"] = place.Slug
+	}
+	result := tx.Unscoped().Model(place).Where("version = ?", version).Updates(changes)
+	if result.Error != nil {
+		return slug.Conflict(tx, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errVersionConflict
+	}
+	place.Version = version + 1
+	return recordRevision(tx, *place, revision)
+}
+
+// diffSnapshots lists the fields that differ between two snapshots. A nil
+// previous snapshot reports every field as new.
+func diffSnapshots(previous, current *models.PlaceSnapshot) []fieldChange {
+	toMap := func(snapshot *models.PlaceSnapshot) map[string]interface{} {
+		values := map[string]interface{}{}
+		if snapshot != nil {
+			data, _ := json.Marshal(snapshot)
+			json.Unmarshal(data, &values)
+		}
+		return values
+	}
+	from, to := toMap(previous), toMap(current)
+
+	fields := make([]string, 0, len(to))
+	for field := range to {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	changes := []fieldChange{}
+	for _, field := range fields {
+		if !reflect.DeepEqual(from[field], to[field]) {
+			changes = append(changes, fieldChange{Field: field, From: from[field], To: to[field]})
+		}
+	}
+	return changes
+}
+
+func (pc *PlaceController) GetRevisions(ctx *gin.Context) {
+	var place models.Place
+	if err := pc.DB.First(&place, "id = ?", ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
+		return
+	}
+
+	var revisions []models.PlaceRevision
+	if err := pc.DB.Preload("Author").Where("place_id = ?", place.ID).
+		Order("version ASC, id ASC").Find(&revisions).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revisions"})
+		return
+	}
+
+	results := make([]gin.H, len(revisions))
+	var previous *models.PlaceSnapshot
+	for i, revision := range revisions {
+		snapshot, err := revision.Decode()
+		if err != nil {
+			log.Printf("Invalid snapshot in revision %d: %s", revision.ID, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revisions"})
+			return
+		}
+		var author *revisionAuthor
+		if revision.Author != nil {
+			author = &revisionAuthor{ID: revision.Author.ID, Username: revision.Author.Username}
+		}
+		// Newest first.
+		results[len(revisions)-1-i] = gin.H{
+			"id":         revision.ID,
+			"version":    revision.Version,
+			"action":     revision.Action,
+			"author":     author,
+			"revert_of":  revision.RevertOf,
+			"created_at": revision.CreatedAt,
+			"snapshot":   snapshot,
+			"changes":    diffSnapshots(previous, &snapshot),
+		}
+		previous = &snapshot
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"revisions": results, "total": len(results)})
+}
+
+// RevertActivity restores the fields and amenities recorded in a revision.
+// The revert is saved as a new revision, so it can be undone in turn.
+// Ownership is not reverted, and media that has since been deleted is not
+// brought back; the place keeps its current file instead.
+func (pc *PlaceController) RevertActivity(ctx *gin.Context) {
+	var place models.Place
+	if err := pc.DB.First(&place, "id = ?", ctx.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		}
+		return
+	}
+	if !pc.checkIfMatch(ctx, place) {
+		return
+	}
+
+	var revision models.PlaceRevision
+	if err := pc.DB.Where("id = ? AND place_id = ?", ctx.Param("revisionId"), place.ID).First(&revision).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	snapshot, err := revision.Decode()
+	if err != nil {
+		log.Printf("Invalid snapshot in revision %d: %s", revision.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read revision"})
+		return
+	}
+
+	original := place
+	snapshot.Apply(&place)
+	for _, field := range []struct{ current, reverted *string }{
+		{&original.Logo, &place.Logo},
+		{&original.FacilitiesImage, &place.FacilitiesImage},
+	} {
+		available, err := mediaAvailable(ctx, pc.Store, *field.reverted)
+		if err != nil {
+			log.Println("Error checking media:", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revert activity"})
+			return
+		}
+		if !available {
+			*field.reverted = *field.current
+		}
+	}
+	// A revision without a phone number was saved that way, so reverting to
+	// it may clear the phone.
+	if err := validation.PlaceChange(models.Place{Phone: snapshot.Phone}, &place); err != nil {
+		respondInvalidPlace(ctx, err)
+		return
+	}
+
+	// Amenities removed from the vocabulary since the revision are dropped.
+	amenities := []models.Amenity{}
+	if len(snapshot.Amenities) > 0 {
+		if err := pc.DB.Where("slug IN ?", snapshot.Amenities).Find(&amenities).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve amenities"})
+			return
+		}
+	}
+
+	staged := newMediaChanges(pc.Store)
+	defer staged.Rollback(ctx)
+	err = pc.DB.Transaction(func(tx *gorm.DB) error {
+		return savePlace(tx, original, &place, amenities, true, models.PlaceRevision{
+			Action:   models.RevisionRevert,
+			AuthorID: revisionAuthorID(ctx),
+			RevertOf: &revision.ID,
+		})
+	})
+	if err == errVersionConflict {
+		pc.respondVersionConflict(ctx, place.ID)
+		return
+	}
+	if err == slug.ErrTaken {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println("Error reverting activity:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revert activity"})
+		return
+	}
+	staged.RemoveReplaced(original, place)
+	staged.Commit(ctx)
+	pc.DB.Preload("Amenities").First(&place, place.ID)
+
+	ctx.Header("ETag", placeETag(place))
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":  "Activity reverted successfully",
+		"activity": placeWithURLs(pc.Store, place),
+	})
+}
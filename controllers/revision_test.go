@@ -0,0 +1,218 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivityRevisionHistoryAndRevert(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(&[]models.Amenity{{Slug: "pool", Name: "Pool"}, {Slug: "sauna", Name: "Sauna"}}).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, newTestStore(t))
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.POST("/activities/new", controller.CreateActivity)
+	r.PATCH("/activities/:id", controller.UpdateActivity)
+	r.GET("/activities/:id/revisions", controller.GetRevisions)
+	r.POST("/activities/:id/revisions/:revisionId/revert", controller.RevertActivity)
+
+	send := func(method, url, body, ifMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	type revision struct {
+		ID      uint   `json:"id"`
+		Version uint   `json:"version"`
+		Action  string `json:"action"`
+		Author  *struct {
+			Username string `json:"username"`
+		} `json:"author"`
+		Changes []struct {
+			Field string      `json:"field"`
+			From  interface{} `json:"from"`
+			To    interface{} `json:"to"`
+		} `json:"changes"`
+	}
+	history := func(id uint) []revision {
+		w := send("GET", fmt.Sprintf("/activities/%d/revisions", id), "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Revisions []revision `json:"revisions"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Revisions
+	}
+	changed := func(rev revision) map[string][2]interface{} {
+		fields := map[string][2]interface{}{}
+		for _, change := range rev.Changes {
+			fields[change.Field] = [2]interface{}{change.From, change.To}
+		}
+		return fields
+	}
+
+	w := send("POST", "/activities/new", `{"name": "Harbour Gym", "phone": "0161 496 0001",
+		"description": "Gym", "latitude": 53.48, "longitude": -2.24, "amenities": ["pool"]}`, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Activity models.Place `json:"activity"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	id := created.Activity.ID
+	url := fmt.Sprintf("/activities/%d", id)
+
+	w = send("PATCH", url, `{"name": "Harbour Fitness", "amenities": ["pool", "sauna"]}`, "*")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	revisions := history(id)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "update", revisions[0].Action)
+	assert.Equal(t, uint(2), revisions[0].Version)
+	assert.Equal(t, "testuser", revisions[0].Author.Username)
+	assert.Equal(t, map[string][2]interface{}{
+		"name":      {"Harbour Gym", "Harbour Fitness"},
+		"amenities": {[]interface{}{"pool"}, []interface{}{"pool", "sauna"}},
+	}, changed(revisions[0]))
+	assert.Equal(t, "create", revisions[1].Action)
+	assert.Equal(t, "Harbour Gym", changed(revisions[1])["name"][1])
+
+	revertURL := fmt.Sprintf("%s/revisions/%d/revert", url, revisions[1].ID)
+	assert.Equal(t, http.StatusPreconditionRequired, send("POST", revertURL, "", "").Code)
+	assert.Equal(t, http.StatusPreconditionFailed, send("POST", revertURL, "", fmt.Sprintf(`"%d-1"`, id)).Code)
+	assert.Equal(t, http.StatusNotFound, send("POST", url+"/revisions/999/revert", "", "*").Code)
+
+	// Ownership changes outside the history and is not reverted.
+	newOwner := models.User{Username: "newowner", Email: "new@example.com", Password: "x"}
+	assert.NoError(t, db.Create(&newOwner).Error)
+	assert.NoError(t, db.Model(&models.Place{}).Where("id = ?", id).UpdateColumn("user_id", newOwner.ID).Error)
+
+	w = send("POST", revertURL, "", fmt.Sprintf(`"%d-2"`, id))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, fmt.Sprintf(`"%d-3"`, id), w.Header().Get("ETag"))
+
+	var place models.Place
+	db.Preload("Amenities").First(&place, id)
+	assert.Equal(t, "Harbour Gym", place.Name)
+	assert.Len(t, place.Amenities, 1)
+	assert.Equal(t, newOwner.ID, place.UserID)
+
+	revisions = history(id)
+	assert.Len(t, revisions, 3)
+	assert.Equal(t, "revert", revisions[0].Action)
+	assert.Equal(t, map[string][2]interface{}{
+		"name":      {"Harbour Fitness", "Harbour Gym"},
+		"amenities": {[]interface{}{"pool", "sauna"}, []interface{}{"pool"}},
+	}, changed(revisions[0]))
+}
+
+func TestActivityRevisionBaselineForExistingPlaces(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Model(&models.Place{}).Where("id = ?", 1).Updates(map[string]interface{}{
+		"latitude": 53.48, "longitude": -2.24,
+	}).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, newTestStore(t))
+	r.PATCH("/activities/:id", controller.UpdateActivity)
+	r.GET("/activities/:id/revisions", controller.GetRevisions)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/activities/1", bytes.NewBufferString(`{"city": "Leeds"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", "*")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var revisions []models.PlaceRevision
+	db.Where("place_id = ?", 1).Order("version").Find(&revisions)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, models.RevisionBaseline, revisions[0].Action)
+	assert.Nil(t, revisions[0].AuthorID)
+	assert.Equal(t, uint(1), revisions[0].Version)
+	assert.Equal(t, models.RevisionUpdate, revisions[1].Action)
+
+	baseline, err := revisions[0].Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "", baseline.City)
+	latest, err := revisions[1].Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "Leeds", latest.City)
+}
+
+func TestRevertKeepsMediaThatWasReplaced(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Put(ctx, "logos/old.jpg", strings.NewReader("old"), "image/jpeg"))
+	assert.NoError(t, db.Model(&models.Place{}).Where("id = ?", 1).Updates(map[string]interface{}{
+		"logo": "logos/old.jpg", "latitude": 53.48, "longitude": -2.24,
+	}).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, store)
+	r.PATCH("/activities/:id", controller.UpdateActivity)
+	r.POST("/activities/:id/revisions/:revisionId/revert", controller.RevertActivity)
+
+	send := func(method, url, body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	newLogo := "https://cdn.example.com/new.jpg"
+	assert.Equal(t, http.StatusOK, send("PATCH", "/activities/1", `{"name": "Renamed", "logo": "`+newLogo+`"}`))
+	objects, err := store.List(ctx, "logos/")
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+
+	// The old logo is gone, so reverting restores the name but keeps the
+	// current logo rather than pointing at a missing file.
+	var baseline models.PlaceRevision
+	assert.NoError(t, db.Where("place_id = ? AND action = ?", 1, models.RevisionBaseline).First(&baseline).Error)
+	assert.Equal(t, http.StatusOK, send("POST", fmt.Sprintf("/activities/1/revisions/%d/revert", baseline.ID), ""))
+
+	var place models.Place
+	db.First(&place, 1)
+	assert.Equal(t, "Test Place", place.Name)
+	assert.Equal(t, newLogo, place.Logo)
+}
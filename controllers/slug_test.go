@@ -0,0 +1,130 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/middleware"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestActivitySlugs(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, newTestStore(t))
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Set("db", db)
+		c.Next()
+	})
+	r.POST("/api/activities/new", controller.CreateActivity)
+	r.PATCH("/api/activities/:id", controller.UpdateActivity)
+	r.GET("/api/activities/:id", middleware.ResolveActivity(), controller.GetActivityById)
+	r.GET("/api/activities/:id/prices", middleware.ResolveActivity(), controller.GetPrices)
+
+	send := func(method, url, body, ifMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	create := func() (uint, string) {
+		w := send("POST", "/api/activities/new", `{"name": "PureGym", "city": "Manchester Central",
+			"phone": "0161 496 0001", "description": "Gym", "latitude": 53.48, "longitude": -2.24}`, "")
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response struct {
+			Activity struct {
+				ID   uint   `json:"ID"`
+				Slug string `json:"slug"`
+			} `json:"activity"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Activity.ID, response.Activity.Slug
+	}
+
+	id, first := create()
+	assert.Equal(t, "puregym-manchester-central", first)
+	_, second := create()
+	assert.Equal(t, "puregym-manchester-central-2", second)
+
+	w := send("GET", "/api/activities/puregym-manchester-central", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var details struct {
+		ID   uint   `json:"id"`
+		Slug string `json:"slug"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &details))
+	assert.Equal(t, id, details.ID)
+	assert.Equal(t, http.StatusOK, send("GET", fmt.Sprintf("/api/activities/%d", id), "", "").Code)
+
+	w = send("PATCH", fmt.Sprintf("/api/activities/%d", id), `{"name": "PureGym Piccadilly"}`, fmt.Sprintf(`"%d-1"`, id))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send("GET", "/api/activities/puregym-manchester-central/prices?currency=GBP", "", "")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/api/activities/puregym-piccadilly-manchester-central/prices?currency=GBP", w.Header().Get("Location"))
+	assert.Equal(t, http.StatusOK, send("GET", "/api/activities/puregym-piccadilly-manchester-central", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/api/activities/no-such-gym", "", "").Code)
+}
+
+func TestActivityRenameLosingSlugRace(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, newTestStore(t))
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+	r.PATCH("/api/activities/:id", controller.UpdateActivity)
+
+	var place models.Place
+	assert.NoError(t, db.First(&place).Error)
+
+	// Another rename takes the slug after it has been checked but before the
+	// place is saved.
+	raced := false
+	assert.NoError(t, db.Callback().Update().Before("gorm:update").Register("test:take_slug", func(tx *gorm.DB) {
+		if raced || tx.Statement.Table != "places" {
+			return
+		}
+		raced = true
+		tx.Session(&gorm.Session{NewDB: true}).Create(&models.Place{Name: "Other", Slug: "harbour-gym"})
+	}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", fmt.Sprintf("/api/activities/%d", place.ID), bytes.NewBufferString(`{"name": "Harbour Gym", "city": ""}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", fmt.Sprintf(`"%d-%d"`, place.ID, place.Version))
+	r.ServeHTTP(w, req)
+	assert.True(t, raced)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var unchanged models.Place
+	assert.NoError(t, db.First(&unchanged, place.ID).Error)
+	assert.Equal(t, place.Name, unchanged.Name)
+	assert.Equal(t, place.Version, unchanged.Version)
+}
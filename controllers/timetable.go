@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/laurawarren88/go_spa_backend.git/models"
+)
+
+const (
+	dateLayout       = "2006-01-02"
+	clockLayout      = "15:04"
+	maxTimetableSpan = 92 * 24 * time.Hour
+)
+
+// timetableLocation is the zone class schedules are written in. Venues are UK
+// based, so local times follow Europe/London including daylight saving.
+var timetableLocation = loadTimetableLocation()
+
+func loadTimetableLocation() *time.Location {
+	loc, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+type Session struct {
+	ClassID         uint      `json:"class_id"`
+	PlaceID         uint      `json:"place_id"`
+	Title           string    `json:"title"`
+	Instructor      string    `json:"instructor"`
+	Category        string    `json:"category"`
+	Capacity        int       `json:"capacity"`
+	DurationMinutes int       `json:"duration_minutes"`
+	PricePence      int       `json:"price_pence"`
+	Booked          int       `json:"booked"`
+	Waitlisted      int       `json:"waitlisted"`
+	SpacesLeft      int       `json:"spaces_left"`
+	StartsAt        time.Time `json:"starts_at"`
+	EndsAt          time.Time `json:"ends_at"`
+}
+
+func validateSchedule(schedule models.ClassSchedule) error {
+	switch schedule.Kind {
+	case models.ScheduleWeekly:
+		if schedule.Weekday < 0 || schedule.Weekday > 6 {
+			return fmt.Errorf("weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		if _, err := time.Parse(clockLayout, schedule.StartTime); err != nil {
+			return fmt.Errorf("start_time must be HH:MM")
+		}
+		if schedule.StartsOn != "" {
+			if _, err := time.Parse(dateLayout, schedule.StartsOn); err != nil {
+				return fmt.Errorf("starts_on must be YYYY-MM-DD")
+			}
+		}
+		if schedule.EndsOn != "" {
+			if _, err := time.Parse(dateLayout, schedule.EndsOn); err != nil {
+				return fmt.Errorf("ends_on must be YYYY-MM-DD")
+			}
+		}
+	case models.ScheduleDate:
+		if _, err := time.Parse(dateLayout, schedule.Date); err != nil {
+			return fmt.Errorf("date must be YYYY-MM-DD")
+		}
+		if _, err := time.Parse(clockLayout, schedule.StartTime); err != nil {
+			return fmt.Errorf("start_time must be HH:MM")
+		}
+	case models.ScheduleException:
+		if _, err := time.Parse(dateLayout, schedule.Date); err != nil {
+			return fmt.Errorf("date must be YYYY-MM-DD")
+		}
+		if schedule.StartTime != "" {
+			if _, err := time.Parse(clockLayout, schedule.StartTime); err != nil {
+				return fmt.Errorf("start_time must be HH:MM")
+			}
+		}
+	default:
+		return fmt.Errorf("kind must be one of %s, %s or %s", models.ScheduleWeekly, models.ScheduleDate, models.ScheduleException)
+	}
+	return nil
+}
+
+func localDateTime(date, clock string) (time.Time, error) {
+	return time.ParseInLocation(dateLayout+" "+clockLayout, date+" "+clock, timetableLocation)
+}
+
+// expandClassSessions turns the recurrence rules of a class into the concrete
+// sessions starting within [from, to).
+func expandClassSessions(class models.Class, from, to time.Time) []Session {
+	cancelledDays := map[string]bool{}
+	cancelledSlots := map[string]bool{}
+	for _, schedule := range class.Schedules {
+		if schedule.Kind != models.ScheduleException {
+			continue
+		}
+		if schedule.StartTime == "" {
+			cancelledDays[schedule.Date] = true
+		} else {
+			cancelledSlots[schedule.Date+" "+schedule.StartTime] = true
+		}
+	}
+
+	seen := map[int64]bool{}
+	var sessions []Session
+	add := func(date, clock string) {
+		if cancelledDays[date] || cancelledSlots[date+" "+clock] {
+			return
+		}
+		start, err := localDateTime(date, clock)
+		if err != nil || start.Before(from) || !start.Before(to) || seen[start.Unix()] {
+			return
+		}
+		seen[start.Unix()] = true
+		sessions = append(sessions, Session{
+			ClassID:         class.ID,
+			PlaceID:         class.PlaceID,
+			Title:           class.Title,
+			Instructor:      class.Instructor,
+			Category:        class.Category,
+			Capacity:        class.Capacity,
+			DurationMinutes: class.DurationMinutes,
+			PricePence:      class.PricePence,
+			SpacesLeft:      class.Capacity,
+			StartsAt:        start,
+			EndsAt:          start.Add(time.Duration(class.DurationMinutes) * time.Minute),
+		})
+	}
+
+	localFrom := from.In(timetableLocation)
+	firstDay := time.Date(localFrom.Year(), localFrom.Month(), localFrom.Day(), 0, 0, 0, 0, timetableLocation)
+	for _, schedule := range class.Schedules {
+		switch schedule.Kind {
+		case models.ScheduleDate:
+			add(schedule.Date, schedule.StartTime)
+		case models.ScheduleWeekly:
+			for day := firstWeeklyDay(schedule, firstDay); day.Before(to); day = day.AddDate(0, 0, 7) {
+				date := day.Format(dateLayout)
+				if schedule.EndsOn != "" && date > schedule.EndsOn {
+					break
+				}
+				add(date, schedule.StartTime)
+			}
+		}
+	}
+
+	sortSessions(sessions)
+	return sessions
+}
+
+// firstWeeklyDay returns the first day of a weekly schedule on or after day,
+// which must be midnight in timetableLocation. Days before the schedule's
+// StartsOn date are skipped.
+func firstWeeklyDay(schedule models.ClassSchedule, day time.Time) time.Time {
+	if startsOn, err := time.ParseInLocation(dateLayout, schedule.StartsOn, timetableLocation); err == nil && startsOn.After(day) {
+		day = startsOn
+	}
+	for int(day.Weekday()) != schedule.Weekday {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+func sortSessions(sessions []Session) {
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].StartsAt.Before(sessions[j].StartsAt)
+	})
+}
+
+// parseTimetableRange reads the from/to query values, accepting either a date
+// or an RFC 3339 timestamp. It defaults to the seven days starting today.
+func parseTimetableRange(fromParam, toParam string) (time.Time, time.Time, error) {
+	parse := func(value string) (time.Time, error) {
+		if t, err := time.ParseInLocation(dateLayout, value, timetableLocation); err == nil {
+			return t, nil
+		}
+		return time.Parse(time.RFC3339, value)
+	}
+
+	now := time.Now().In(timetableLocation)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, timetableLocation)
+	if fromParam != "" {
+		t, err := parse(fromParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from value")
+		}
+		from = t
+	}
+
+	to := from.AddDate(0, 0, 7)
+	if toParam != "" {
+		t, err := parse(toParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to value")
+		}
+		to = t
+	}
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to must be after from")
+	}
+	if to.Sub(from) > maxTimetableSpan {
+		return time.Time{}, time.Time{}, fmt.Errorf("range cannot exceed 92 days")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/jobs"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/slug"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+	"gorm.io/gorm"
+)
+
+// TrashController lets admins review soft-deleted activities before the purge
+// job removes them for good once Retention has passed.
+type TrashController struct {
+	DB        *gorm.DB
+	Store     storage.BlobStore
+	Retention time.Duration
+}
+
+func NewTrashController(db *gorm.DB, store storage.BlobStore, retention time.Duration) *TrashController {
+	return &TrashController{DB: db, Store: store, Retention: retention}
+}
+
+type trashedPlace struct {
+	Activity  models.Place `json:"activity"`
+	DeletedAt time.Time    `json:"deleted_at"`
+	PurgeAt   time.Time    `json:"purge_at"`
+}
+
+func (tc *TrashController) findDeletedPlace(ctx *gin.Context) (models.Place, bool) {
+	var place models.Place
+	err := tc.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&place, "id = ?", ctx.Param("id")).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Deleted activity not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		}
+		return place, false
+	}
+	return place, true
+}
+
+func (tc *TrashController) GetTrash(ctx *gin.Context) {
+	var places []models.Place
+	err := tc.DB.Unscoped().Preload("User").
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Find(&places).Error
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve deleted activities"})
+		return
+	}
+
+	trashed := make([]trashedPlace, 0, len(places))
+	for _, place := range places {
+		trashed = append(trashed, trashedPlace{
+			Activity:  placeWithURLs(tc.Store, place),
+			DeletedAt: place.DeletedAt.Time,
+			PurgeAt:   place.DeletedAt.Time.Add(tc.Retention),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"activities":     trashed,
+		"total":          len(trashed),
+		"retention_days": int(tc.Retention.Hours() / 24),
+	})
+}
+
+func (tc *TrashController) RestoreActivity(ctx *gin.Context) {
+	place, ok := tc.findDeletedPlace(ctx)
+	if !ok {
+		return
+	}
+
+	// A restored activity that had been merged into another stops
+	// redirecting to it.
+	err := tc.DB.Transaction(func(tx *gorm.DB) error {
+		err := trashPlace(tx, &place, false, models.PlaceRevision{
+			Action:   models.RevisionRestore,
+			AuthorID: revisionAuthorID(ctx),
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Where("from_id = ?", place.ID).Delete(&models.PlaceRedirect{}).Error
+	})
+	if err == slug.ErrTaken {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err == errVersionConflict {
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println("Error restoring activity:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore activity"})
+		return
+	}
+
+	ctx.Header("ETag", placeETag(place))
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":  "Activity restored successfully",
+		"activity": placeWithURLs(tc.Store, place),
+	})
+}
+
+// PurgeActivity permanently removes a deleted activity and its media without
+// waiting for the retention period to pass.
+func (tc *TrashController) PurgeActivity(ctx *gin.Context) {
+	place, ok := tc.findDeletedPlace(ctx)
+	if !ok {
+		return
+	}
+
+	if err := jobs.PurgePlace(ctx, tc.DB, tc.Store, place.ID); err != nil {
+		log.Println("Error purging activity:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge activity"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Activity purged successfully"})
+}
@@ -0,0 +1,100 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashRestoreAndPurge(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Put(ctx, "logos/gym.jpg", strings.NewReader("logo"), "image/jpeg"))
+	assert.NoError(t, db.Model(&models.Place{}).Where("id = ?", 1).Update("logo", "logos/gym.jpg").Error)
+	class := models.Class{PlaceID: 1, Title: "Spin", Capacity: 10, DurationMinutes: 45}
+	assert.NoError(t, db.Create(&class).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	placeController := controllers.NewPlaceController(db, store)
+	trashController := controllers.NewTrashController(db, store, 30*24*time.Hour)
+	r.GET("/activity/:id", placeController.GetActivityById)
+	r.DELETE("/activity/:id", placeController.DeleteActivity)
+	r.GET("/trash", trashController.GetTrash)
+	r.POST("/trash/:id/restore", trashController.RestoreActivity)
+	r.DELETE("/trash/:id", trashController.PurgeActivity)
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("If-Match", "*")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	logoStored := func() bool {
+		objects, err := store.List(ctx, "logos/")
+		assert.NoError(t, err)
+		return len(objects) == 1
+	}
+
+	assert.Equal(t, http.StatusNotFound, request("POST", "/trash/1/restore").Code)
+	assert.Equal(t, http.StatusOK, request("DELETE", "/activity/1").Code)
+	assert.Equal(t, http.StatusNotFound, request("GET", "/activity/1").Code)
+	assert.True(t, logoStored())
+
+	w := request("GET", "/trash")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var trash struct {
+		Activities []struct {
+			Activity  models.Place `json:"activity"`
+			DeletedAt time.Time    `json:"deleted_at"`
+			PurgeAt   time.Time    `json:"purge_at"`
+		} `json:"activities"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &trash))
+	assert.Len(t, trash.Activities, 1)
+	assert.Equal(t, "/uploads/logos/gym.jpg", trash.Activities[0].Activity.Logo)
+	assert.WithinDuration(t, trash.Activities[0].DeletedAt.Add(30*24*time.Hour), trash.Activities[0].PurgeAt, time.Second)
+
+	w = request("POST", "/trash/1/restore")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1-3"`, w.Header().Get("ETag"))
+	assert.Equal(t, http.StatusOK, request("GET", "/activity/1").Code)
+	assert.Equal(t, http.StatusNotFound, request("DELETE", "/trash/1").Code)
+
+	// Delete and restore are in the history, and each bumped the version so
+	// an If-Match from before the delete no longer matches.
+	var actions []string
+	db.Model(&models.PlaceRevision{}).Where("place_id = ?", 1).Order("id").Pluck("action", &actions)
+	assert.Equal(t, []string{models.RevisionBaseline, models.RevisionDelete, models.RevisionRestore}, actions)
+	stale := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/activity/1", nil)
+	req.Header.Set("If-Match", `"1-1"`)
+	r.ServeHTTP(stale, req)
+	assert.Equal(t, http.StatusPreconditionFailed, stale.Code)
+
+	assert.Equal(t, http.StatusOK, request("DELETE", "/activity/1").Code)
+	assert.Equal(t, http.StatusOK, request("DELETE", "/trash/1").Code)
+	assert.False(t, logoStored())
+
+	var count int64
+	db.Unscoped().Model(&models.Place{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Unscoped().Model(&models.Class{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/media"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+)
+
+// saveImageUpload validates and re-encodes an uploaded image, then stores it
+// and its thumbnails under prefix. It returns the key of the cleaned original.
+func saveImageUpload(ctx context.Context, store storage.BlobStore, file *multipart.FileHeader, prefix string) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	processed, err := media.Process(src, media.DefaultLimits)
+	if err != nil {
+		return "", err
+	}
+
+	key := prefix + "/" + processed.Original.Filename
+	variants := []media.Variant{processed.Original}
+	for _, thumb := range processed.Thumbnails {
+		variants = append(variants, thumb)
+	}
+
+	stored := []string{}
+	for _, variant := range variants {
+		variantKey := prefix + "/" + variant.Filename
+		if err := store.Put(ctx, variantKey, bytes.NewReader(variant.Data), variant.ContentType); err != nil {
+			for _, k := range stored {
+				store.Delete(ctx, k)
+			}
+			return "", err
+		}
+		stored = append(stored, variantKey)
+	}
+	return key, nil
+}
+
+// mediaChanges stages the media writes and deletions made while handling a
+// request. New uploads are stored straight away and removed again by Rollback;
+// files that are replaced or deleted are only removed by Commit, once the
+// database no longer references them. Anything left behind by a crash in
+// between is cleaned up by the orphan sweeper.
+type mediaChanges struct {
+	store   storage.BlobStore
+	added   []string
+	removed []string
+	done    bool
+}
+
+func newMediaChanges(store storage.BlobStore) *mediaChanges {
+	return &mediaChanges{store: store}
+}
+
+func (m *mediaChanges) Upload(ctx context.Context, file *multipart.FileHeader, prefix string) (string, error) {
+	key, err := saveImageUpload(ctx, m.store, file, prefix)
+	if err == nil {
+		m.added = append(m.added, key)
+	}
+	return key, err
+}
+
+// Remove schedules ref for deletion when the changes are committed.
+func (m *mediaChanges) Remove(ref string) {
+	if ref != "" {
+		m.removed = append(m.removed, ref)
+	}
+}
+
+// RemoveReplaced schedules the logo and facilities image that original used
+// and place no longer does.
+func (m *mediaChanges) RemoveReplaced(original, place models.Place) {
+	if original.Logo != place.Logo {
+		m.Remove(original.Logo)
+	}
+	if original.FacilitiesImage != place.FacilitiesImage {
+		m.Remove(original.FacilitiesImage)
+	}
+}
+
+func (m *mediaChanges) Commit(ctx context.Context) {
+	if m.done {
+		return
+	}
+	m.done = true
+	for _, ref := range m.removed {
+		removeImageFiles(ctx, m.store, ref)
+	}
+}
+
+// Rollback discards the uploads made so far. It does nothing once the changes
+// have been committed, so it can be deferred.
+func (m *mediaChanges) Rollback(ctx context.Context) {
+	if m.done {
+		return
+	}
+	m.done = true
+	for _, key := range m.added {
+		removeImageFiles(ctx, m.store, key)
+	}
+}
+
+// mediaAvailable reports whether ref can still be shown: it is empty, linked
+// from elsewhere or still in the store.
+func mediaAvailable(ctx context.Context, store storage.BlobStore, ref string) (bool, error) {
+	if ref == "" || storage.IsExternal(ref) {
+		return true, nil
+	}
+	key := storage.NormalizeKey(ref)
+	objects, err := store.List(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	for _, object := range objects {
+		if object.Key == key {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// thumbnailKeys returns the keys of the thumbnails generated for key.
+func thumbnailKeys(key string) map[string]string {
+	keys := map[string]string{}
+	for _, thumb := range media.ThumbnailSizes {
+		keys[thumb.Name] = media.ThumbnailName(key, thumb.Name)
+	}
+	return keys
+}
+
+// removeImageFiles deletes an uploaded image along with its thumbnails.
+// Images linked from elsewhere are left alone.
+func removeImageFiles(ctx context.Context, store storage.BlobStore, ref string) {
+	if ref == "" || storage.IsExternal(ref) {
+		return
+	}
+	key := storage.NormalizeKey(ref)
+	for _, k := range append([]string{key}, mapValues(thumbnailKeys(key))...) {
+		if err := store.Delete(ctx, k); err != nil {
+			log.Printf("Failed to delete media: %s: %s", k, err)
+		}
+	}
+}
+
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
+
+// placeWithURLs returns a copy of place whose logo and facilities image are
+// public URLs rather than store keys, ready to be sent to clients.
+func placeWithURLs(store storage.BlobStore, place models.Place) models.Place {
+	place.Logo = storage.PublicURL(store, place.Logo)
+	place.FacilitiesImage = storage.PublicURL(store, place.FacilitiesImage)
+	return place
+}
+
+// eventWithURLs is placeWithURLs for the place an event is held at.
+func eventWithURLs(store storage.BlobStore, event models.Event) models.Event {
+	if event.Place != nil {
+		place := placeWithURLs(store, *event.Place)
+		event.Place = &place
+	}
+	return event
+}
+
+// imagesWithURLs fills in the URL and thumbnail URLs of gallery images.
+func imagesWithURLs(store storage.BlobStore, images []models.PlaceImage) []models.PlaceImage {
+	for i := range images {
+		key := storage.NormalizeKey(images[i].Path)
+		images[i].URL = store.URL(key)
+		images[i].Thumbnails = map[string]string{}
+		for name, thumb := range thumbnailKeys(key) {
+			images[i].Thumbnails[name] = store.URL(thumb)
+		}
+	}
+	return images
+}
+
+// respondUploadError reports a failed upload, distinguishing files that were
+// rejected from server side failures.
+func respondUploadError(ctx *gin.Context, message string, err error) {
+	if media.IsValidationError(err) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": message + ": " + err.Error()})
+		return
+	}
+	log.Printf("%s: %v", message, err)
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,79 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestUpdateActivityKeepsMediaWhenSaveFails(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+
+	err = createTestData(db)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Put(ctx, "logos/old.jpg", strings.NewReader("old"), "image/jpeg"))
+	assert.NoError(t, db.Model(&models.Place{}).Where("id = ?", 1).Updates(map[string]interface{}{
+		"logo": "logos/old.jpg", "latitude": 53.48, "longitude": -2.24,
+	}).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	controller := controllers.NewPlaceController(db, store)
+	r.PUT("/activity/:id", controller.UpdateActivity)
+
+	updateLogo := func() int {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("logo", "logo.png")
+		part.Write(testPNG(t))
+		writer.Close()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/activity/1", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("If-Match", "*")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	logoKeys := func() []string {
+		objects, err := store.List(ctx, "logos/")
+		assert.NoError(t, err)
+		keys := []string{}
+		for _, object := range objects {
+			keys = append(keys, object.Key)
+		}
+		return keys
+	}
+
+	db.Callback().Update().Before("gorm:update").Register("test:fail", func(tx *gorm.DB) {
+		tx.AddError(errors.New("database unavailable"))
+	})
+	assert.Equal(t, http.StatusInternalServerError, updateLogo())
+	assert.Equal(t, []string{"logos/old.jpg"}, logoKeys())
+
+	var place models.Place
+	db.First(&place, 1)
+	assert.Equal(t, "logos/old.jpg", place.Logo)
+
+	db.Callback().Update().Remove("test:fail")
+	assert.Equal(t, http.StatusOK, updateLogo())
+	db.First(&place, 1)
+	assert.NotEqual(t, "logos/old.jpg", place.Logo)
+	assert.Contains(t, logoKeys(), place.Logo)
+	assert.NotContains(t, logoKeys(), "logos/old.jpg")
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/config"
 	"github.com/laurawarren88/go_spa_backend.git/middleware"
 	"github.com/laurawarren88/go_spa_backend.git/models"
 	"golang.org/x/crypto/bcrypt"
@@ -14,11 +15,13 @@ import (
 )
 
 type UserController struct {
-	DB *gorm.DB
+	DB      *gorm.DB
+	Auth    config.AuthConfig
+	Cookies config.CookieConfig
 }
 
-func NewUserController(db *gorm.DB) *UserController {
-	return &UserController{DB: db}
+func NewUserController(db *gorm.DB, auth config.AuthConfig, cookies config.CookieConfig) *UserController {
+	return &UserController{DB: db, Auth: auth, Cookies: cookies}
 }
 
 func (uc *UserController) GetSignupForm(ctx *gin.Context) {
@@ -108,22 +111,19 @@ func (uc *UserController) LoginUser(ctx *gin.Context) {
 		return
 	}
 
-	accessToken, err := middleware.GenerateToken(user)
+	accessToken, err := middleware.GenerateToken(user, uc.Auth.AccessSecret)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
 		return
 	}
 
-	refreshToken, err := middleware.GenerateRefreshToken(user)
+	refreshToken, err := middleware.GenerateRefreshToken(user, uc.Auth.RefreshSecret)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate refresh token"})
 		return
 	}
 
-	domain, secure, httpOnly, err := middleware.GetCookieSettings()
-	if err != nil {
-		log.Fatalf("Failed to parse environment variables: %v", err)
-	}
+	domain, secure, httpOnly := uc.Cookies.Domain, uc.Cookies.Secure, uc.Cookies.HTTPOnly
 
 	ctx.SetSameSite(http.SameSiteLaxMode)
 	ctx.SetCookie(
@@ -169,15 +169,7 @@ func (uc *UserController) ResetPassword(ctx *gin.Context) {
 func (uc *UserController) LogoutUser(ctx *gin.Context) {
 	log.Println("LogoutUser endpoint hit")
 
-	domain, err := middleware.GetLogoutCookieSettings()
-	if err != nil {
-		log.Fatalf("Failed to parse environment variables: %v", err)
-		log.Println("GetLogoutCookieSettings error:", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process logout"})
-		return
-	}
-
-	secure, httpOnly := false, false
+	domain, secure, httpOnly := uc.Cookies.Domain, uc.Cookies.Secure, uc.Cookies.HTTPOnly
 	log.Printf("Domain: %s, Secure: %v, HttpOnly: %v", domain, secure, httpOnly)
 
 	ctx.SetSameSite(http.SameSiteLaxMode)
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const minPasswordLength = 12
+
+// commonPasswords are rejected however long they are.
+var commonPasswords = map[string]bool{
+	"123456789012": true, "1234567890123": true, "12345678901234": true,
+	"password1234": true, "password123!": true, "passwordpassword": true,
+	"qwertyuiop12": true, "qwertyuiopasdf": true, "administrator": true,
+	"administrator1": true, "adminadminadmin": true, "changemechangeme": true,
+	"letmeinletmein": true, "iloveyou1234": true, "welcome12345": true,
+	"correcthorsebatterystaple": true,
+}
+
+// ValidatePassword rejects passwords that are empty, short, common or built
+// from related values such as the account's username or email address.
+func ValidatePassword(password string, related ...string) error {
+	if password == "" {
+		return errors.New("password is required")
+	}
+	if len([]rune(password)) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] || strings.Count(lower, lower[:1]) == len(lower) {
+		return errors.New("password is too common")
+	}
+	for _, value := range related {
+		value, _, _ = strings.Cut(strings.ToLower(value), "@")
+		if len(value) >= 3 && strings.Contains(lower, value) {
+			return errors.New("password must not contain the username or email address")
+		}
+	}
+	return nil
+}
+
+// CreateAdmin adds an admin user. An existing account with the same username
+// or email is left alone unless replace is set, in which case it is made an
+// admin and given the new password.
+func CreateAdmin(db *gorm.DB, username, email, password string, replace bool) (models.User, error) {
+	if username == "" || email == "" {
+		return models.User{}, errors.New("username and email are required")
+	}
+	if err := ValidatePassword(password, username, email); err != nil {
+		return models.User{}, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	var existing []models.User
+	if err := db.Where("username = ? OR email = ?", username, email).Find(&existing).Error; err != nil {
+		return models.User{}, err
+	}
+	switch {
+	case len(existing) == 0:
+		admin := models.User{Username: username, Email: email, Password: string(hash), IsAdmin: true}
+		if err := db.Create(&admin).Error; err != nil {
+			return models.User{}, err
+		}
+		return admin, nil
+	case !replace:
+		return models.User{}, fmt.Errorf("a user named %q or with email %q already exists", username, email)
+	case len(existing) > 1:
+		return models.User{}, fmt.Errorf("username %q and email %q belong to different users", username, email)
+	}
+
+	admin := existing[0]
+	err = db.Model(&admin).Updates(map[string]interface{}{"password": string(hash), "is_admin": true}).Error
+	return admin, err
+}
+
+// SetPassword replaces the password of the user whose username or email is
+// login.
+func SetPassword(db *gorm.DB, login, password string) (models.User, error) {
+	var user models.User
+	if err := db.Where("username = ? OR email = ?", login, login).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, fmt.Errorf("no user named %q", login)
+		}
+		return user, err
+	}
+	if err := ValidatePassword(password, user.Username, user.Email); err != nil {
+		return user, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return user, err
+	}
+	if err := db.Model(&user).Update("password", string(hash)).Error; err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// HasAdmin reports whether any admin user exists.
+func HasAdmin(db *gorm.DB) (bool, error) {
+	var admins int64
+	err := db.Model(&models.User{}).Where("is_admin = ?", true).Count(&admins).Error
+	return admins > 0, err
+}
+
+// BootstrapAdmin creates the first admin user so that a new deployment can be
+// managed. It does nothing once any admin exists and never changes an
+// existing account; use the create-admin or reset-password commands for that.
+// It reports whether it created the admin.
+func BootstrapAdmin(db *gorm.DB, username, email, password string) (bool, error) {
+	if exists, err := HasAdmin(db); err != nil || exists {
+		return false, err
+	}
+	if email == "" || password == "" {
+		return false, errors.New("there is no admin user: set ADMIN_EMAIL and ADMIN_PASSWORD, or run the create-admin command")
+	}
+	if _, err := CreateAdmin(db, username, email, password, false); err != nil {
+		// Another replica starting at the same time may have won.
+		if exists, _ := HasAdmin(db); exists {
+			return false, nil
+		}
+		return false, fmt.Errorf("creating the first admin user: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,54 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/laurawarren88/go_spa_backend.git/database"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestValidatePassword(t *testing.T) {
+	assert.EqualError(t, database.ValidatePassword(""), "password is required")
+	assert.Error(t, database.ValidatePassword("short-pass1"))
+	assert.Error(t, database.ValidatePassword("Password1234"))
+	assert.Error(t, database.ValidatePassword("aaaaaaaaaaaaaaaa"))
+	assert.Error(t, database.ValidatePassword("laura-warren-2024", "laura", "laura@example.com"))
+	assert.Error(t, database.ValidatePassword("my-ops.team-login", "admin", "ops.team@example.com"))
+	assert.NoError(t, database.ValidatePassword("tidal-basin-rowing", "admin", "ops@example.com"))
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.User{}))
+
+	_, err = database.BootstrapAdmin(db, "admin", "", "")
+	assert.ErrorContains(t, err, "ADMIN_EMAIL and ADMIN_PASSWORD")
+	_, err = database.BootstrapAdmin(db, "admin", "ops@example.com", "admin")
+	assert.ErrorContains(t, err, "at least 12 characters")
+
+	// A member who registered with the admin email is not taken over.
+	member := models.User{Username: "ops", Email: "ops@example.com", Password: "hash"}
+	assert.NoError(t, db.Create(&member).Error)
+	_, err = database.BootstrapAdmin(db, "admin", "ops@example.com", "tidal-basin-rowing")
+	assert.ErrorContains(t, err, "already exists")
+	assert.NoError(t, db.First(&member, member.ID).Error)
+	assert.False(t, member.IsAdmin)
+	assert.Equal(t, "hash", member.Password)
+
+	created, err := database.BootstrapAdmin(db, "admin", "admin@example.com", "tidal-basin-rowing")
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	// Later boots leave the admin alone, whatever ADMIN_PASSWORD says.
+	created, err = database.BootstrapAdmin(db, "admin", "admin@example.com", "a different password")
+	assert.NoError(t, err)
+	assert.False(t, created)
+	var admin models.User
+	assert.NoError(t, db.First(&admin, "username = ?", "admin").Error)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte("tidal-basin-rowing")))
+}
@@ -1,81 +1,49 @@
 package database
 
 import (
-	"fmt"
 	"log"
-	"os"
 
+	"github.com/laurawarren88/go_spa_backend.git/config"
 	"github.com/laurawarren88/go_spa_backend.git/models"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
-
-func ConnectToDB() {
-	// Change db to localhost if running locally of machine and not from docker
-	// dsn := fmt.Sprintf("host=localhost user=%s password=%s dbname=%s port=5432 sslmode=disable",
-	// dsn := fmt.Sprintf("host=db user=%s password=%s dbname=%s port=5432 sslmode=disable",
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_SSLMODE"),
-	)
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// ConnectToDB opens the postgres database described by cfg.
+func ConnectToDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	log.Println("Database connection established")
-	log.Printf("DSN: %s", dsn)
-
-	if err := DB.AutoMigrate(&models.User{}, &models.Place{}); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+		return nil, err
 	}
-	// log.Println("Database migration completed")
+	log.Printf("Database connection established to %s on %s:%s", cfg.Name, cfg.Host, cfg.Port)
+	return db, nil
 }
 
-func GetDB() *gorm.DB {
-	return DB
+// defaultAmenities seeds the amenity vocabulary on first start. Admins can
+// extend or rename entries afterwards through the amenities API.
+var defaultAmenities = []models.Amenity{
+	{Slug: "parking", Name: "Parking", Category: "facility"},
+	{Slug: "showers", Name: "Showers", Category: "facility"},
+	{Slug: "changing-rooms", Name: "Changing rooms", Category: "facility"},
+	{Slug: "lockers", Name: "Lockers", Category: "facility"},
+	{Slug: "pool", Name: "Swimming pool", Category: "facility"},
+	{Slug: "sauna", Name: "Sauna", Category: "facility"},
+	{Slug: "free-weights", Name: "Free weights", Category: "facility"},
+	{Slug: "cafe", Name: "Café", Category: "facility"},
+	{Slug: "wifi", Name: "Wi-Fi", Category: "facility"},
+	{Slug: "childcare", Name: "Childcare", Category: "facility"},
+	{Slug: "wheelchair-access", Name: "Wheelchair access", Category: "accessibility"},
+	{Slug: "step-free-access", Name: "Step-free access", Category: "accessibility"},
+	{Slug: "accessible-toilet", Name: "Accessible toilet", Category: "accessibility"},
+	{Slug: "hearing-loop", Name: "Hearing loop", Category: "accessibility"},
+	{Slug: "women-only-sessions", Name: "Women-only sessions", Category: "sessions"},
 }
 
-func SetupAdminUser(db *gorm.DB) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(os.Getenv("ADMIN_PASSWORD")), bcrypt.DefaultCost)
-	if err != nil {
-		log.Fatalf("Password hashing failed: %v", err)
-		return err
-	}
-	// log.Println("Hashed Password:", string(hashedPassword))
-
-	admin := models.User{
-		Username: "admin",
-		Email:    "admin@admin.com",
-		Password: string(hashedPassword),
-		IsAdmin:  true,
-	}
-
-	var existingUser models.User
-	result := db.Where("email = ?", admin.Email).First(&existingUser)
-
-	if result.Error == gorm.ErrRecordNotFound {
-		if err := db.Create(&admin).Error; err != nil {
+func SeedAmenities(db *gorm.DB) error {
+	for _, amenity := range defaultAmenities {
+		if err := db.Where(models.Amenity{Slug: amenity.Slug}).FirstOrCreate(&amenity).Error; err != nil {
 			return err
 		}
-	} else if result.Error == nil {
-		existingUser.Username = admin.Username
-		existingUser.Password = admin.Password
-		existingUser.IsAdmin = admin.IsAdmin
-		if err := db.Save(&existingUser).Error; err != nil {
-			return err
-		}
-		log.Println("Admin user updated successfully")
-	} else {
-		return result.Error
 	}
-
 	return nil
 }
@@ -0,0 +1,267 @@
+// Package exporter writes places as GeoJSON, CSV or KML. Records are written
+// one at a time so a whole directory can be streamed without holding it in
+// memory, and every format carries the same public fields in the same order.
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+)
+
+const (
+	FormatGeoJSON = "geojson"
+	FormatCSV     = "csv"
+	FormatKML     = "kml"
+)
+
+// Columns is the export schema. New fields are only ever appended so that
+// consumers can rely on the existing columns and properties.
+var Columns = []string{
+	"id", "name", "type", "description", "vicinity", "city", "postcode",
+	"phone", "email", "website", "opening_hours", "latitude", "longitude",
+	"amenities", "logo_url", "facilities_image_url", "updated_at",
+}
+
+// Record is the public view of a place. Owner details and internal fields
+// such as the version are deliberately left out.
+type Record struct {
+	ID                 uint      `json:"id"`
+	Name               string    `json:"name"`
+	Type               string    `json:"type"`
+	Description        string    `json:"description"`
+	Vicinity           string    `json:"vicinity"`
+	City               string    `json:"city"`
+	Postcode           string    `json:"postcode"`
+	Phone              string    `json:"phone"`
+	Email              string    `json:"email"`
+	Website            string    `json:"website"`
+	OpeningHours       string    `json:"opening_hours"`
+	Latitude           float64   `json:"-"`
+	Longitude          float64   `json:"-"`
+	Amenities          []string  `json:"amenities"`
+	LogoURL            string    `json:"logo_url"`
+	FacilitiesImageURL string    `json:"facilities_image_url"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// FromPlace builds the record for place, which should have its Amenities
+// loaded. Media URLs come from store.
+func FromPlace(place models.Place, store storage.BlobStore) Record {
+	amenities := []string{}
+	for _, amenity := range place.Amenities {
+		amenities = append(amenities, amenity.Slug)
+	}
+	sort.Strings(amenities)
+	return Record{
+		ID:                 place.ID,
+		Name:               place.Name,
+		Type:               place.Type,
+		Description:        place.Description,
+		Vicinity:           place.Vicinity,
+		City:               place.City,
+		Postcode:           place.Postcode,
+		Phone:              place.Phone,
+		Email:              place.Email,
+		Website:            place.Website,
+		OpeningHours:       place.OpeningHours,
+		Latitude:           place.Latitude,
+		Longitude:          place.Longitude,
+		Amenities:          amenities,
+		LogoURL:            storage.PublicURL(store, place.Logo),
+		FacilitiesImageURL: storage.PublicURL(store, place.FacilitiesImage),
+		UpdatedAt:          place.UpdatedAt.UTC(),
+	}
+}
+
+// values returns the record as strings in Columns order.
+func (r Record) values() []string {
+	return []string{
+		strconv.FormatUint(uint64(r.ID), 10), r.Name, r.Type, r.Description,
+		r.Vicinity, r.City, r.Postcode, r.Phone, r.Email, r.Website, r.OpeningHours,
+		formatCoordinate(r.Latitude), formatCoordinate(r.Longitude),
+		strings.Join(r.Amenities, ";"), r.LogoURL, r.FacilitiesImageURL,
+		r.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func formatCoordinate(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Writer streams records in one format. Close finishes the document but does
+// not close the underlying writer.
+type Writer interface {
+	Write(Record) error
+	// Flush pushes buffered records to the underlying writer.
+	Flush() error
+	Close() error
+}
+
+// NewWriter returns a Writer for format.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatGeoJSON:
+		return &geoJSONWriter{w: bufio.NewWriter(w)}, nil
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatKML:
+		return &kmlWriter{w: bufio.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// ContentType returns the media type for format.
+func ContentType(format string) string {
+	switch format {
+	case FormatGeoJSON:
+		return "application/geo+json"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatKML:
+		return "application/vnd.google-earth.kml+xml"
+	}
+	return "application/octet-stream"
+}
+
+type geoJSONWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+type geoJSONFeature struct {
+	Type     string `json:"type"`
+	ID       uint   `json:"id"`
+	Geometry struct {
+		Type        string     `json:"type"`
+		Coordinates [2]float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties Record `json:"properties"`
+}
+
+func (g *geoJSONWriter) Write(r Record) error {
+	prefix := ","
+	if g.count == 0 {
+		prefix = `{"type":"FeatureCollection","features":[`
+	}
+	feature := geoJSONFeature{Type: "Feature", ID: r.ID, Properties: r}
+	feature.Geometry.Type = "Point"
+	feature.Geometry.Coordinates = [2]float64{r.Longitude, r.Latitude}
+	data, err := json.Marshal(feature)
+	if err != nil {
+		return err
+	}
+	g.count++
+	g.w.WriteString(prefix)
+	_, err = g.w.Write(data)
+	return err
+}
+
+func (g *geoJSONWriter) Flush() error {
+	return g.w.Flush()
+}
+
+func (g *geoJSONWriter) Close() error {
+	if g.count == 0 {
+		g.w.WriteString(`{"type":"FeatureCollection","features":[`)
+	}
+	g.w.WriteString("]}\n")
+	return g.w.Flush()
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.w.Write(Columns)
+}
+
+func (c *csvWriter) Write(r Record) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	return c.w.Write(r.values())
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	return c.Flush()
+}
+
+type kmlWriter struct {
+	w       *bufio.Writer
+	started bool
+}
+
+type kmlPlacemark struct {
+	XMLName     xml.Name  `xml:"Placemark"`
+	ID          string    `xml:"id,attr"`
+	Name        string    `xml:"name"`
+	Description string    `xml:"description,omitempty"`
+	Data        []kmlData `xml:"ExtendedData>Data"`
+	Coordinates string    `xml:"Point>coordinates"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+func (k *kmlWriter) start() {
+	if !k.started {
+		k.started = true
+		k.w.WriteString(xml.Header + `<kml xmlns="http://www.opengis.net/kml/2.2"><Document><name>Activities</name>`)
+	}
+}
+
+func (k *kmlWriter) Write(r Record) error {
+	k.start()
+	placemark := kmlPlacemark{
+		ID:          "place-" + strconv.FormatUint(uint64(r.ID), 10),
+		Name:        r.Name,
+		Description: r.Description,
+		Coordinates: formatCoordinate(r.Longitude) + "," + formatCoordinate(r.Latitude),
+	}
+	for i, value := range r.values() {
+		placemark.Data = append(placemark.Data, kmlData{Name: Columns[i], Value: value})
+	}
+	data, err := xml.Marshal(placemark)
+	if err != nil {
+		return err
+	}
+	_, err = k.w.Write(data)
+	return err
+}
+
+func (k *kmlWriter) Flush() error {
+	return k.w.Flush()
+}
+
+func (k *kmlWriter) Close() error {
+	k.start()
+	k.w.WriteString("</Document></kml>\n")
+	return k.w.Flush()
+}
@@ -0,0 +1,113 @@
+package exporter_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/laurawarren88/go_spa_backend.git/exporter"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleRecords() []exporter.Record {
+	store := storage.NewLocal("unused", "/uploads")
+	place := models.Place{
+		Name: "Harbour Gym", Description: `Weights & "cardio"`, Postcode: "M1 1AA",
+		Latitude: 53.48, Longitude: -2.24, Logo: "logos/a.jpg", Version: 4, UserID: 9,
+		Amenities: []models.Amenity{{Slug: "sauna"}, {Slug: "pool"}},
+	}
+	place.ID = 7
+	place.UpdatedAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	other := models.Place{Name: "Studio, Two", Latitude: 51.5, Longitude: -0.12}
+	other.ID = 8
+	return []exporter.Record{exporter.FromPlace(place, store), exporter.FromPlace(other, store)}
+}
+
+func export(t *testing.T, format string, records []exporter.Record) []byte {
+	var buf bytes.Buffer
+	writer, err := exporter.NewWriter(format, &buf)
+	assert.NoError(t, err)
+	for _, record := range records {
+		assert.NoError(t, writer.Write(record))
+	}
+	assert.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestGeoJSON(t *testing.T) {
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			ID       uint `json:"id"`
+			Geometry struct {
+				Type        string    `json:"type"`
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	assert.NoError(t, json.Unmarshal(export(t, exporter.FormatGeoJSON, sampleRecords()), &collection))
+	assert.Equal(t, "FeatureCollection", collection.Type)
+	assert.Len(t, collection.Features, 2)
+
+	feature := collection.Features[0]
+	assert.Equal(t, uint(7), feature.ID)
+	assert.Equal(t, []float64{-2.24, 53.48}, feature.Geometry.Coordinates)
+	assert.Equal(t, "/uploads/logos/a.jpg", feature.Properties["logo_url"])
+	assert.Equal(t, []interface{}{"pool", "sauna"}, feature.Properties["amenities"])
+	for _, private := range []string{"user_id", "user", "version", "logo"} {
+		assert.NotContains(t, feature.Properties, private)
+	}
+
+	assert.JSONEq(t, `{"type":"FeatureCollection","features":[]}`, string(export(t, exporter.FormatGeoJSON, nil)))
+}
+
+func TestCSV(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(export(t, exporter.FormatCSV, sampleRecords()))).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, exporter.Columns, records[0])
+	assert.Equal(t, []string{
+		"7", "Harbour Gym", "", `Weights & "cardio"`, "", "", "M1 1AA", "", "", "", "",
+		"53.48", "-2.24", "pool;sauna", "/uploads/logos/a.jpg", "", "2026-03-01T12:00:00Z",
+	}, records[1])
+	assert.Equal(t, "Studio, Two", records[2][1])
+
+	records, err = csv.NewReader(bytes.NewReader(export(t, exporter.FormatCSV, nil))).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{exporter.Columns}, records)
+}
+
+func TestKML(t *testing.T) {
+	var document struct {
+		Placemarks []struct {
+			ID          string `xml:"id,attr"`
+			Name        string `xml:"name"`
+			Description string `xml:"description"`
+			Data        []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:"value"`
+			} `xml:"ExtendedData>Data"`
+			Coordinates string `xml:"Point>coordinates"`
+		} `xml:"Document>Placemark"`
+	}
+	assert.NoError(t, xml.Unmarshal(export(t, exporter.FormatKML, sampleRecords()), &document))
+	assert.Len(t, document.Placemarks, 2)
+	placemark := document.Placemarks[0]
+	assert.Equal(t, "place-7", placemark.ID)
+	assert.Equal(t, `Weights & "cardio"`, placemark.Description)
+	assert.Equal(t, "-2.24,53.48", placemark.Coordinates)
+	assert.Len(t, placemark.Data, len(exporter.Columns))
+	assert.Equal(t, "amenities", placemark.Data[13].Name)
+	assert.Equal(t, "pool;sauna", placemark.Data[13].Value)
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	_, err := exporter.NewWriter("xlsx", &bytes.Buffer{})
+	assert.Error(t, err)
+}
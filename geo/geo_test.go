@@ -0,0 +1,24 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", 53.48, -2.24, 53.48, -2.24, 0},
+		{"manchester to leeds", 53.4808, -2.2426, 53.8008, -1.5491, 58200},
+		{"quarter meridian", 0, 0, 90, 0, math.Pi / 2 * EarthRadius},
+	}
+	for _, tt := range tests {
+		got := Distance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+		if math.Abs(got-tt.want) > 500 {
+			t.Errorf("%s: Distance = %.0f, want about %.0f", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Package ical writes RFC 5545 iCalendar feeds.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	maxLineLen  = 75
+)
+
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	// RRule is the recurrence rule value, e.g. "FREQ=WEEKLY;BYDAY=MO".
+	RRule string
+	// ExDates are start times of occurrences excluded from RRule.
+	ExDates   []time.Time
+	Tentative bool
+	Stamp     time.Time
+}
+
+type Calendar struct {
+	Name     string
+	Location *time.Location
+	Events   []Event
+}
+
+// Write encodes the calendar. Times are written in the calendar's Location
+// with a matching VTIMEZONE when one is known, and in UTC otherwise.
+func (c Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	lw := &lineWriter{w: bw}
+
+	tz, hasTZ := vtimezones[locationName(c.Location)]
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:-//Fitness Locator//Timetables//EN")
+	lw.line("CALSCALE:GREGORIAN")
+	lw.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+	if hasTZ {
+		lw.line("X-WR-TIMEZONE:" + c.Location.String())
+		for _, line := range tz {
+			lw.line(line)
+		}
+	}
+
+	formatTime := func(name string, t time.Time) string {
+		if hasTZ {
+			return fmt.Sprintf("%s;TZID=%s:%s", name, c.Location.String(), t.In(c.Location).Format(localLayout))
+		}
+		return name + ":" + t.UTC().Format(utcLayout)
+	}
+
+	for _, event := range c.Events {
+		stamp := event.Stamp
+		if stamp.IsZero() {
+			stamp = time.Now()
+		}
+		lw.line("BEGIN:VEVENT")
+		lw.line("UID:" + event.UID)
+		lw.line("DTSTAMP:" + stamp.UTC().Format(utcLayout))
+		lw.line(formatTime("DTSTART", event.Start))
+		lw.line(formatTime("DTEND", event.End))
+		if event.RRule != "" {
+			lw.line("RRULE:" + event.RRule)
+		}
+		for _, exdate := range event.ExDates {
+			lw.line(formatTime("EXDATE", exdate))
+		}
+		lw.line("SUMMARY:" + escapeText(event.Summary))
+		if event.Description != "" {
+			lw.line("DESCRIPTION:" + escapeText(event.Description))
+		}
+		if event.Location != "" {
+			lw.line("LOCATION:" + escapeText(event.Location))
+		}
+		if event.URL != "" {
+			lw.line("URL:" + event.URL)
+		}
+		if event.Tentative {
+			lw.line("STATUS:TENTATIVE")
+		} else {
+			lw.line("STATUS:CONFIRMED")
+		}
+		lw.line("END:VEVENT")
+	}
+
+	lw.line("END:VCALENDAR")
+	if lw.err != nil {
+		return lw.err
+	}
+	return bw.Flush()
+}
+
+// UntilUTC formats t for use as the UNTIL part of an RRULE.
+func UntilUTC(t time.Time) string {
+	return t.UTC().Format(utcLayout)
+}
+
+// Weekday returns the RRULE BYDAY code for d.
+func Weekday(d time.Weekday) string {
+	return [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}[d]
+}
+
+func locationName(loc *time.Location) string {
+	if loc == nil {
+		return ""
+	}
+	return loc.String()
+}
+
+func escapeText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, ";", `\;`)
+	s = strings.ReplaceAll(s, ",", `\,`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return s
+}
+
+// lineWriter writes CRLF terminated content lines, folding them at 75 octets
+// without splitting UTF-8 sequences.
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+	limit := maxLineLen
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if _, lw.err = lw.w.WriteString(s[:cut] + "\r\n "); lw.err != nil {
+			return
+		}
+		s = s[cut:]
+		// Continuation lines start with a space, which counts towards the limit.
+		limit = maxLineLen - 1
+	}
+	_, lw.err = lw.w.WriteString(s + "\r\n")
+}
+
+// vtimezones holds VTIMEZONE definitions for the zones the service schedules
+// in, keyed by IANA name.
+var vtimezones = map[string][]string{
+	"Europe/London": {
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/London",
+		"X-LIC-LOCATION:Europe/London",
+		"BEGIN:DAYLIGHT",
+		"TZOFFSETFROM:+0000",
+		"TZOFFSETTO:+0100",
+		"TZNAME:BST",
+		"DTSTART:19700329T010000",
+		"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU",
+		"END:DAYLIGHT",
+		"BEGIN:STANDARD",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0000",
+		"TZNAME:GMT",
+		"DTSTART:19701025T020000",
+		"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+	},
+}
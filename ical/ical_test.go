@@ -0,0 +1,83 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestWriteRecurringEvent(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 3, 17, 18, 30, 0, 0, london)
+	calendar := Calendar{
+		Name:     "Gym, timetable",
+		Location: london,
+		Events: []Event{{
+			UID:     "class-1-schedule-2@example",
+			Summary: "Spin; beginners",
+			Start:   start,
+			End:     start.Add(45 * time.Minute),
+			RRule:   "FREQ=WEEKLY;BYDAY=" + Weekday(start.Weekday()),
+			ExDates: []time.Time{start.AddDate(0, 0, 14)},
+			Stamp:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	var sb strings.Builder
+	if err := calendar.Write(&sb); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Gym\\, timetable\r\n",
+		"TZID:Europe/London\r\n",
+		"UID:class-1-schedule-2@example\r\n",
+		"DTSTAMP:20250101T000000Z\r\n",
+		"DTSTART;TZID=Europe/London:20250317T183000\r\n",
+		"DTEND;TZID=Europe/London:20250317T191500\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO\r\n",
+		"EXDATE;TZID=Europe/London:20250331T183000\r\n",
+		"SUMMARY:Spin\\; beginners\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+}
+
+func TestWriteFoldsLongLines(t *testing.T) {
+	calendar := Calendar{
+		Events: []Event{{
+			UID:         "booking-1@example",
+			Summary:     "Yoga",
+			Description: strings.Repeat("é", 100),
+			Start:       time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC),
+			End:         time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	var sb strings.Builder
+	if err := calendar.Write(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(sb.String(), "DTSTART:20250601T090000Z\r\n") {
+		t.Errorf("expected UTC start time without a VTIMEZONE")
+	}
+	for _, line := range strings.Split(sb.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line splits a UTF-8 sequence: %q", line)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/osm"
+	"gorm.io/gorm"
+)
+
+// Command runs the import subcommand:
+//
+//	import [-format csv|geojson] [-dry-run] [-mode atomic|batched]
+//	       [-batch-size n] [-start n] [-owner id] [-radius metres] [-json] FILE
+//
+// Places are owned by the first admin user unless -owner is given. The report
+// is written to out.
+func Command(ctx context.Context, db *gorm.DB, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(out)
+	format := flags.String("format", "", "file format, csv or geojson (default: from the file extension)")
+	dryRun := flags.Bool("dry-run", false, "validate and report without writing")
+	mode := flags.String("mode", string(ModeAtomic), "atomic or batched")
+	batchSize := flags.Int("batch-size", DefaultBatchSize, "rows per transaction in batched mode")
+	start := flags.Int("start", 1, "first row to import, to resume a batched import")
+	owner := flags.Uint("owner", 0, "ID of the user who will own the places")
+	radius := flags.Float64("radius", DefaultDedupeRadius, "metres within which a row duplicates an existing place")
+	asJSON := flags.Bool("json", false, "print the full report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: import [flags] FILE")
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = FormatFromName(path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	rows, err := Parse(*format, file)
+	if err != nil {
+		return err
+	}
+
+	ownerID, err := ownerOrAdmin(db, *owner)
+	if err != nil {
+		return err
+	}
+
+	report, runErr := Run(ctx, db, rows, Options{
+		DryRun:       *dryRun,
+		Mode:         Mode(*mode),
+		BatchSize:    *batchSize,
+		Start:        *start,
+		OwnerID:      ownerID,
+		DedupeRadius: *radius,
+	})
+	if report.Rows != nil {
+		if err := writeReport(out, report, *asJSON); err != nil {
+			return err
+		}
+	}
+	return runErr
+}
+
+// OSMCommand runs the import-osm subcommand:
+//
+//	import-osm [-format pbf|overpass] [-dry-run] [-owner id] [-radius metres] [-json] FILE
+//
+// FILE is an OSM PBF extract or the JSON output of an Overpass query. New
+// places are owned by the first admin user unless -owner is given.
+func OSMCommand(ctx context.Context, db *gorm.DB, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("import-osm", flag.ContinueOnError)
+	flags.SetOutput(out)
+	format := flags.String("format", "", "file format, pbf or overpass (default: from the file extension)")
+	dryRun := flags.Bool("dry-run", false, "validate and report without writing")
+	owner := flags.Uint("owner", 0, "ID of the user who will own new places")
+	radius := flags.Float64("radius", DefaultDedupeRadius, "metres within which a new venue duplicates an existing place")
+	asJSON := flags.Bool("json", false, "print the full report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: import-osm [flags] FILE")
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = "overpass"
+		if strings.HasSuffix(strings.ToLower(path), ".pbf") {
+			*format = "pbf"
+		}
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var elements []osm.Element
+	switch *format {
+	case "pbf":
+		elements, err = osm.ReadPBF(file, IsFitnessVenue)
+	case "overpass":
+		elements, err = osm.ReadOverpass(file, IsFitnessVenue)
+	default:
+		return fmt.Errorf("unknown OSM format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	ownerID, err := ownerOrAdmin(db, *owner)
+	if err != nil {
+		return err
+	}
+
+	report, runErr := ImportOSM(ctx, db, elements, OSMOptions{
+		DryRun:       *dryRun,
+		OwnerID:      ownerID,
+		DedupeRadius: *radius,
+	})
+	if report.Rows != nil {
+		if err := writeReport(out, report, *asJSON); err != nil {
+			return err
+		}
+	}
+	return runErr
+}
+
+// ownerOrAdmin returns owner, or the first admin user if it is zero.
+func ownerOrAdmin(db *gorm.DB, owner uint) (uint, error) {
+	if owner != 0 {
+		return owner, nil
+	}
+	var admin models.User
+	if err := db.Where("is_admin = ?", true).Order("id").First(&admin).Error; err != nil {
+		return 0, fmt.Errorf("no admin user to own the places, use -owner: %w", err)
+	}
+	return admin.ID, nil
+}
+
+func writeReport(out io.Writer, report Report, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	printReport(out, report)
+	return nil
+}
+
+func printReport(out io.Writer, report Report) {
+	for _, row := range report.Rows {
+		name := row.Name
+		if row.OSMID != "" {
+			name += ", " + row.OSMID
+		}
+		if len(row.Kept) > 0 {
+			fmt.Fprintf(out, "row %d (%s): kept owner edits to %s\n", row.Row, name, strings.Join(row.Kept, ", "))
+		}
+		switch row.Status {
+		case StatusInvalid:
+			for _, err := range row.Errors {
+				fmt.Fprintf(out, "row %d (%s): %s: %s\n", row.Row, name, err.Field, err.Message)
+			}
+		case StatusDuplicate:
+			if row.DuplicateOf != 0 {
+				fmt.Fprintf(out, "row %d (%s): duplicate of place %d\n", row.Row, name, row.DuplicateOf)
+			} else {
+				fmt.Fprintf(out, "row %d (%s): duplicate of row %d\n", row.Row, name, row.DuplicateOfRow)
+			}
+		}
+	}
+	if report.Updated+report.Unchanged > 0 {
+		fmt.Fprintf(out, "%d updated, %d unchanged\n", report.Updated, report.Unchanged)
+	}
+	fmt.Fprintf(out, "%d rows: %d created, %d valid, %d duplicates, %d invalid, %d skipped, %d failed\n",
+		report.Total, report.Created, report.Valid, report.Duplicates, report.Invalid, report.Skipped, report.Failed)
+	if report.ResumeFrom != 0 {
+		fmt.Fprintf(out, "import stopped; rerun with -start %d to resume\n", report.ResumeFrom)
+	}
+}
@@ -0,0 +1,338 @@
+// Package importer loads places in bulk from CSV files and GeoJSON feature
+// collections. Every row goes through the same validation as the API, rows
+// that match an existing place are skipped, and the outcome of each row is
+// reported so a file can be checked with a dry run before it is imported.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/laurawarren88/go_spa_backend.git/geo"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/slug"
+	"github.com/laurawarren88/go_spa_backend.git/validation"
+	"gorm.io/gorm"
+)
+
+type Mode string
+
+const (
+	// ModeAtomic writes every row in one transaction, and nothing at all if
+	// any row is invalid.
+	ModeAtomic Mode = "atomic"
+	// ModeBatched commits valid rows in batches and skips invalid ones. If a
+	// batch fails the import stops and can be resumed from Report.ResumeFrom.
+	ModeBatched Mode = "batched"
+)
+
+const (
+	StatusCreated   = "created"
+	StatusValid     = "valid"
+	StatusDuplicate = "duplicate"
+	StatusInvalid   = "invalid"
+	StatusSkipped   = "skipped"
+	StatusFailed    = "failed"
+	// StatusUpdated and StatusUnchanged are used by ImportOSM for places
+	// imported before.
+	StatusUpdated   = "updated"
+	StatusUnchanged = "unchanged"
+)
+
+const (
+	DefaultBatchSize    = 100
+	DefaultDedupeRadius = 25.0
+)
+
+// ErrInvalidRows is returned by an atomic import that was not written because
+// some rows failed validation.
+var ErrInvalidRows = errors.New("import has invalid rows")
+
+// ErrInvalidOptions is returned, wrapped, when Options cannot be used.
+var ErrInvalidOptions = errors.New("invalid import options")
+
+type Options struct {
+	DryRun    bool
+	Mode      Mode
+	BatchSize int
+	// Start is the number of the first row to import. Earlier rows are
+	// reported as skipped, which lets a batched import be resumed.
+	Start int
+	// OwnerID is the user the imported places belong to.
+	OwnerID uint
+	// AuthorID is recorded as the author of the import revisions.
+	AuthorID *uint
+	// DedupeRadius is the distance in metres within which a row is treated
+	// as a duplicate of an existing place. Zero only matches on name and
+	// postcode.
+	DedupeRadius float64
+}
+
+type RowResult struct {
+	Row            int               `json:"row"`
+	Name           string            `json:"name"`
+	Status         string            `json:"status"`
+	PlaceID        uint              `json:"place_id,omitempty"`
+	DuplicateOf    uint              `json:"duplicate_of,omitempty"`
+	DuplicateOfRow int               `json:"duplicate_of_row,omitempty"`
+	Errors         validation.Errors `json:"errors,omitempty"`
+	// OSMID and Kept are only set by ImportOSM. Kept lists the fields left
+	// alone because they were edited after the last import.
+	OSMID string   `json:"osm_id,omitempty"`
+	Kept  []string `json:"kept,omitempty"`
+}
+
+type Report struct {
+	DryRun     bool        `json:"dry_run"`
+	Mode       Mode        `json:"mode"`
+	Total      int         `json:"total"`
+	Created    int         `json:"created"`
+	Updated    int         `json:"updated,omitempty"`
+	Unchanged  int         `json:"unchanged,omitempty"`
+	Valid      int         `json:"valid"`
+	Duplicates int         `json:"duplicates"`
+	Invalid    int         `json:"invalid"`
+	Skipped    int         `json:"skipped"`
+	Failed     int         `json:"failed"`
+	ResumeFrom int         `json:"resume_from,omitempty"`
+	Rows       []RowResult `json:"rows"`
+}
+
+// candidate is a place the dedupe check compares rows against: either one
+// already in the database or a row accepted earlier in the same file.
+type candidate struct {
+	placeID  uint
+	row      int
+	name     string
+	postcode string
+	lat, lng float64
+}
+
+type pending struct {
+	result    *RowResult
+	place     models.Place
+	amenities []models.Amenity
+}
+
+// Run validates rows, drops duplicates and, unless opts.DryRun is set, creates
+// the remaining places. The report covers every row even when an error is
+// returned.
+func Run(ctx context.Context, db *gorm.DB, rows []Row, opts Options) (Report, error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeAtomic
+	}
+	if opts.Mode != ModeAtomic && opts.Mode != ModeBatched {
+		return Report{}, fmt.Errorf("%w: unknown import mode %q", ErrInvalidOptions, opts.Mode)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.OwnerID == 0 {
+		return Report{}, fmt.Errorf("%w: an owner is required for imported places", ErrInvalidOptions)
+	}
+	db = db.WithContext(ctx)
+
+	vocabulary, err := loadVocabulary(db)
+	if err != nil {
+		return Report{}, err
+	}
+	candidates, err := loadCandidates(db)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{DryRun: opts.DryRun, Mode: opts.Mode, Total: len(rows), Rows: make([]RowResult, len(rows))}
+	var accepted []pending
+	for i, row := range rows {
+		result := &report.Rows[i]
+		*result = RowResult{Row: row.Number, Name: row.Place.Name}
+		if row.Number < opts.Start {
+			result.Status = StatusSkipped
+			continue
+		}
+
+		place := row.Place
+		place.UserID = opts.OwnerID
+		errs := append(validation.Errors{}, row.Errors...)
+		if err := validation.Place(&place); err != nil {
+			errs = append(errs, err.(validation.Errors)...)
+		}
+		amenities := resolveAmenities(vocabulary, row.Amenities, &errs)
+		result.Name = place.Name
+		if len(errs) > 0 {
+			result.Status, result.Errors = StatusInvalid, errs
+			continue
+		}
+
+		if match := findDuplicate(candidates, place, opts.DedupeRadius); match != nil {
+			result.Status = StatusDuplicate
+			result.DuplicateOf, result.DuplicateOfRow = match.placeID, match.row
+			continue
+		}
+		candidates = append(candidates, candidate{
+			row: row.Number, name: place.Name, postcode: place.Postcode,
+			lat: place.Latitude, lng: place.Longitude,
+		})
+		result.Status = StatusValid
+		accepted = append(accepted, pending{result: result, place: place, amenities: amenities})
+	}
+
+	invalid := false
+	for _, result := range report.Rows {
+		invalid = invalid || result.Status == StatusInvalid
+	}
+
+	switch {
+	case opts.DryRun:
+	case opts.Mode == ModeAtomic && invalid:
+		err = ErrInvalidRows
+	case opts.Mode == ModeAtomic:
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return createPlaces(tx, accepted, opts.AuthorID)
+		})
+		if err != nil {
+			markFailed(accepted)
+		}
+	default:
+		for start := 0; start < len(accepted); start += opts.BatchSize {
+			batch := accepted[start:min(start+opts.BatchSize, len(accepted))]
+			err = db.Transaction(func(tx *gorm.DB) error {
+				return createPlaces(tx, batch, opts.AuthorID)
+			})
+			if err != nil {
+				report.ResumeFrom = batch[0].result.Row
+				markFailed(accepted[start:])
+				break
+			}
+		}
+	}
+
+	report.count()
+	return report, err
+}
+
+func createPlaces(tx *gorm.DB, places []pending, authorID *uint) error {
+	for i := range places {
+		p := &places[i]
+		p.place.Amenities = p.amenities
+		if err := slug.Assign(tx, &p.place); err != nil {
+			return err
+		}
+		if err := tx.Create(&p.place).Error; err != nil {
+			return fmt.Errorf("row %d: %w", p.result.Row, err)
+		}
+		revision, err := models.NewPlaceRevision(p.place, p.amenities, models.RevisionImport, authorID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return fmt.Errorf("row %d: %w", p.result.Row, err)
+		}
+	}
+	// Run marks the batch failed again if the commit itself fails.
+	for i := range places {
+		places[i].result.Status = StatusCreated
+		places[i].result.PlaceID = places[i].place.ID
+	}
+	return nil
+}
+
+func markFailed(places []pending) {
+	for _, p := range places {
+		p.result.Status = StatusFailed
+		p.result.PlaceID = 0
+	}
+}
+
+func (r *Report) count() {
+	for _, result := range r.Rows {
+		switch result.Status {
+		case StatusCreated:
+			r.Created++
+		case StatusUpdated:
+			r.Updated++
+		case StatusUnchanged:
+			r.Unchanged++
+		case StatusValid:
+			r.Valid++
+		case StatusDuplicate:
+			r.Duplicates++
+		case StatusInvalid:
+			r.Invalid++
+		case StatusSkipped:
+			r.Skipped++
+		case StatusFailed:
+			r.Failed++
+		}
+	}
+}
+
+func loadVocabulary(db *gorm.DB) (map[string]models.Amenity, error) {
+	var amenities []models.Amenity
+	if err := db.Find(&amenities).Error; err != nil {
+		return nil, err
+	}
+	vocabulary := make(map[string]models.Amenity, len(amenities))
+	for _, amenity := range amenities {
+		vocabulary[amenity.Slug] = amenity
+	}
+	return vocabulary, nil
+}
+
+func resolveAmenities(vocabulary map[string]models.Amenity, slugs []string, errs *validation.Errors) []models.Amenity {
+	seen := map[string]bool{}
+	amenities := []models.Amenity{}
+	for _, slug := range slugs {
+		slug = strings.ToLower(strings.TrimSpace(slug))
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		amenity, ok := vocabulary[slug]
+		if !ok {
+			errs.Add("amenities", validation.CodeUnknownValue, "unknown amenity %q", slug)
+			continue
+		}
+		amenities = append(amenities, amenity)
+	}
+	return amenities
+}
+
+func loadCandidates(db *gorm.DB) ([]candidate, error) {
+	var places []models.Place
+	if err := db.Select("id", "name", "postcode", "latitude", "longitude").Find(&places).Error; err != nil {
+		return nil, err
+	}
+	candidates := make([]candidate, len(places))
+	for i, place := range places {
+		// Places saved before postcodes were normalised may still be in
+		// their original form.
+		postcode, ok := validation.NormalizePostcode(place.Postcode)
+		if !ok {
+			postcode = place.Postcode
+		}
+		candidates[i] = candidate{
+			placeID: place.ID, name: place.Name, postcode: postcode,
+			lat: place.Latitude, lng: place.Longitude,
+		}
+	}
+	return candidates, nil
+}
+
+// findDuplicate returns the candidate place matches, if any. Places match
+// when they share a name and postcode, or lie within radius metres of each
+// other.
+func findDuplicate(candidates []candidate, place models.Place, radius float64) *candidate {
+	for i := range candidates {
+		c := &candidates[i]
+		if place.Postcode != "" && c.postcode == place.Postcode && strings.EqualFold(c.name, place.Name) {
+			return c
+		}
+		if radius > 0 && geo.Distance(c.lat, c.lng, place.Latitude, place.Longitude) <= radius {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package importer_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/laurawarren88/go_spa_backend.git/importer"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/validation"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.User{}, &models.Place{}, &models.Amenity{}, &models.PlaceRevision{}, &models.PlaceSlug{}))
+	assert.NoError(t, db.Create(&models.User{Username: "admin", Email: "admin@example.com", Password: "x", IsAdmin: true}).Error)
+	assert.NoError(t, db.Create(&[]models.Amenity{{Slug: "pool", Name: "Pool"}, {Slug: "sauna", Name: "Sauna"}}).Error)
+	assert.NoError(t, db.Create(&models.Place{
+		Name: "Existing Gym", Postcode: "M1 1AA", Phone: "+441614960000", Description: "Gym",
+		Latitude: 53.4800, Longitude: -2.2400, UserID: 1,
+	}).Error)
+	return db
+}
+
+const sampleCSV = `Name,Address,Postcode,Telephone,Description,Lat,Lng,Amenities,Notes
+Harbour Gym,1 Quay St,m11ab,0161 496 0001,Gym,53.4900,-2.2500,pool;sauna,ignored
+existing gym,,M1 1AA,0161 496 0002,Gym,53.5000,-2.3000,,
+Next Door,,,0161 496 0003,Studio,53.48001,-2.24001,,
+No Coordinates,,,0161 496 0004,Studio,,,,
+Bad Amenity,,,0161 496 0005,Studio,53.6,-2.4,helipad,
+Harbour Gym Annex,,M1 1AB,0161 496 0006,Gym,53.49001,-2.25001,,
+`
+
+func statuses(report importer.Report) []string {
+	var result []string
+	for _, row := range report.Rows {
+		result = append(result, row.Status)
+	}
+	return result
+}
+
+func TestParseCSV(t *testing.T) {
+	rows, err := importer.ParseCSV(strings.NewReader(sampleCSV))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 6)
+	assert.Equal(t, 1, rows[0].Number)
+	assert.Equal(t, "Harbour Gym", rows[0].Place.Name)
+	assert.Equal(t, "1 Quay St", rows[0].Place.Vicinity)
+	assert.Equal(t, 53.49, rows[0].Place.Latitude)
+	assert.Equal(t, []string{"pool", "sauna"}, rows[0].Amenities)
+	assert.Empty(t, rows[0].Errors)
+	assert.True(t, rows[3].Errors.Has("latitude"))
+
+	_, err = importer.ParseCSV(strings.NewReader("title,phone\nGym,0161\n"))
+	assert.Error(t, err)
+}
+
+func TestParseGeoJSON(t *testing.T) {
+	rows, err := importer.ParseGeoJSON(strings.NewReader(`{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-2.25, 53.49]},
+		 "properties": {"name": "Harbour Gym", "phone": 1614960001, "amenities": ["pool"]}},
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]},
+		 "properties": {"name": "Route"}}
+	]}`))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, 53.49, rows[0].Place.Latitude)
+	assert.Equal(t, -2.25, rows[0].Place.Longitude)
+	assert.Equal(t, "1614960001", rows[0].Place.Phone)
+	assert.Equal(t, []string{"pool"}, rows[0].Amenities)
+	assert.Equal(t, validation.CodeInvalidType, rows[1].Errors[0].Code)
+
+	_, err = importer.ParseGeoJSON(strings.NewReader(`{"type": "Feature"}`))
+	assert.Error(t, err)
+}
+
+func TestRunDryRunReportsEveryRow(t *testing.T) {
+	db := setupDB(t)
+	rows, err := importer.ParseCSV(strings.NewReader(sampleCSV))
+	assert.NoError(t, err)
+
+	report, err := importer.Run(context.Background(), db, rows, importer.Options{
+		DryRun: true, OwnerID: 1, DedupeRadius: 25,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"valid", "duplicate", "duplicate", "invalid", "invalid", "duplicate"}, statuses(report))
+	assert.Equal(t, uint(1), report.Rows[1].DuplicateOf)
+	assert.Equal(t, uint(1), report.Rows[2].DuplicateOf)
+	assert.Equal(t, 1, report.Rows[5].DuplicateOfRow)
+	assert.Equal(t, validation.CodeUnknownValue, report.Rows[4].Errors[0].Code)
+	assert.Equal(t, 1, report.Valid)
+	assert.Equal(t, 2, report.Invalid)
+
+	var count int64
+	db.Model(&models.Place{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestRunAtomicWritesNothingWhenRowsAreInvalid(t *testing.T) {
+	db := setupDB(t)
+	rows, err := importer.ParseCSV(strings.NewReader(sampleCSV))
+	assert.NoError(t, err)
+
+	_, err = importer.Run(context.Background(), db, rows, importer.Options{OwnerID: 1, DedupeRadius: 25})
+	assert.True(t, errors.Is(err, importer.ErrInvalidRows))
+	var count int64
+	db.Model(&models.Place{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	report, err := importer.Run(context.Background(), db, rows[:3], importer.Options{OwnerID: 1, DedupeRadius: 25})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+
+	var place models.Place
+	assert.NoError(t, db.Preload("Amenities").First(&place, report.Rows[0].PlaceID).Error)
+	assert.Equal(t, "M1 1AB", place.Postcode)
+	assert.Equal(t, "+441614960001", place.Phone)
+	assert.Len(t, place.Amenities, 2)
+
+	var revision models.PlaceRevision
+	assert.NoError(t, db.Where("place_id = ?", place.ID).First(&revision).Error)
+	assert.Equal(t, models.RevisionImport, revision.Action)
+	assert.Equal(t, uint(1), revision.Version)
+}
+
+func TestRunBatchedResumesAfterFailure(t *testing.T) {
+	db := setupDB(t)
+	var csv strings.Builder
+	csv.WriteString("name,phone,description,latitude,longitude\n")
+	for i := 0; i < 5; i++ {
+		csv.WriteString("Gym " + string(rune('A'+i)) + ",0161 496 0001,Gym,5" + string(rune('0'+i)) + ".0,-2.0\n")
+	}
+	csv.WriteString("Broken,0161 496 0001,,51.0,-2.0\n")
+	rows, err := importer.ParseCSV(strings.NewReader(csv.String()))
+	assert.NoError(t, err)
+
+	// Fail every insert of the fourth place, which falls in the second batch.
+	inserts := 0
+	db.Callback().Create().Before("gorm:create").Register("test:fail", func(tx *gorm.DB) {
+		if tx.Statement.Table == "places" {
+			inserts++
+			if inserts == 4 {
+				tx.AddError(errors.New("database unavailable"))
+			}
+		}
+	})
+	report, err := importer.Run(context.Background(), db, rows, importer.Options{Mode: importer.ModeBatched, BatchSize: 3, OwnerID: 1})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"created", "created", "created", "failed", "failed", "invalid"}, statuses(report))
+	assert.Equal(t, 4, report.ResumeFrom)
+	db.Callback().Create().Remove("test:fail")
+
+	report, err = importer.Run(context.Background(), db, rows, importer.Options{
+		Mode: importer.ModeBatched, BatchSize: 3, OwnerID: 1, Start: report.ResumeFrom,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"skipped", "skipped", "skipped", "created", "created", "invalid"}, statuses(report))
+
+	var count int64
+	db.Model(&models.Place{}).Count(&count)
+	assert.Equal(t, int64(6), count)
+}
@@ -0,0 +1,385 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/osm"
+	"github.com/laurawarren88/go_spa_backend.git/slug"
+	"github.com/laurawarren88/go_spa_backend.git/validation"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// fitnessLeisure are the leisure=* values imported whatever their sport.
+var fitnessLeisure = map[string]bool{
+	"fitness_centre": true,
+	"sports_centre":  true,
+	"sports_hall":    true,
+	"swimming_pool":  true,
+	"dance":          true,
+}
+
+// outdoorLeisure are features that carry sport=* but are not venues in their
+// own right, such as individual pitches.
+var outdoorLeisure = map[string]bool{
+	"pitch":      true,
+	"track":      true,
+	"park":       true,
+	"playground": true,
+	"garden":     true,
+}
+
+// osmManagedFields are the snapshot fields that OpenStreetMap supplies and a
+// re-import may refresh.
+var osmManagedFields = []string{
+	"name", "vicinity", "city", "postcode", "phone", "email", "website",
+	"opening_hours", "description", "type", "latitude", "longitude", "amenities",
+}
+
+// IsFitnessVenue selects named fitness centres, sports centres, pools, dance
+// studios, dojos and sports clubs. Pitches, tracks and shops are left out.
+func IsFitnessVenue(tags map[string]string) bool {
+	if tags["name"] == "" || tags["access"] == "private" || tags["shop"] != "" {
+		return false
+	}
+	leisure := tags["leisure"]
+	switch {
+	case fitnessLeisure[leisure], tags["amenity"] == "dojo", tags["club"] == "sport":
+		return true
+	case tags["sport"] != "":
+		return !outdoorLeisure[leisure]
+	}
+	return false
+}
+
+// osmCategory maps tags to the place type shown on the map.
+func osmCategory(tags map[string]string) (string, string) {
+	sports := strings.Split(tags["sport"], ";")
+	has := func(sport ...string) bool {
+		for _, s := range sports {
+			for _, want := range sport {
+				if strings.TrimSpace(s) == want {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	switch {
+	case has("yoga", "pilates"):
+		return "yoga", "Yoga studio"
+	case has("climbing", "bouldering"):
+		return "climbing", "Climbing centre"
+	case has("swimming") || tags["leisure"] == "swimming_pool":
+		return "swimming", "Swimming pool"
+	case has("martial_arts", "karate", "judo", "taekwondo", "boxing", "kickboxing", "aikido") || tags["amenity"] == "dojo":
+		return "martial_arts", "Martial arts club"
+	case has("dance") || tags["leisure"] == "dance":
+		return "dance", "Dance studio"
+	case tags["leisure"] == "fitness_centre" || has("fitness", "crossfit"):
+		return "gym", "Gym"
+	case tags["leisure"] == "sports_centre" || tags["leisure"] == "sports_hall":
+		return "sports_centre", "Sports centre"
+	}
+	return "sport", "Sports club"
+}
+
+// osmAmenities maps tags to amenity slugs. Slugs missing from the vocabulary
+// are dropped when the place is saved.
+func osmAmenities(tags map[string]string) []string {
+	var slugs []string
+	if tags["wheelchair"] == "yes" {
+		slugs = append(slugs, "wheelchair-access")
+	}
+	if tags["leisure"] == "swimming_pool" || tags["swimming_pool"] == "yes" || strings.Contains(tags["sport"], "swimming") {
+		slugs = append(slugs, "pool")
+	}
+	if tags["sauna"] == "yes" {
+		slugs = append(slugs, "sauna")
+	}
+	if tags["shower"] == "yes" {
+		slugs = append(slugs, "showers")
+	}
+	if tags["internet_access"] == "wlan" || tags["internet_access"] == "yes" {
+		slugs = append(slugs, "wifi")
+	}
+	if tags["parking"] != "" && tags["parking"] != "no" {
+		slugs = append(slugs, "parking")
+	}
+	return slugs
+}
+
+func firstTag(tags map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(tags[key]); value != "" {
+			// Multiple values are separated by semicolons; keep the first.
+			return strings.TrimSpace(strings.Split(value, ";")[0])
+		}
+	}
+	return ""
+}
+
+// osmPlace maps an element's tags to place fields.
+func osmPlace(element osm.Element) (models.Place, []string) {
+	tags := element.Tags
+	category, label := osmCategory(tags)
+
+	vicinity := firstTag(tags, "addr:full")
+	if vicinity == "" {
+		vicinity = strings.TrimSpace(firstTag(tags, "addr:housenumber") + " " + firstTag(tags, "addr:street"))
+	}
+	website := firstTag(tags, "website", "contact:website", "url")
+	if website != "" && !strings.Contains(website, "://") {
+		website = "https://" + website
+	}
+	description := firstTag(tags, "description")
+	if description == "" {
+		description = label
+	}
+
+	ref := element.Ref()
+	place := models.Place{
+		Name:         strings.TrimSpace(tags["name"]),
+		Vicinity:     vicinity,
+		City:         firstTag(tags, "addr:city", "addr:town"),
+		Postcode:     firstTag(tags, "addr:postcode"),
+		Phone:        firstTag(tags, "phone", "contact:phone"),
+		Email:        firstTag(tags, "email", "contact:email"),
+		Website:      website,
+		OpeningHours: strings.TrimSpace(tags["opening_hours"]),
+		Description:  description,
+		Type:         category,
+		Latitude:     element.Lat,
+		Longitude:    element.Lon,
+		OSMID:        &ref,
+	}
+	return place, osmAmenities(tags)
+}
+
+type OSMOptions struct {
+	DryRun bool
+	// OwnerID is the user new places belong to.
+	OwnerID  uint
+	AuthorID *uint
+	// DedupeRadius is used to skip new elements that duplicate a place that
+	// was entered by hand, as in Options.
+	DedupeRadius float64
+}
+
+// ImportOSM creates a place for each new element and refreshes the places
+// imported from earlier extracts, matched by OSM ID. A field is only
+// refreshed while it still holds the value OSM supplied last time, so edits
+// made by owners are kept and listed in RowResult.Kept. Each
+// element is saved in its own transaction, so an interrupted import can
+// simply be run again.
+func ImportOSM(ctx context.Context, db *gorm.DB, elements []osm.Element, opts OSMOptions) (Report, error) {
+	if opts.OwnerID == 0 {
+		return Report{}, fmt.Errorf("an owner is required for imported places")
+	}
+	db = db.WithContext(ctx)
+
+	vocabulary, err := loadVocabulary(db)
+	if err != nil {
+		return Report{}, err
+	}
+	candidates, err := loadCandidates(db)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{DryRun: opts.DryRun, Mode: ModeBatched, Total: len(elements), Rows: make([]RowResult, len(elements))}
+	for i, element := range elements {
+		result := &report.Rows[i]
+		*result = RowResult{Row: i + 1, Name: element.Tags["name"], OSMID: element.Ref()}
+		if !element.HasLocation {
+			result.Status = StatusInvalid
+			result.Errors.Add("geometry", validation.CodeRequired, "%s has no location", element.Ref())
+			continue
+		}
+		incoming, slugs := osmPlace(element)
+
+		var existing models.Place
+		lookup := db.Unscoped().Preload("Amenities").Where("osm_id = ?", element.Ref()).Limit(1).Find(&existing)
+		err := lookup.Error
+		switch {
+		case err != nil:
+		case lookup.RowsAffected == 0:
+			err = createOSMPlace(db, result, &candidates, incoming, slugs, vocabulary, opts)
+		case existing.DeletedAt.Valid:
+			// Deleted by its owner or an admin; do not bring it back.
+			result.Status = StatusSkipped
+			result.PlaceID = existing.ID
+		default:
+			err = updateOSMPlace(db, result, existing, incoming, slugs, vocabulary, opts)
+		}
+		if err != nil {
+			result.Status = StatusFailed
+			report.count()
+			return report, fmt.Errorf("%s: %w", element.Ref(), err)
+		}
+	}
+
+	report.count()
+	return report, nil
+}
+
+func createOSMPlace(db *gorm.DB, result *RowResult, candidates *[]candidate, place models.Place, slugs []string, vocabulary map[string]models.Amenity, opts OSMOptions) error {
+	place.UserID = opts.OwnerID
+	if err := validation.ImportedPlace(&place); err != nil {
+		result.Status, result.Errors = StatusInvalid, err.(validation.Errors)
+		return nil
+	}
+	result.Name = place.Name
+	if match := findDuplicate(*candidates, place, opts.DedupeRadius); match != nil {
+		result.Status = StatusDuplicate
+		result.DuplicateOf, result.DuplicateOfRow = match.placeID, match.row
+		return nil
+	}
+	*candidates = append(*candidates, candidate{
+		row: result.Row, name: place.Name, postcode: place.Postcode,
+		lat: place.Latitude, lng: place.Longitude,
+	})
+	if opts.DryRun {
+		result.Status = StatusValid
+		return nil
+	}
+
+	amenities := knownAmenities(vocabulary, slugs)
+	snapshot, err := json.Marshal(models.SnapshotOf(place, amenities))
+	if err != nil {
+		return err
+	}
+	place.OSMSnapshot = string(snapshot)
+	p := pending{result: result, place: place, amenities: amenities}
+	return db.Transaction(func(tx *gorm.DB) error {
+		return createPlaces(tx, []pending{p}, opts.AuthorID)
+	})
+}
+
+func updateOSMPlace(db *gorm.DB, result *RowResult, existing models.Place, incoming models.Place, slugs []string, vocabulary map[string]models.Amenity, opts OSMOptions) error {
+	result.PlaceID = existing.ID
+
+	// Normalise the incoming values the same way saved ones were.
+	if err := validation.ImportedPlace(&incoming); err != nil {
+		result.Status, result.Errors = StatusInvalid, err.(validation.Errors)
+		return nil
+	}
+	incomingSnapshot := models.SnapshotOf(incoming, knownAmenities(vocabulary, slugs))
+	current := snapshotFields(models.SnapshotOf(existing, existing.Amenities))
+	next := snapshotFields(incomingSnapshot)
+
+	// The values OSM supplied last time. Without them every field that
+	// differs is treated as edited and kept.
+	var imported map[string]interface{}
+	if existing.OSMSnapshot != "" {
+		var snapshot models.PlaceSnapshot
+		if err := json.Unmarshal([]byte(existing.OSMSnapshot), &snapshot); err != nil {
+			return err
+		}
+		imported = snapshotFields(snapshot)
+	}
+
+	merged := map[string]interface{}{}
+	for field, value := range current {
+		merged[field] = value
+	}
+	for _, field := range osmManagedFields {
+		switch {
+		case reflect.DeepEqual(current[field], next[field]):
+		case imported != nil && reflect.DeepEqual(next[field], imported[field]):
+			// Unchanged in OSM, so any difference is the owner's.
+		case imported != nil && reflect.DeepEqual(current[field], imported[field]):
+			merged[field] = next[field]
+		default:
+			result.Kept = append(result.Kept, field)
+		}
+	}
+
+	var snapshot models.PlaceSnapshot
+	data, _ := json.Marshal(merged)
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	place := existing
+	snapshot.Apply(&place)
+	if err := validation.ImportedPlace(&place); err != nil {
+		result.Status, result.Errors = StatusInvalid, err.(validation.Errors)
+		return nil
+	}
+	result.Name = place.Name
+
+	osmSnapshot, err := json.Marshal(incomingSnapshot)
+	if err != nil {
+		return err
+	}
+	place.OSMSnapshot = string(osmSnapshot)
+	amenities := knownAmenities(vocabulary, snapshot.Amenities)
+	changed := !reflect.DeepEqual(current, snapshotFields(models.SnapshotOf(place, amenities)))
+	switch {
+	case opts.DryRun && changed:
+		result.Status = StatusValid
+		return nil
+	case opts.DryRun:
+		result.Status = StatusUnchanged
+		return nil
+	case !changed:
+		// Only remember what OSM now says, which needs no new revision.
+		result.Status = StatusUnchanged
+		return db.Model(&existing).UpdateColumn("osm_snapshot", place.OSMSnapshot).Error
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := slug.Assign(tx, &place); err != nil {
+			return err
+		}
+		place.Version = existing.Version + 1
+		update := tx.Model(&place).Where("version = ?", existing.Version).
+			Select("*").Omit("created_at", clause.Associations).Updates(&place)
+		if update.Error != nil {
+			return update.Error
+		}
+		if update.RowsAffected == 0 {
+			return fmt.Errorf("place %d was changed during the import", place.ID)
+		}
+		if err := tx.Model(&place).Association("Amenities").Replace(amenities); err != nil {
+			return err
+		}
+		revision, err := models.NewPlaceRevision(place, amenities, models.RevisionImport, opts.AuthorID)
+		if err != nil {
+			return err
+		}
+		return tx.Create(&revision).Error
+	})
+	if err == nil {
+		result.Status = StatusUpdated
+	}
+	return err
+}
+
+// snapshotFields returns a snapshot as a map keyed by JSON field name, with
+// amenities sorted so that snapshots can be compared.
+func snapshotFields(snapshot models.PlaceSnapshot) map[string]interface{} {
+	sorted := append([]string{}, snapshot.Amenities...)
+	sort.Strings(sorted)
+	snapshot.Amenities = sorted
+	data, _ := json.Marshal(snapshot)
+	fields := map[string]interface{}{}
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+func knownAmenities(vocabulary map[string]models.Amenity, slugs []string) []models.Amenity {
+	amenities := []models.Amenity{}
+	for _, slug := range slugs {
+		if amenity, ok := vocabulary[slug]; ok {
+			amenities = append(amenities, amenity)
+		}
+	}
+	return amenities
+}
@@ -0,0 +1,108 @@
+package importer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/laurawarren88/go_spa_backend.git/importer"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/osm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsFitnessVenue(t *testing.T) {
+	assert.True(t, importer.IsFitnessVenue(map[string]string{"name": "Gym", "leisure": "fitness_centre"}))
+	assert.True(t, importer.IsFitnessVenue(map[string]string{"name": "Dojo", "amenity": "dojo"}))
+	assert.True(t, importer.IsFitnessVenue(map[string]string{"name": "Wall", "sport": "climbing"}))
+	assert.False(t, importer.IsFitnessVenue(map[string]string{"leisure": "fitness_centre"}))
+	assert.False(t, importer.IsFitnessVenue(map[string]string{"name": "Pitch 3", "leisure": "pitch", "sport": "soccer"}))
+	assert.False(t, importer.IsFitnessVenue(map[string]string{"name": "Bike Shop", "shop": "bicycle", "sport": "cycling"}))
+	assert.False(t, importer.IsFitnessVenue(map[string]string{"name": "Hotel Gym", "leisure": "fitness_centre", "access": "private"}))
+}
+
+func osmGym(phone, description string) osm.Element {
+	return osm.Element{Type: "node", ID: 1, Lat: 53.40, Lon: -2.10, HasLocation: true, Tags: map[string]string{
+		"name": "Quay Fitness", "leisure": "fitness_centre", "addr:housenumber": "2", "addr:street": "Quay St",
+		"addr:city": "Manchester", "addr:postcode": "m3 3aa", "phone": phone, "description": description,
+		"website": "quayfitness.example", "sauna": "yes", "wheelchair": "yes",
+	}}
+}
+
+func TestImportOSM(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	opts := importer.OSMOptions{OwnerID: 1, DedupeRadius: importer.DefaultDedupeRadius}
+
+	elements := []osm.Element{
+		osmGym("0161 496 0100", "Open all hours"),
+		{Type: "way", ID: 2, Tags: map[string]string{"name": "No Location", "leisure": "sports_centre"}},
+		{Type: "node", ID: 3, Lat: 53.7, Lon: -2.4, HasLocation: true, Tags: map[string]string{
+			"name": "No Phone", "sport": "yoga", "addr:postcode": "M3",
+		}},
+		{Type: "node", ID: 4, Lat: 53.48001, Lon: -2.24001, HasLocation: true, Tags: map[string]string{
+			"name": "Existing Gym", "leisure": "fitness_centre", "phone": "0161 496 0000",
+		}},
+	}
+
+	report, err := importer.ImportOSM(ctx, db, elements, importer.OSMOptions{OwnerID: 1, DryRun: true, DedupeRadius: importer.DefaultDedupeRadius})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"valid", "invalid", "valid", "duplicate"}, statuses(report))
+
+	report, err = importer.ImportOSM(ctx, db, elements, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"created", "invalid", "created", "duplicate"}, statuses(report))
+	assert.True(t, report.Rows[1].Errors.Has("geometry"))
+	assert.Equal(t, uint(1), report.Rows[3].DuplicateOf)
+
+	var place models.Place
+	assert.NoError(t, db.Preload("Amenities").First(&place, report.Rows[0].PlaceID).Error)
+	assert.Equal(t, "node/1", *place.OSMID)
+	assert.Equal(t, "gym", place.Type)
+	assert.Equal(t, "2 Quay St", place.Vicinity)
+	assert.Equal(t, "M3 3AA", place.Postcode)
+	assert.Equal(t, "+441614960100", place.Phone)
+	assert.Equal(t, "https://quayfitness.example", place.Website)
+	assert.Len(t, place.Amenities, 1)
+
+	// Most venues have no phone number, and a partial postcode is dropped.
+	var yoga models.Place
+	assert.NoError(t, db.First(&yoga, report.Rows[2].PlaceID).Error)
+	assert.Equal(t, "", yoga.Phone)
+	assert.Equal(t, "", yoga.Postcode)
+
+	// The owner rewrites the description; OSM then changes both it and the
+	// phone number.
+	assert.NoError(t, db.Model(&place).Update("description", "Our own words").Error)
+	changed := []osm.Element{osmGym("0161 496 0199", "Open most hours")}
+	report, err = importer.ImportOSM(ctx, db, changed, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"updated"}, statuses(report))
+	assert.Equal(t, []string{"description"}, report.Rows[0].Kept)
+
+	assert.NoError(t, db.First(&place, place.ID).Error)
+	assert.Equal(t, "+441614960199", place.Phone)
+	assert.Equal(t, "Our own words", place.Description)
+	assert.Equal(t, uint(2), place.Version)
+	var revisions int64
+	db.Model(&models.PlaceRevision{}).Where("place_id = ? AND action = ?", place.ID, models.RevisionImport).Count(&revisions)
+	assert.Equal(t, int64(2), revisions)
+
+	report, err = importer.ImportOSM(ctx, db, changed, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"unchanged"}, statuses(report))
+	assert.Equal(t, 1, report.Unchanged)
+
+	// An update that no longer validates is reported, not saved.
+	broken := osmGym("0161 496 0199", "Open most hours")
+	broken.Tags["website"] = "http://"
+	report, err = importer.ImportOSM(ctx, db, []osm.Element{broken}, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"invalid"}, statuses(report))
+	assert.True(t, report.Rows[0].Errors.Has("website"))
+
+	// A deleted place is not brought back.
+	assert.NoError(t, db.Delete(&place).Error)
+	report, err = importer.ImportOSM(ctx, db, changed, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"skipped"}, statuses(report))
+}
@@ -0,0 +1,276 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/validation"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatGeoJSON = "geojson"
+)
+
+// Row is one place read from an import file, before it has been validated.
+type Row struct {
+	// Number is the 1-based position of the row among the data rows or
+	// features of the file.
+	Number    int
+	Place     models.Place
+	Amenities []string
+	// Errors holds the problems found while reading the row, such as
+	// coordinates that are not numbers.
+	Errors validation.Errors
+}
+
+// columns maps the accepted column and property names to place fields.
+var columns = map[string]string{
+	"name":          "name",
+	"vicinity":      "vicinity",
+	"address":       "vicinity",
+	"city":          "city",
+	"town":          "city",
+	"postcode":      "postcode",
+	"postal_code":   "postcode",
+	"phone":         "phone",
+	"telephone":     "phone",
+	"email":         "email",
+	"website":       "website",
+	"url":           "website",
+	"opening_hours": "opening_hours",
+	"description":   "description",
+	"type":          "type",
+	"category":      "type",
+	"latitude":      "latitude",
+	"lat":           "latitude",
+	"longitude":     "longitude",
+	"lng":           "longitude",
+	"lon":           "longitude",
+	"amenities":     "amenities",
+}
+
+// FormatFromName guesses the format of an import file from its name.
+func FormatFromName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".geojson", ".json":
+		return FormatGeoJSON
+	}
+	return ""
+}
+
+// Parse reads rows in the given format.
+func Parse(format string, r io.Reader) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(r)
+	case FormatGeoJSON:
+		return ParseGeoJSON(r)
+	}
+	return nil, fmt.Errorf("unsupported import format %q", format)
+}
+
+// ParseCSV reads a CSV file whose first line names the columns. Column names
+// are matched case-insensitively against the place fields and a few common
+// aliases; unknown columns are ignored.
+func ParseCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	fields := make([]string, len(header))
+	hasName := false
+	for i, column := range header {
+		fields[i] = columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))]
+		hasName = hasName || fields[i] == "name"
+	}
+	if !hasName {
+		return nil, fmt.Errorf("CSV header must include a name column")
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		row := Row{Number: len(rows) + 1}
+		var hasLat, hasLng bool
+		for i, value := range record {
+			if i >= len(fields) || fields[i] == "" {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			switch fields[i] {
+			case "latitude":
+				hasLat = setCoordinate(&row, "latitude", value, &row.Place.Latitude)
+			case "longitude":
+				hasLng = setCoordinate(&row, "longitude", value, &row.Place.Longitude)
+			case "amenities":
+				row.Amenities = splitList(value)
+			default:
+				setText(&row.Place, fields[i], value)
+			}
+		}
+		requireCoordinates(&row, hasLat, hasLng)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type     string `json:"type"`
+	Geometry *struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// ParseGeoJSON reads a FeatureCollection of Point features. Coordinates come
+// from the geometry and the other fields from the feature properties, using
+// the same names as CSV columns.
+func ParseGeoJSON(r io.Reader) ([]Row, error) {
+	var collection featureCollection
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+	if collection.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("GeoJSON must be a FeatureCollection")
+	}
+
+	rows := make([]Row, 0, len(collection.Features))
+	for i, f := range collection.Features {
+		row := Row{Number: i + 1}
+		keys := make([]string, 0, len(f.Properties))
+		for key := range f.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := f.Properties[key]
+			field := columns[strings.ToLower(key)]
+			switch field {
+			case "", "latitude", "longitude":
+			case "amenities":
+				row.Amenities = propertyList(value)
+			default:
+				setText(&row.Place, field, propertyString(value))
+			}
+		}
+
+		var point []float64
+		switch {
+		case f.Geometry == nil:
+			row.Errors.Add("geometry", validation.CodeRequired, "feature has no geometry")
+		case f.Geometry.Type != "Point":
+			row.Errors.Add("geometry", validation.CodeInvalidType, "geometry must be a Point, not %s", f.Geometry.Type)
+		case json.Unmarshal(f.Geometry.Coordinates, &point) != nil || len(point) < 2:
+			row.Errors.Add("geometry", validation.CodeInvalidNumber, "point coordinates must be [longitude, latitude]")
+		default:
+			row.Place.Longitude, row.Place.Latitude = point[0], point[1]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func setText(place *models.Place, field, value string) {
+	switch field {
+	case "name":
+		place.Name = value
+	case "vicinity":
+		place.Vicinity = value
+	case "city":
+		place.City = value
+	case "postcode":
+		place.Postcode = value
+	case "phone":
+		place.Phone = value
+	case "email":
+		place.Email = value
+	case "website":
+		place.Website = value
+	case "opening_hours":
+		place.OpeningHours = value
+	case "description":
+		place.Description = value
+	case "type":
+		place.Type = value
+	}
+}
+
+func setCoordinate(row *Row, field, value string, target *float64) bool {
+	if value == "" {
+		return false
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		row.Errors.Add(field, validation.CodeInvalidNumber, "%s must be a number", field)
+		return true
+	}
+	*target = number
+	return true
+}
+
+func requireCoordinates(row *Row, hasLat, hasLng bool) {
+	if !hasLat {
+		row.Errors.Add("latitude", validation.CodeRequired, "latitude is required")
+	}
+	if !hasLng {
+		row.Errors.Add("longitude", validation.CodeRequired, "longitude is required")
+	}
+}
+
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' })
+}
+
+func propertyString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func propertyList(value interface{}) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		return splitList(propertyString(value))
+	}
+	var list []string
+	for _, item := range items {
+		list = append(list, propertyString(item))
+	}
+	return list
+}
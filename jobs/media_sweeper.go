@@ -0,0 +1,106 @@
+// Package jobs holds the background maintenance tasks run alongside the API.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/laurawarren88/go_spa_backend.git/media"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+	"gorm.io/gorm"
+)
+
+// mediaPrefixes are the parts of the store written by the upload handlers.
+var mediaPrefixes = []string{"logos/", "facilities/", "gallery/"}
+
+// referencedMedia returns the store keys, thumbnails included, that are still
+// referenced from the database. Soft-deleted places keep their media so they
+// can be restored.
+func referencedMedia(db *gorm.DB) (map[string]bool, error) {
+	var refs []string
+	var logos, facilities, gallery []string
+	if err := db.Unscoped().Model(&models.Place{}).Pluck("logo", &logos).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Unscoped().Model(&models.Place{}).Pluck("facilities_image", &facilities).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.PlaceImage{}).Pluck("path", &gallery).Error; err != nil {
+		return nil, err
+	}
+	refs = append(append(append(refs, logos...), facilities...), gallery...)
+
+	keys := map[string]bool{}
+	for _, ref := range refs {
+		for _, key := range mediaKeys(ref) {
+			keys[key] = true
+		}
+	}
+	return keys, nil
+}
+
+// mediaKeys returns the store keys of an uploaded image and its thumbnails.
+// Empty references and images hosted elsewhere have none.
+func mediaKeys(ref string) []string {
+	if ref == "" || storage.IsExternal(ref) {
+		return nil
+	}
+	key := storage.NormalizeKey(ref)
+	keys := []string{key}
+	for _, thumb := range media.ThumbnailSizes {
+		keys = append(keys, media.ThumbnailName(key, thumb.Name))
+	}
+	return keys
+}
+
+// SweepOrphanedMedia deletes stored media that nothing in the database refers
+// to, such as uploads whose request failed half way. Blobs younger than grace
+// are left alone so that uploads still waiting for their row to be committed
+// are not removed. It returns the number of blobs deleted.
+func SweepOrphanedMedia(ctx context.Context, db *gorm.DB, store storage.BlobStore, grace time.Duration) (int, error) {
+	referenced, err := referencedMedia(db)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-grace)
+	removed := 0
+	for _, prefix := range mediaPrefixes {
+		objects, err := store.List(ctx, prefix)
+		if err != nil {
+			return removed, err
+		}
+		for _, object := range objects {
+			if referenced[object.Key] || object.ModTime.After(cutoff) {
+				continue
+			}
+			if err := store.Delete(ctx, object.Key); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// StartMediaSweeper runs SweepOrphanedMedia every interval until ctx is done.
+func StartMediaSweeper(ctx context.Context, db *gorm.DB, store storage.BlobStore, interval, grace time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		removed, err := SweepOrphanedMedia(ctx, db, store, grace)
+		if err != nil {
+			log.Printf("Error sweeping orphaned media: %v", err)
+		} else if removed > 0 {
+			log.Printf("Removed %d orphaned media files", removed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package jobs_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/laurawarren88/go_spa_backend.git/jobs"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSweepOrphanedMedia(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.User{}, &models.Place{}, &models.PlaceImage{}))
+
+	ctx := context.Background()
+	store := storage.NewLocal(t.TempDir(), "/uploads")
+	keys := []string{
+		"logos/kept.jpg", "logos/kept_small.jpg",
+		"facilities/legacy.png",
+		"gallery/kept.jpg", "gallery/orphan.jpg", "gallery/orphan_small.jpg",
+		"gallery/fresh.jpg",
+		"logos/deleted-place.jpg",
+	}
+	for _, key := range keys {
+		assert.NoError(t, store.Put(ctx, key, strings.NewReader("x"), "image/jpeg"))
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	for _, key := range keys {
+		if key != "gallery/fresh.jpg" {
+			assert.NoError(t, os.Chtimes(filepath.Join(store.Root, key), old, old))
+		}
+	}
+
+	place := models.Place{Name: "Gym", Logo: "logos/kept.jpg", FacilitiesImage: "./uploads/facilities/legacy.png"}
+	assert.NoError(t, db.Create(&place).Error)
+	assert.NoError(t, db.Create(&models.PlaceImage{PlaceID: place.ID, Path: "gallery/kept.jpg"}).Error)
+	deleted := models.Place{Name: "Closed", Logo: "logos/deleted-place.jpg"}
+	assert.NoError(t, db.Create(&deleted).Error)
+	assert.NoError(t, db.Delete(&deleted).Error)
+
+	removed, err := jobs.SweepOrphanedMedia(ctx, db, store, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	objects, err := store.List(ctx, "")
+	assert.NoError(t, err)
+	var remaining []string
+	for _, object := range objects {
+		remaining = append(remaining, object.Key)
+	}
+	assert.ElementsMatch(t, []string{
+		"logos/kept.jpg", "logos/kept_small.jpg", "facilities/legacy.png",
+		"gallery/kept.jpg", "gallery/fresh.jpg", "logos/deleted-place.jpg",
+	}, remaining)
+}
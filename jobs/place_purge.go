@@ -0,0 +1,135 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+	"gorm.io/gorm"
+)
+
+// PurgePlace permanently removes a place along with everything hanging off
+// it: prices, amenity links, gallery images, revisions, old slugs and
+// redirects to it, classes with their sessions and bookings, and finally its
+// media. Events held at the place are kept but no longer linked to it, and
+// media that another place still uses, such as a logo taken over in a merge,
+// is kept.
+func PurgePlace(ctx context.Context, db *gorm.DB, store storage.BlobStore, placeID uint) error {
+	var place models.Place
+	if err := db.Unscoped().First(&place, placeID).Error; err != nil {
+		return err
+	}
+	var images []models.PlaceImage
+	if err := db.Unscoped().Where("place_id = ?", place.ID).Find(&images).Error; err != nil {
+		return err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var classIDs, sessionIDs []uint
+		if err := tx.Unscoped().Model(&models.Class{}).Where("place_id = ?", place.ID).Pluck("id", &classIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.ClassSession{}).Where("class_id IN ?", classIDs).Pluck("id", &sessionIDs).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("session_id IN ?", sessionIDs).Delete(&models.Booking{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("class_id IN ?", classIDs).Delete(&models.ClassSession{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("class_id IN ?", classIDs).Delete(&models.ClassSchedule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("place_id = ?", place.ID).Delete(&models.Class{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Event{}).Where("place_id = ?", place.ID).Update("place_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("place_id = ?", place.ID).Delete(&models.PlacePrice{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM place_amenities WHERE place_id = ?", place.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("place_id = ?", place.ID).Delete(&models.PlaceImage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("place_id = ?", place.ID).Delete(&models.PlaceRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("to_id = ?", place.ID).Delete(&models.PlaceRedirect{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("place_id = ?", place.ID).Delete(&models.PlaceSlug{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&place).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	inUse, err := referencedMedia(db)
+	if err != nil {
+		return err
+	}
+	refs := []string{place.Logo, place.FacilitiesImage}
+	for _, image := range images {
+		refs = append(refs, image.Path)
+	}
+	for _, ref := range refs {
+		for _, key := range mediaKeys(ref) {
+			if inUse[key] {
+				continue
+			}
+			if err := store.Delete(ctx, key); err != nil {
+				log.Printf("Failed to delete media: %s: %s", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// PurgeDeletedPlaces purges every place that has been in the trash for longer
+// than retention and returns how many were removed.
+func PurgeDeletedPlaces(ctx context.Context, db *gorm.DB, store storage.BlobStore, retention time.Duration) (int, error) {
+	var ids []uint
+	err := db.Unscoped().Model(&models.Place{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", time.Now().Add(-retention)).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		if err := PurgePlace(ctx, db, store, id); err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+// StartPlacePurger runs PurgeDeletedPlaces every interval until ctx is done.
+func StartPlacePurger(ctx context.Context, db *gorm.DB, store storage.BlobStore, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := PurgeDeletedPlaces(ctx, db, store, retention)
+		if err != nil {
+			log.Printf("Error purging deleted places: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deleted places", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package jobs_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/laurawarren88/go_spa_backend.git/jobs"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestPurgeDeletedPlacesHonoursRetention(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(
+		&models.User{}, &models.Place{}, &models.Class{}, &models.ClassSchedule{},
+		&models.ClassSession{}, &models.Booking{}, &models.Event{}, &models.PlacePrice{},
+		&models.Amenity{}, &models.PlaceImage{}, &models.PlaceRevision{}, &models.PlaceRedirect{},
+		&models.PlaceSlug{},
+	))
+
+	places := []models.Place{{Name: "Live"}, {Name: "Recently deleted"}, {Name: "Long gone"}}
+	assert.NoError(t, db.Create(&places).Error)
+	db.Unscoped().Model(&places[1]).Update("deleted_at", time.Now().Add(-24*time.Hour))
+	db.Unscoped().Model(&places[2]).Update("deleted_at", time.Now().Add(-40*24*time.Hour))
+
+	placeID := places[2].ID
+	event := models.Event{Title: "Open day", PlaceID: &placeID, StartsAt: time.Now(), EndsAt: time.Now().Add(time.Hour)}
+	assert.NoError(t, db.Create(&event).Error)
+	assert.NoError(t, db.Create(&models.PlacePrice{PlaceID: placeID, Kind: models.PriceDayPass, AmountPence: 800}).Error)
+	assert.NoError(t, db.Create(&models.PlaceRevision{PlaceID: placeID, Version: 1, Action: models.RevisionCreate, Snapshot: "{}"}).Error)
+
+	purged, err := jobs.PurgeDeletedPlaces(context.Background(), db, storage.NewLocal(t.TempDir(), "/uploads"), 30*24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	var names []string
+	db.Unscoped().Model(&models.Place{}).Order("id").Pluck("name", &names)
+	assert.Equal(t, []string{"Live", "Recently deleted"}, names)
+
+	var count int64
+	db.Model(&models.PlacePrice{}).Unscoped().Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&models.PlaceRevision{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.First(&event, event.ID)
+	assert.Nil(t, event.PlaceID)
+}
+
+func TestPurgePlaceKeepsMediaUsedElsewhere(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(
+		&models.User{}, &models.Place{}, &models.Class{}, &models.ClassSchedule{},
+		&models.ClassSession{}, &models.Booking{}, &models.Event{}, &models.PlacePrice{},
+		&models.Amenity{}, &models.PlaceImage{}, &models.PlaceRevision{}, &models.PlaceRedirect{},
+		&models.PlaceSlug{},
+	))
+
+	ctx := context.Background()
+	store := storage.NewLocal(t.TempDir(), "/uploads")
+	for _, key := range []string{"logos/shared.jpg", "facilities/own.jpg"} {
+		assert.NoError(t, store.Put(ctx, key, strings.NewReader("x"), "image/jpeg"))
+	}
+
+	// The merged place's logo was taken over by the place it was merged into.
+	places := []models.Place{
+		{Name: "Merged", Logo: "logos/shared.jpg", FacilitiesImage: "facilities/own.jpg"},
+		{Name: "Kept", Logo: "logos/shared.jpg"},
+	}
+	assert.NoError(t, db.Create(&places).Error)
+	assert.NoError(t, db.Create(&models.PlaceRedirect{FromID: 3, ToID: places[0].ID}).Error)
+	assert.NoError(t, db.Delete(&places[0]).Error)
+
+	assert.NoError(t, jobs.PurgePlace(ctx, db, store, places[0].ID))
+
+	objects, err := store.List(ctx, "")
+	assert.NoError(t, err)
+	var keys []string
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	assert.Equal(t, []string{"logos/shared.jpg"}, keys)
+
+	var redirects int64
+	db.Model(&models.PlaceRedirect{}).Count(&redirects)
+	assert.Equal(t, int64(0), redirects)
+}
@@ -1,36 +1,15 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"log"
+	"os"
 
-	"github.com/laurawarren88/go_spa_backend.git/config"
-	"github.com/laurawarren88/go_spa_backend.git/database"
+	"github.com/laurawarren88/go_spa_backend.git/cli"
 )
 
-func init() {
-	config.LoadEnv()
-	config.SetGinMode()
-}
-
 func main() {
-	database.ConnectToDB()
-	db := database.GetDB()
-
-	if err := database.SetupAdminUser(db); err != nil {
-		log.Printf("Error setting up admin user: %v", err)
-	}
-
-	router := config.SetupServer()
-
-	config.SetupHandlers(router, db)
-
-	port := config.GetEnv("PORT", "8081")
-	fmt.Printf("Starting the server on port %s\n", port)
-	log.Printf("Starting the server on port %s\n", port)
-
-	// Start the server on the specified port
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start the server:", err)
+	if err := cli.Run(context.Background(), os.Args[1:], os.Stdout); err != nil {
+		log.Fatal(err)
 	}
 }
@@ -0,0 +1,197 @@
+// Package media validates uploaded images and prepares them for storage.
+//
+// Uploads are sniffed rather than trusted by extension, decoded, and
+// re-encoded so that EXIF, GPS and any other embedded metadata is dropped.
+// Files are named after a hash of their re-encoded content plus a random
+// suffix, so names never come from the client and two uploads of the same
+// picture do not share a file.
+package media
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedType = errors.New("only JPEG, PNG and WebP images are accepted")
+	ErrTooLarge        = errors.New("image file is too large")
+	ErrDimensions      = errors.New("image dimensions are out of range")
+	ErrCorrupt         = errors.New("image could not be decoded")
+)
+
+type Limits struct {
+	MaxBytes  int64
+	MinWidth  int
+	MinHeight int
+	MaxWidth  int
+	MaxHeight int
+}
+
+var DefaultLimits = Limits{
+	MaxBytes:  10 << 20,
+	MinWidth:  16,
+	MinHeight: 16,
+	MaxWidth:  8000,
+	MaxHeight: 8000,
+}
+
+// ThumbnailSizes maps thumbnail names to the length of their longest side.
+var ThumbnailSizes = []struct {
+	Name string
+	Size int
+}{
+	{"small", 160},
+	{"medium", 480},
+	{"large", 1200},
+}
+
+type Variant struct {
+	Filename    string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+type Processed struct {
+	Original   Variant
+	Thumbnails map[string]Variant
+}
+
+var acceptedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// IsValidationError reports whether err was caused by the upload itself
+// rather than by the server.
+func IsValidationError(err error) bool {
+	return errors.Is(err, ErrUnsupportedType) || errors.Is(err, ErrTooLarge) ||
+		errors.Is(err, ErrDimensions) || errors.Is(err, ErrCorrupt)
+}
+
+// Process validates the image read from r against limits and returns the
+// cleaned original along with its thumbnails.
+func Process(r io.Reader, limits Limits) (*Processed, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limits.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limits.MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !acceptedTypes[contentType] {
+		return nil, ErrUnsupportedType
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || "image/"+format != contentType {
+		return nil, ErrCorrupt
+	}
+	if config.Width < limits.MinWidth || config.Height < limits.MinHeight ||
+		config.Width > limits.MaxWidth || config.Height > limits.MaxHeight {
+		return nil, fmt.Errorf("%w: %dx%d", ErrDimensions, config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	asPNG := false
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+		asPNG = true
+	}
+
+	original, err := encode(img, asPNG)
+	if err != nil {
+		return nil, err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	ext := path.Ext(original.Filename)
+	original.Filename = strings.TrimSuffix(original.Filename, ext) + "-" + hex.EncodeToString(suffix) + ext
+
+	processed := &Processed{Original: original, Thumbnails: map[string]Variant{}}
+	for _, thumb := range ThumbnailSizes {
+		variant, err := encode(resize(img, thumb.Size), asPNG)
+		if err != nil {
+			return nil, err
+		}
+		variant.Filename = ThumbnailName(original.Filename, thumb.Name)
+		processed.Thumbnails[thumb.Name] = variant
+	}
+	return processed, nil
+}
+
+// ThumbnailName returns the file name of the named thumbnail of filename.
+func ThumbnailName(filename, size string) string {
+	ext := path.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "_" + size + ext
+}
+
+// encode writes img as PNG when it has transparency to keep and as JPEG
+// otherwise, and names the result after the SHA-256 of the encoded bytes.
+func encode(img image.Image, asPNG bool) (Variant, error) {
+	var buf bytes.Buffer
+	contentType, ext := "image/jpeg", ".jpg"
+	if asPNG {
+		contentType, ext = "image/png", ".png"
+		if err := png.Encode(&buf, img); err != nil {
+			return Variant{}, err
+		}
+	} else if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return Variant{}, err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	bounds := img.Bounds()
+	return Variant{
+		Filename:    hex.EncodeToString(sum[:16]) + ext,
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// resize scales img so its longest side is at most size pixels.
+func resize(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
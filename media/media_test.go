@@ -0,0 +1,103 @@
+package media_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/laurawarren88/go_spa_backend.git/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeJPEG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{G: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+// withExif inserts an APP1 segment carrying an orientation tag and a fake GPS
+// marker straight after the JPEG SOI marker.
+func withExif(data []byte, orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8,
+		0, 1,
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0,
+		0, 0, 0, 0,
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	payload = append(payload, []byte("GPS 51.5074N")...)
+	length := len(payload) + 2
+	segment := append([]byte{0xFF, 0xE1, byte(length >> 8), byte(length)}, payload...)
+	return append(append([]byte{0xFF, 0xD8}, segment...), data[2:]...)
+}
+
+func TestProcessRejectsUnsupportedFiles(t *testing.T) {
+	_, err := media.Process(bytes.NewReader([]byte("GIF89a not really")), media.DefaultLimits)
+	assert.ErrorIs(t, err, media.ErrUnsupportedType)
+
+	_, err = media.Process(bytes.NewReader([]byte("<svg xmlns='http://www.w3.org/2000/svg'/>")), media.DefaultLimits)
+	assert.ErrorIs(t, err, media.ErrUnsupportedType)
+
+	truncated := encodeJPEG(t, 64, 64)[:40]
+	_, err = media.Process(bytes.NewReader(truncated), media.DefaultLimits)
+	assert.True(t, media.IsValidationError(err))
+}
+
+func TestProcessEnforcesLimits(t *testing.T) {
+	data := encodeJPEG(t, 64, 32)
+
+	_, err := media.Process(bytes.NewReader(data), media.Limits{MaxBytes: 10, MaxWidth: 100, MaxHeight: 100})
+	assert.ErrorIs(t, err, media.ErrTooLarge)
+
+	_, err = media.Process(bytes.NewReader(data), media.Limits{MaxBytes: 1 << 20, MinWidth: 16, MinHeight: 16, MaxWidth: 50, MaxHeight: 50})
+	assert.ErrorIs(t, err, media.ErrDimensions)
+
+	_, err = media.Process(bytes.NewReader(data), media.Limits{MaxBytes: 1 << 20, MinWidth: 16, MinHeight: 48, MaxWidth: 100, MaxHeight: 100})
+	assert.ErrorIs(t, err, media.ErrDimensions)
+}
+
+func TestProcessStripsMetadataAndAppliesOrientation(t *testing.T) {
+	data := withExif(encodeJPEG(t, 64, 32), 6)
+
+	processed, err := media.Process(bytes.NewReader(data), media.DefaultLimits)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", processed.Original.ContentType)
+	assert.False(t, bytes.Contains(processed.Original.Data, []byte("Exif")))
+	assert.False(t, bytes.Contains(processed.Original.Data, []byte("GPS")))
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(processed.Original.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, 32, config.Width)
+	assert.Equal(t, 64, config.Height)
+}
+
+func TestProcessThumbnails(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 600, 300))
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+
+	processed, err := media.Process(bytes.NewReader(buf.Bytes()), media.DefaultLimits)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", processed.Original.ContentType)
+	assert.Len(t, processed.Thumbnails, len(media.ThumbnailSizes))
+
+	small := processed.Thumbnails["small"]
+	assert.Equal(t, 160, small.Width)
+	assert.Equal(t, 80, small.Height)
+	assert.Equal(t, media.ThumbnailName(processed.Original.Filename, "small"), small.Filename)
+
+	large := processed.Thumbnails["large"]
+	assert.Equal(t, 600, large.Width)
+
+	again, err := media.Process(bytes.NewReader(buf.Bytes()), media.DefaultLimits)
+	assert.NoError(t, err)
+	assert.NotEqual(t, processed.Original.Filename, again.Original.Filename)
+	assert.Equal(t, processed.Original.Filename[:32], again.Original.Filename[:32])
+}
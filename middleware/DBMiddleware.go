@@ -2,12 +2,12 @@ package middleware
 
 import (
 	"github.com/gin-gonic/gin"
-	"github.com/laurawarren88/go_spa_backend.git/database"
+	"gorm.io/gorm"
 )
 
-func DBMiddleware() gin.HandlerFunc {
+func DBMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Set("db", database.GetDB())
+		c.Set("db", db)
 		c.Next()
 	}
 }
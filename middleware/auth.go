@@ -4,15 +4,17 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/laurawarren88/go_spa_backend.git/config"
 	"github.com/laurawarren88/go_spa_backend.git/models"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware accepts requests with a valid access token. When the access
+// token has expired, a valid refresh token earns a new access cookie.
+func AuthMiddleware(auth config.AuthConfig, cookies config.CookieConfig) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var accessToken string
 
@@ -35,7 +37,7 @@ func AuthMiddleware() gin.HandlerFunc {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
-			return []byte(os.Getenv("ACCESS_SECRET_KEY")), nil
+			return []byte(auth.AccessSecret), nil
 		})
 
 		if err != nil {
@@ -51,7 +53,7 @@ func AuthMiddleware() gin.HandlerFunc {
 					if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 						return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 					}
-					return []byte(os.Getenv("REFRESH_SECRET_KEY")), nil
+					return []byte(auth.RefreshSecret), nil
 				})
 				if err != nil {
 					ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
@@ -60,19 +62,14 @@ func AuthMiddleware() gin.HandlerFunc {
 				}
 
 				var user models.User
-				accessToken, err := GenerateToken(user)
+				accessToken, err := GenerateToken(user, auth.AccessSecret)
 				if err != nil {
 					ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
 					ctx.Abort()
 					return
 				}
 
-				domain, secure, httpOnly, err := GetCookieSettings()
-				if err != nil {
-					log.Fatalf("Failed to parse environment variables: %v", err)
-				}
-
-				ctx.SetCookie("access_token", accessToken, 3600*1, "/", domain, secure, httpOnly)
+				ctx.SetCookie("access_token", accessToken, 3600*1, "/", cookies.Domain, cookies.Secure, cookies.HTTPOnly)
 
 				ctx.Set("userID", user.ID)
 				ctx.Set("isAdmin", user.IsAdmin)
@@ -1,53 +1,30 @@
 package middleware
 
 import (
-	"time"
-
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/config"
 )
 
-func CORSMiddleware() gin.HandlerFunc {
-	// env := os.Getenv("GO_ENV")
-
-	// var allowedOrigins []string
-	// if env == "development" {
-	// 	allowedOrigins = []string{
-	// 		os.Getenv("BASE_URL"),
-	// 	}
-	// } else {
-	// 	allowedOrigins = []string{
-	// 		os.Getenv("BASE_URL"),
-	// 	}
-	// }
-
-	return cors.New(cors.Config{
-		AllowOrigins: []string{
-			"http://localhost:8081",
-		},
-		AllowMethods: []string{
-			"GET",
-			"POST",
-			"PUT",
-			"DELETE",
-			"OPTIONS",
-		},
-		AllowHeaders: []string{
-			"Content-Type",
-			"Content-Length",
-			"Accept-Encoding",
-			"Authorization",
-			"Accept",
-			"Origin",
-			"Cache-Control",
-			"X-Requested-With",
-		},
+// CORSMiddleware answers preflight requests and sets the CORS headers for the
+// origins in cfg. Requests from other origins are refused with 403.
+func CORSMiddleware(cfg config.CORSConfig) gin.HandlerFunc {
+	corsConfig := cors.Config{
+		AllowMethods: cfg.AllowedMethods,
+		AllowHeaders: cfg.AllowedHeaders,
 		ExposeHeaders: []string{
 			"Content-Length",
 			"Content-Type",
 			"Content-Disposition",
+			"ETag",
 		},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	})
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	}
+	if cfg.AllowsAnyOrigin() {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOriginFunc = cfg.AllowOrigin
+	}
+	return cors.New(corsConfig)
 }
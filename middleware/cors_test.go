@@ -0,0 +1,99 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/config"
+	"github.com/laurawarren88/go_spa_backend.git/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func corsRouter(cfg config.CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.CORSMiddleware(cfg))
+	router.GET("/api/places", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"places": []string{}})
+	})
+	return router
+}
+
+func corsRequest(router *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "http://api.fitness.test/api/places", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		req.Header.Set("Access-Control-Request-Headers", "Authorization")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	cfg := config.Default().CORS
+	cfg.AllowedOrigins = []string{"http://localhost:5050", "https://*.example.com"}
+	router := corsRouter(cfg)
+
+	t.Run("preflight from an allowed origin", func(t *testing.T) {
+		w := corsRequest(router, http.MethodOptions, "http://localhost:5050")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "http://localhost:5050", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "PATCH")
+		assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+		assert.Equal(t, "43200", w.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("preflight from a subdomain", func(t *testing.T) {
+		w := corsRequest(router, http.MethodOptions, "https://app.example.com")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("preflight from other origins is refused", func(t *testing.T) {
+		for _, origin := range []string{
+			"http://localhost:8081",
+			"https://example.com",
+			"https://evilexample.com",
+			"https://app.example.com.evil.net",
+			"http://app.example.com",
+		} {
+			w := corsRequest(router, http.MethodOptions, origin)
+			assert.Equal(t, http.StatusForbidden, w.Code, origin)
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), origin)
+		}
+	})
+
+	t.Run("simple request", func(t *testing.T) {
+		w := corsRequest(router, http.MethodGet, "https://app.example.com")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "Etag")
+	})
+
+	t.Run("request without an origin", func(t *testing.T) {
+		w := corsRequest(router, http.MethodGet, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestCORSMiddlewareAnyOrigin(t *testing.T) {
+	router := corsRouter(config.CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET"},
+		MaxAge:         time.Hour,
+	})
+
+	w := corsRequest(router, http.MethodOptions, "https://anywhere.test")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
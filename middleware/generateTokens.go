@@ -3,7 +3,6 @@ package middleware
 import (
 	"fmt"
 	"log"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -17,7 +16,7 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(user models.User) (string, error) {
+func GenerateToken(user models.User, accessSecret string) (string, error) {
 	claims := Claims{
 		UserID:   user.ID,
 		Username: user.Username,
@@ -27,10 +26,8 @@ func GenerateToken(user models.User) (string, error) {
 		},
 	}
 
-	accessSecret := os.Getenv("ACCESS_SECRET_KEY")
 	if accessSecret == "" {
-		log.Println("ACCESS_SECRET_KEY is not set")
-		return "", fmt.Errorf("access secret key not set in environment")
+		return "", fmt.Errorf("access secret key not configured")
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -42,7 +39,7 @@ func GenerateToken(user models.User) (string, error) {
 	return signedToken, nil
 }
 
-func GenerateRefreshToken(user models.User) (string, error) {
+func GenerateRefreshToken(user models.User, refreshSecret string) (string, error) {
 	claims := Claims{
 		UserID:   user.ID,
 		Username: user.Username,
@@ -52,10 +49,8 @@ func GenerateRefreshToken(user models.User) (string, error) {
 		},
 	}
 
-	refreshSecret := os.Getenv("REFRESH_SECRET_KEY")
 	if refreshSecret == "" {
-		log.Println("REFRESH_SECRET_KEY is not set")
-		return "", fmt.Errorf("refresh secret key not set in environment")
+		return "", fmt.Errorf("refresh secret key not configured")
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
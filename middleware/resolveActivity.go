@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/slug"
+	"gorm.io/gorm"
+)
+
+// ResolveActivity lets read routes address an activity by its slug as well as
+// its ID. Handlers always see the numeric ID in the :id parameter. Slugs used
+// before a rename, and the IDs of activities merged into another, are
+// redirected permanently to the activity's current address.
+func ResolveActivity() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value := ctx.Param("id")
+		if value == "" {
+			ctx.Next()
+			return
+		}
+
+		DB := ctx.MustGet("db").(*gorm.DB)
+		place, moved, err := slug.Resolve(DB, value)
+		if err == gorm.ErrRecordNotFound {
+			// The handler answers 404 as it always has.
+			ctx.Next()
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving activity"})
+			ctx.Abort()
+			return
+		}
+
+		id := strconv.FormatUint(uint64(place.ID), 10)
+		if moved {
+			to := place.Slug
+			if slug.IsID(value) || to == "" {
+				to = id
+			}
+			location := strings.Replace(ctx.Request.URL.Path, "/activities/"+value, "/activities/"+to, 1)
+			if ctx.Request.URL.RawQuery != "" {
+				location += "?" + ctx.Request.URL.RawQuery
+			}
+			ctx.Redirect(http.StatusMovedPermanently, location)
+			ctx.Abort()
+			return
+		}
+
+		for i := range ctx.Params {
+			if ctx.Params[i].Key == "id" {
+				ctx.Params[i].Value = id
+			}
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,105 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"gorm.io/gorm"
+)
+
+const usage = "usage: migrate up | down [N] | status | to VERSION"
+
+// Command runs the migrate subcommand:
+//
+//	migrate up            apply every pending migration
+//	migrate down [N]      roll back the latest N migrations (default 1)
+//	migrate status        list migrations and when they were applied
+//	migrate to VERSION    migrate up or down to VERSION; 0 rolls back everything
+func Command(ctx context.Context, db *gorm.DB, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	migrator, err := Default(db)
+	if err != nil {
+		return err
+	}
+
+	var done []Migration
+	verb := "Applied"
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		done, err = migrator.Up(ctx)
+	case args[0] == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		verb = "Rolled back"
+		done, err = migrator.Down(ctx, steps)
+	case args[0] == "to" && len(args) == 2:
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		current, statusErr := latestApplied(ctx, migrator)
+		if statusErr != nil {
+			return statusErr
+		}
+		if version < current {
+			verb = "Rolled back"
+		}
+		done, err = migrator.To(ctx, version)
+	case args[0] == "status" && len(args) == 1:
+		return printStatus(ctx, migrator, out)
+	default:
+		return errors.New(usage)
+	}
+
+	for _, migration := range done {
+		fmt.Fprintf(out, "%s %s\n", verb, label(migration))
+	}
+	if err == nil && len(done) == 0 {
+		fmt.Fprintln(out, "Nothing to migrate")
+	}
+	return err
+}
+
+func latestApplied(ctx context.Context, migrator *Migrator) (int64, error) {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var latest int64
+	for _, status := range statuses {
+		if status.AppliedAt != nil && status.Version > latest {
+			latest = status.Version
+		}
+	}
+	return latest, nil
+}
+
+func printStatus(ctx context.Context, migrator *Migrator, out io.Writer) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if status.Missing {
+			applied += " (not in this binary)"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, applied)
+	}
+	return w.Flush()
+}
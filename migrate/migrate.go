@@ -0,0 +1,406 @@
+// Package migrate applies the versioned SQL migrations embedded in the binary.
+// Each migration is a pair of files named NNNN_name.up.sql and
+// NNNN_name.down.sql; the versions applied to a database are recorded in the
+// schema_migrations table. Migrators on different processes take a lock
+// first, so replicas starting together apply each migration once.
+package migrate
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var embedded embed.FS
+
+// lockKey identifies the postgres advisory lock held while migrating.
+const lockKey int64 = 7_260_112_046
+
+// localLock serialises migrators in one process on databases without
+// advisory locks, such as the sqlite databases used in tests.
+var localLock sync.Mutex
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes one migration. Missing marks a version recorded in the
+// database that this binary does not know, usually applied by a newer release.
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+	Missing   bool       `json:"missing,omitempty"`
+}
+
+type appliedMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Load reads the migrations in the root of fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, file := range files {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(file, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("%s: expected NNNN_name.up.sql or NNNN_name.down.sql", file)
+		}
+		number, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(number, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%s: invalid version %q", file, number)
+		}
+		body, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("%s: version %d is already used by %q", file, version, migration.Name)
+		}
+		if direction == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("migration %d %s has no up migration", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func New(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Default returns a migrator for the migrations embedded in the binary.
+func Default(db *gorm.DB) (*Migrator, error) {
+	dir, err := fs.Sub(embedded, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	return New(db, migrations), nil
+}
+
+// Up applies every pending migration in version order and returns those it
+// applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		pending, err := m.pending(db)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			if err := apply(db, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the latest steps applied migrations and returns those it
+// rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for i := len(applied) - 1; i >= 0 && len(done) < steps; i-- {
+			migration, err := m.rollback(db, applied[i])
+			if err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// To migrates up or down until version is the latest migration applied, and
+// returns the migrations applied or rolled back on the way. Version 0 rolls
+// back everything.
+func (m *Migrator) To(ctx context.Context, version int64) ([]Migration, error) {
+	if version != 0 {
+		if _, ok := m.find(version); !ok {
+			return nil, fmt.Errorf("unknown migration version %d", version)
+		}
+	}
+
+	var done []Migration
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for i := len(applied) - 1; i >= 0 && applied[i].Version > version; i-- {
+			migration, err := m.rollback(db, applied[i])
+			if err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+
+		pending, err := m.pending(db)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			if migration.Version > version {
+				break
+			}
+			if err := apply(db, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Pending returns the migrations not yet applied.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	db := m.db.WithContext(ctx)
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	return m.pending(db)
+}
+
+// Status lists every known migration with when it was applied, followed by
+// any applied versions this binary does not know.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	db := m.db.WithContext(ctx)
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(db)
+	if err != nil {
+		return nil, err
+	}
+	appliedAt := map[int64]time.Time{}
+	for _, row := range applied {
+		appliedAt[row.Version] = row.AppliedAt
+	}
+
+	var statuses []Status
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		if _, ok := m.find(row.Version); !ok {
+			at := row.AppliedAt
+			statuses = append(statuses, Status{Version: row.Version, Name: row.Name, AppliedAt: &at, Missing: true})
+		}
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+func (m *Migrator) applied(db *gorm.DB) ([]appliedMigration, error) {
+	var applied []appliedMigration
+	err := db.Order("version").Find(&applied).Error
+	return applied, err
+}
+
+func (m *Migrator) pending(db *gorm.DB) ([]Migration, error) {
+	applied, err := m.applied(db)
+	if err != nil {
+		return nil, err
+	}
+	done := map[int64]bool{}
+	for _, row := range applied {
+		done[row.Version] = true
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) rollback(db *gorm.DB, row appliedMigration) (Migration, error) {
+	migration, ok := m.find(row.Version)
+	if !ok {
+		return Migration{}, fmt.Errorf("migration %d %s is not known to this binary and cannot be rolled back", row.Version, row.Name)
+	}
+	if strings.TrimSpace(migration.Down) == "" {
+		return Migration{}, fmt.Errorf("migration %d %s has no down migration", migration.Version, migration.Name)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(forDialect(tx, migration.Down)).Error; err != nil {
+			return fmt.Errorf("rolling back %d %s: %w", migration.Version, migration.Name, err)
+		}
+		return tx.Delete(&appliedMigration{}, "version = ?", migration.Version).Error
+	})
+	return migration, err
+}
+
+// apply runs one migration and records it in the same transaction, so a
+// failing migration leaves nothing behind on databases with transactional
+// DDL.
+func apply(db *gorm.DB, migration Migration) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(forDialect(tx, migration.Up)).Error; err != nil {
+			return fmt.Errorf("applying %d %s: %w", migration.Version, migration.Name, err)
+		}
+		return tx.Create(&appliedMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	})
+}
+
+// sqliteTypes maps the postgres types used in migrations to ones sqlite and
+// its driver understand, so an id becomes a rowid and times scan as times.
+var sqliteTypes = strings.NewReplacer("bigserial", "integer", "timestamptz", "datetime")
+
+// forDialect adapts a migration written for postgres to the database.
+func forDialect(db *gorm.DB, sql string) string {
+	if db.Dialector.Name() == "sqlite" {
+		return sqliteTypes.Replace(sql)
+	}
+	return sql
+}
+
+func ensureTable(db *gorm.DB) error {
+	return db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name varchar(255) NOT NULL,
+		applied_at timestamp NOT NULL
+	)`).Error
+}
+
+// locked runs fn while holding the migration lock. On postgres this is a
+// session advisory lock, held on a connection of its own, that every replica
+// waits for.
+func (m *Migrator) locked(ctx context.Context, fn func(db *gorm.DB) error) error {
+	db := m.db.WithContext(ctx)
+	if db.Dialector.Name() != "postgres" {
+		localLock.Lock()
+		defer localLock.Unlock()
+		if err := ensureTable(db); err != nil {
+			return err
+		}
+		return fn(db)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&acquired); err != nil {
+		return err
+	}
+	if !acquired {
+		log.Println("Waiting for another process to finish migrating the database")
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		// The lock goes with the session if the connection is already broken.
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			log.Printf("Error releasing migration lock: %v", err)
+		}
+	}()
+
+	if err := ensureTable(db); err != nil {
+		return err
+	}
+	return fn(db)
+}
+
+// ErrPending is returned by Check when the database is behind the binary.
+var ErrPending = errors.New("database has pending migrations")
+
+// Check returns ErrPending, naming the first pending migration, when the
+// database is missing migrations this binary expects.
+func (m *Migrator) Check(ctx context.Context) error {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d, starting with %s", ErrPending, len(pending), label(pending[0]))
+	}
+	return nil
+}
+
+func label(migration Migration) string {
+	return fmt.Sprintf("%04d_%s", migration.Version, migration.Name)
+}
@@ -0,0 +1,217 @@
+package migrate_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/laurawarren88/go_spa_backend.git/migrate"
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func testMigrations(t *testing.T) []migrate.Migration {
+	migrations, err := migrate.Load(fstest.MapFS{
+		"0001_gyms.up.sql":          {Data: []byte("CREATE TABLE gyms (id integer PRIMARY KEY, name text);")},
+		"0001_gyms.down.sql":        {Data: []byte("DROP TABLE gyms;")},
+		"0002_gym_city.up.sql":      {Data: []byte("ALTER TABLE gyms ADD COLUMN city text;\nUPDATE gyms SET city = 'Manchester';")},
+		"0002_gym_city.down.sql":    {Data: []byte("ALTER TABLE gyms DROP COLUMN city;")},
+		"0003_gym_index.up.sql":     {Data: []byte("CREATE INDEX idx_gyms_name ON gyms (name);")},
+		"0003_gym_index.down.sql":   {Data: []byte("DROP INDEX idx_gyms_name;")},
+		"README.md":                 {Data: []byte("ignored")},
+		"0004_not_yet.up.sql.draft": {Data: []byte("ignored")},
+	})
+	assert.NoError(t, err)
+	return migrations
+}
+
+func versions(migrations []migrate.Migration) []int64 {
+	var versions []int64
+	for _, migration := range migrations {
+		versions = append(versions, migration.Version)
+	}
+	return versions
+}
+
+func TestLoad(t *testing.T) {
+	migrations := testMigrations(t)
+	assert.Equal(t, []int64{1, 2, 3}, versions(migrations))
+	assert.Equal(t, "gym_city", migrations[1].Name)
+
+	_, err := migrate.Load(fstest.MapFS{"0001_gyms.sideways.sql": {Data: []byte("SELECT 1;")}})
+	assert.Error(t, err)
+	_, err = migrate.Load(fstest.MapFS{"gyms.up.sql": {Data: []byte("SELECT 1;")}})
+	assert.Error(t, err)
+	_, err = migrate.Load(fstest.MapFS{"0001_gyms.down.sql": {Data: []byte("DROP TABLE gyms;")}})
+	assert.Error(t, err)
+	_, err = migrate.Load(fstest.MapFS{
+		"0001_gyms.up.sql":   {Data: []byte("SELECT 1;")},
+		"0001_other.up.sql":  {Data: []byte("SELECT 1;")},
+		"0001_gyms.down.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.Error(t, err)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	migrator, err := migrate.Default(db)
+	assert.NoError(t, err)
+	statuses, err := migrator.Status(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "baseline", statuses[0].Name)
+}
+
+func TestMigrator(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	ctx := context.Background()
+	migrator := migrate.New(db, testMigrations(t))
+
+	assert.ErrorIs(t, migrator.Check(ctx), migrate.ErrPending)
+	done, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, versions(done))
+	assert.NoError(t, migrator.Check(ctx))
+	assert.True(t, db.Migrator().HasColumn("gyms", "city"))
+
+	done, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, done)
+
+	done, err = migrator.Down(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 2}, versions(done))
+	assert.False(t, db.Migrator().HasColumn("gyms", "city"))
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 3)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+
+	done, err = migrator.To(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2}, versions(done))
+	done, err = migrator.To(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 1}, versions(done))
+	assert.False(t, db.Migrator().HasTable("gyms"))
+	_, err = migrator.To(ctx, 9)
+	assert.Error(t, err)
+
+	// A migration that fails is not recorded and stops the run.
+	broken := append(testMigrations(t)[:1], migrate.Migration{Version: 2, Name: "broken", Up: "ALTER TABLE nowhere ADD COLUMN city text;"})
+	done, err = migrate.New(db, broken).Up(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, []int64{1}, versions(done))
+	pending, err := migrator.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, versions(pending))
+
+	// Versions applied by a newer binary are reported but cannot be rolled back.
+	assert.NoError(t, db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (9, 'future', CURRENT_TIMESTAMP)").Error)
+	statuses, err = migrator.Status(ctx)
+	assert.NoError(t, err)
+	assert.True(t, statuses[len(statuses)-1].Missing)
+	_, err = migrator.Down(ctx, 1)
+	assert.Error(t, err)
+}
+
+func TestCommand(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	assert.Error(t, migrate.Command(ctx, db, nil, &out))
+	assert.Error(t, migrate.Command(ctx, db, []string{"down", "none"}, &out))
+	assert.Error(t, migrate.Command(ctx, db, []string{"sideways"}, &out))
+
+	out.Reset()
+	assert.NoError(t, migrate.Command(ctx, db, []string{"status"}, &out))
+	assert.Regexp(t, `0001 +baseline +pending`, out.String())
+
+	// The migrations are written for postgres but also run on sqlite.
+	out.Reset()
+	assert.NoError(t, migrate.Command(ctx, db, []string{"up"}, &out))
+	assert.Equal(t, "Applied 0001_baseline\nApplied 0002_activity_features\n", out.String())
+	assert.True(t, db.Migrator().HasTable("place_slugs"))
+
+	out.Reset()
+	assert.NoError(t, migrate.Command(ctx, db, []string{"down", "2"}, &out))
+	assert.Equal(t, "Rolled back 0002_activity_features\nRolled back 0001_baseline\n", out.String())
+	assert.False(t, db.Migrator().HasTable("places"))
+}
+
+// The models as they were when the server still ran
+// AutoMigrate(&models.User{}, &models.Place{}) on start.
+type legacyUser struct {
+	gorm.Model
+	Username string        `gorm:"unique;not null"`
+	Email    string        `gorm:"unique;not null"`
+	Password string        `gorm:"not null"`
+	IsAdmin  bool          `gorm:"default:false"`
+	Places   []legacyPlace `gorm:"foreignKey:UserID"`
+}
+
+func (legacyUser) TableName() string { return "users" }
+
+type legacyPlace struct {
+	gorm.Model
+	Name            string `gorm:"size:255"`
+	Vicinity        string `gorm:"size:255"`
+	City            string `gorm:"size:100"`
+	Postcode        string `gorm:"index;size:20"`
+	Phone           string `gorm:"size:15"`
+	Email           string `gorm:"size:100"`
+	Website         string `gorm:"size:255"`
+	OpeningHours    string `gorm:"type:text"`
+	Type            string `gorm:"type:text"`
+	Description     string `gorm:"size:255"`
+	Latitude        float64
+	Longitude       float64
+	Logo            string `gorm:"size:255"`
+	FacilitiesImage string `gorm:"size:255"`
+	UserID          uint
+}
+
+func (legacyPlace) TableName() string { return "places" }
+
+func TestUpAdoptsAutoMigratedDatabase(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "legacy.db")), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&legacyUser{}, &legacyPlace{}))
+	owner := legacyUser{Username: "owner", Email: "owner@example.com", Password: "x"}
+	assert.NoError(t, db.Create(&owner).Error)
+	assert.NoError(t, db.Create(&legacyPlace{Name: "Harbour Gym", UserID: owner.ID}).Error)
+
+	migrator, err := migrate.Default(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, migrator.Check(context.Background()))
+
+	// Every column the current models use now exists.
+	for _, model := range []interface{}{
+		&models.User{}, &models.Place{}, &models.Class{}, &models.ClassSchedule{},
+		&models.ClassSession{}, &models.Booking{}, &models.Event{}, &models.PlacePrice{},
+		&models.Amenity{}, &models.PlaceImage{}, &models.PlaceRevision{}, &models.PlaceRedirect{},
+		&models.PlaceSlug{},
+	} {
+		stmt := &gorm.Statement{DB: db}
+		assert.NoError(t, stmt.Parse(model))
+		for _, column := range stmt.Schema.DBNames {
+			assert.True(t, db.Migrator().HasColumn(model, column), "%s.%s", stmt.Schema.Table, column)
+		}
+	}
+
+	// Existing rows keep their data and pick up the new defaults.
+	var place models.Place
+	assert.NoError(t, db.First(&place).Error)
+	assert.Equal(t, "Harbour Gym", place.Name)
+	assert.Equal(t, uint(1), place.Version)
+	assert.NoError(t, db.Model(&place).Updates(map[string]interface{}{"slug": "harbour-gym", "osm_id": "node/1"}).Error)
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "places";
+DROP TABLE IF EXISTS "users";
@@ -0,0 +1,44 @@
+-- Baseline: the users and places tables as AutoMigrate created them in the
+-- last release before migrations. Every statement is guarded so that those
+-- databases adopt migrations by running it; later changes start at 0002.
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "username" text NOT NULL,
+    "email" text NOT NULL,
+    "password" text NOT NULL,
+    "is_admin" boolean DEFAULT false,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_users_email" UNIQUE ("email"),
+    CONSTRAINT "uni_users_username" UNIQUE ("username")
+);
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "places" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" varchar(255),
+    "vicinity" varchar(255),
+    "city" varchar(100),
+    "postcode" varchar(20),
+    "phone" varchar(15),
+    "email" varchar(100),
+    "website" varchar(255),
+    "opening_hours" text,
+    "type" text,
+    "description" varchar(255),
+    "latitude" decimal,
+    "longitude" decimal,
+    "logo" varchar(255),
+    "facilities_image" varchar(255),
+    "user_id" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_users_places" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_places_postcode" ON "places" ("postcode");
+CREATE INDEX IF NOT EXISTS "idx_places_deleted_at" ON "places" ("deleted_at");
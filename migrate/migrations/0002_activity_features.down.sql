@@ -0,0 +1,22 @@
+DROP TABLE "place_amenities";
+DROP TABLE "place_slugs";
+DROP TABLE "place_redirects";
+DROP TABLE "place_revisions";
+DROP TABLE "place_images";
+DROP TABLE "amenities";
+DROP TABLE "place_prices";
+DROP TABLE "events";
+DROP TABLE "bookings";
+DROP TABLE "class_sessions";
+DROP TABLE "class_schedules";
+DROP TABLE "classes";
+
+DROP INDEX "idx_places_osm_id";
+DROP INDEX "idx_places_slug";
+ALTER TABLE "places" DROP COLUMN "osm_snapshot";
+ALTER TABLE "places" DROP COLUMN "osm_id";
+ALTER TABLE "places" DROP COLUMN "version";
+ALTER TABLE "places" DROP COLUMN "slug";
+
+DROP INDEX "idx_users_calendar_token";
+ALTER TABLE "users" DROP COLUMN "calendar_token";
@@ -0,0 +1,196 @@
+-- Classes, bookings, events, prices, amenities, images, revisions, slugs,
+-- calendar feeds and OpenStreetMap imports, added to the 0001 schema.
+
+ALTER TABLE "users" ADD COLUMN "calendar_token" varchar(64);
+CREATE UNIQUE INDEX "idx_users_calendar_token" ON "users" ("calendar_token");
+
+ALTER TABLE "places" ADD COLUMN "slug" varchar(100);
+ALTER TABLE "places" ADD COLUMN "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE "places" ADD COLUMN "osm_id" varchar(32);
+ALTER TABLE "places" ADD COLUMN "osm_snapshot" text;
+CREATE UNIQUE INDEX "idx_places_slug" ON "places" ("slug") WHERE slug <> '';
+CREATE UNIQUE INDEX "idx_places_osm_id" ON "places" ("osm_id");
+
+CREATE TABLE "classes" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "place_id" bigint NOT NULL,
+    "title" varchar(255) NOT NULL,
+    "instructor" varchar(100),
+    "category" varchar(100),
+    "capacity" bigint,
+    "duration_minutes" bigint,
+    "price_pence" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_classes_place" FOREIGN KEY ("place_id") REFERENCES "places"("id")
+);
+CREATE INDEX "idx_classes_place_id" ON "classes" ("place_id");
+CREATE INDEX "idx_classes_deleted_at" ON "classes" ("deleted_at");
+
+CREATE TABLE "class_schedules" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "class_id" bigint NOT NULL,
+    "kind" varchar(20) NOT NULL,
+    "weekday" bigint,
+    "start_time" varchar(5),
+    "date" varchar(10),
+    "starts_on" varchar(10),
+    "ends_on" varchar(10),
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_classes_schedules" FOREIGN KEY ("class_id") REFERENCES "classes"("id")
+);
+CREATE INDEX "idx_class_schedules_deleted_at" ON "class_schedules" ("deleted_at");
+CREATE INDEX "idx_class_schedules_class_id" ON "class_schedules" ("class_id");
+
+CREATE TABLE "class_sessions" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "class_id" bigint NOT NULL,
+    "starts_at" timestamptz NOT NULL,
+    "ends_at" timestamptz,
+    "capacity" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_class_sessions_class" FOREIGN KEY ("class_id") REFERENCES "classes"("id")
+);
+CREATE UNIQUE INDEX "idx_class_session_start" ON "class_sessions" ("class_id","starts_at");
+CREATE INDEX "idx_class_sessions_deleted_at" ON "class_sessions" ("deleted_at");
+
+CREATE TABLE "bookings" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "session_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "queued_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_bookings_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_bookings_session" FOREIGN KEY ("session_id") REFERENCES "class_sessions"("id")
+);
+CREATE INDEX "idx_bookings_deleted_at" ON "bookings" ("deleted_at");
+CREATE INDEX "idx_bookings_queued_at" ON "bookings" ("queued_at");
+CREATE INDEX "idx_bookings_status" ON "bookings" ("status");
+CREATE UNIQUE INDEX "idx_booking_session_user" ON "bookings" ("session_id","user_id");
+
+CREATE TABLE "events" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "title" varchar(255) NOT NULL,
+    "description" text,
+    "type" varchar(100),
+    "starts_at" timestamptz NOT NULL,
+    "ends_at" timestamptz NOT NULL,
+    "place_id" bigint,
+    "vicinity" varchar(255),
+    "latitude" decimal,
+    "longitude" decimal,
+    "website" varchar(255),
+    "user_id" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_events_place" FOREIGN KEY ("place_id") REFERENCES "places"("id"),
+    CONSTRAINT "fk_events_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX "idx_events_place_id" ON "events" ("place_id");
+CREATE INDEX "idx_events_ends_at" ON "events" ("ends_at");
+CREATE INDEX "idx_events_starts_at" ON "events" ("starts_at");
+CREATE INDEX "idx_events_deleted_at" ON "events" ("deleted_at");
+
+CREATE TABLE "place_prices" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "place_id" bigint NOT NULL,
+    "kind" varchar(20) NOT NULL,
+    "name" varchar(100),
+    "amount_pence" bigint,
+    "currency" varchar(3) DEFAULT 'GBP',
+    "billing_period" varchar(10),
+    "sessions" bigint,
+    "concession" varchar(50),
+    "notes" varchar(255),
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_places_prices" FOREIGN KEY ("place_id") REFERENCES "places"("id")
+);
+CREATE INDEX "idx_place_prices_place_id" ON "place_prices" ("place_id");
+CREATE INDEX "idx_place_prices_deleted_at" ON "place_prices" ("deleted_at");
+
+CREATE TABLE "amenities" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "slug" varchar(50) NOT NULL,
+    "name" varchar(100) NOT NULL,
+    "category" varchar(50),
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "idx_amenities_slug" ON "amenities" ("slug");
+CREATE INDEX "idx_amenities_deleted_at" ON "amenities" ("deleted_at");
+
+CREATE TABLE "place_images" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "place_id" bigint NOT NULL,
+    "path" varchar(255) NOT NULL,
+    "caption" varchar(255),
+    "alt_text" varchar(255),
+    "position" bigint,
+    "is_cover" boolean DEFAULT false,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_places_images" FOREIGN KEY ("place_id") REFERENCES "places"("id")
+);
+CREATE INDEX "idx_place_images_place_id" ON "place_images" ("place_id");
+CREATE INDEX "idx_place_images_deleted_at" ON "place_images" ("deleted_at");
+
+CREATE TABLE "place_revisions" (
+    "id" bigserial,
+    "place_id" bigint NOT NULL,
+    "version" bigint NOT NULL,
+    "action" varchar(20) NOT NULL,
+    "author_id" bigint,
+    "revert_of" bigint,
+    "snapshot" text NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_place_revisions_author" FOREIGN KEY ("author_id") REFERENCES "users"("id")
+);
+CREATE INDEX "idx_place_revisions_place_id" ON "place_revisions" ("place_id");
+
+CREATE TABLE "place_redirects" (
+    "from_id" bigint,
+    "to_id" bigint NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("from_id")
+);
+CREATE INDEX "idx_place_redirects_to_id" ON "place_redirects" ("to_id");
+
+CREATE TABLE "place_slugs" (
+    "id" bigserial,
+    "place_id" bigint NOT NULL,
+    "slug" varchar(100) NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "idx_place_slugs_slug" ON "place_slugs" ("slug");
+CREATE INDEX "idx_place_slugs_place_id" ON "place_slugs" ("place_id");
+
+CREATE TABLE "place_amenities" (
+    "place_id" bigint,
+    "amenity_id" bigint,
+    PRIMARY KEY ("place_id","amenity_id"),
+    CONSTRAINT "fk_place_amenities_place" FOREIGN KEY ("place_id") REFERENCES "places"("id"),
+    CONSTRAINT "fk_place_amenities_amenity" FOREIGN KEY ("amenity_id") REFERENCES "amenities"("id")
+);
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// Amenity is an entry in the managed vocabulary of facilities and
+// accessibility features that places can be tagged with.
+type Amenity struct {
+	gorm.Model
+	Slug     string `json:"slug" gorm:"size:50;uniqueIndex;not null"`
+	Name     string `json:"name" gorm:"size:100;not null"`
+	Category string `json:"category" gorm:"size:50"`
+}
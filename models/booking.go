@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	BookingConfirmed  = "confirmed"
+	BookingWaitlisted = "waitlisted"
+	BookingCancelled  = "cancelled"
+)
+
+// ClassSession is a concrete occurrence of a class. Sessions are created the
+// first time someone books them, copying the class capacity at that moment.
+type ClassSession struct {
+	gorm.Model
+	ClassID  uint      `json:"class_id" gorm:"uniqueIndex:idx_class_session_start;not null"`
+	Class    Class     `json:"-" gorm:"foreignKey:ClassID"`
+	StartsAt time.Time `json:"starts_at" gorm:"uniqueIndex:idx_class_session_start;not null"`
+	EndsAt   time.Time `json:"ends_at"`
+	Capacity int       `json:"capacity"`
+}
+
+type Booking struct {
+	gorm.Model
+	SessionID uint         `json:"session_id" gorm:"uniqueIndex:idx_booking_session_user;not null"`
+	Session   ClassSession `json:"session" gorm:"foreignKey:SessionID"`
+	UserID    uint         `json:"user_id" gorm:"uniqueIndex:idx_booking_session_user;not null"`
+	User      User         `json:"-" gorm:"foreignKey:UserID"`
+	Status    string       `json:"status" gorm:"size:20;index;not null"`
+	QueuedAt  time.Time    `json:"queued_at" gorm:"index"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	ScheduleWeekly    = "weekly"
+	ScheduleDate      = "date"
+	ScheduleException = "exception"
+)
+
+type Class struct {
+	gorm.Model
+	PlaceID         uint            `json:"place_id" gorm:"index;not null"`
+	Place           Place           `json:"-" gorm:"foreignKey:PlaceID"`
+	Title           string          `json:"title" gorm:"size:255;not null"`
+	Instructor      string          `json:"instructor" gorm:"size:100"`
+	Category        string          `json:"category" gorm:"size:100"`
+	Capacity        int             `json:"capacity"`
+	DurationMinutes int             `json:"duration_minutes"`
+	PricePence      int             `json:"price_pence"`
+	Schedules       []ClassSchedule `json:"schedules" gorm:"foreignKey:ClassID"`
+}
+
+// ClassSchedule is a single recurrence rule for a class. Weekly rules repeat on
+// Weekday between the optional StartsOn and EndsOn dates, date rules add a
+// one-off session on Date, and exception rules cancel sessions on Date (all of
+// them, or only the one at StartTime when it is set). Dates are YYYY-MM-DD and
+// times are HH:MM, both in the venue's local time.
+type ClassSchedule struct {
+	gorm.Model
+	ClassID   uint   `json:"class_id" gorm:"index;not null"`
+	Kind      string `json:"kind" gorm:"size:20;not null"`
+	Weekday   int    `json:"weekday"`
+	StartTime string `json:"start_time" gorm:"size:5"`
+	Date      string `json:"date" gorm:"size:10"`
+	StartsOn  string `json:"starts_on" gorm:"size:10"`
+	EndsOn    string `json:"ends_on" gorm:"size:10"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Event is a one-off activity such as a charity run or open day. It either
+// takes place at a Place, whose coordinates are copied onto the event, or at
+// standalone coordinates.
+type Event struct {
+	gorm.Model
+	Title       string    `json:"title" gorm:"size:255;not null"`
+	Description string    `json:"description" gorm:"type:text"`
+	Type        string    `json:"type" gorm:"size:100"`
+	StartsAt    time.Time `json:"starts_at" gorm:"index;not null"`
+	EndsAt      time.Time `json:"ends_at" gorm:"index;not null"`
+	PlaceID     *uint     `json:"place_id" gorm:"index"`
+	Place       *Place    `json:"place,omitempty" gorm:"foreignKey:PlaceID"`
+	Vicinity    string    `json:"vicinity" gorm:"size:255"`
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	Website     string    `json:"website" gorm:"size:255"`
+	UserID      uint      `json:"user_id"`
+	User        User      `json:"-" gorm:"foreignKey:UserID"`
+}
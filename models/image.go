@@ -0,0 +1,17 @@
+package models
+
+import "gorm.io/gorm"
+
+type PlaceImage struct {
+	gorm.Model
+	PlaceID  uint   `json:"place_id" gorm:"index;not null"`
+	Path     string `json:"path" gorm:"size:255;not null"`
+	Caption  string `json:"caption" gorm:"size:255"`
+	AltText  string `json:"alt_text" gorm:"size:255"`
+	Position int    `json:"position"`
+	IsCover  bool   `json:"is_cover" gorm:"default:false"`
+	// URL and Thumbnails are the public addresses of the image and its
+	// thumbnails. They are derived from Path by the media store and not stored.
+	URL        string            `json:"url" gorm:"-"`
+	Thumbnails map[string]string `json:"thumbnails,omitempty" gorm:"-"`
+}
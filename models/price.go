@@ -0,0 +1,32 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	PriceMembership = "membership"
+	PriceDayPass    = "day_pass"
+	PriceClassPack  = "class_pack"
+	PricePayAsYouGo = "pay_as_you_go"
+)
+
+const (
+	BillingOnce  = "once"
+	BillingWeek  = "week"
+	BillingMonth = "month"
+	BillingYear  = "year"
+)
+
+// PlacePrice is one pricing option offered by a place. Amounts are stored in
+// minor units of Currency, e.g. pence for GBP.
+type PlacePrice struct {
+	gorm.Model
+	PlaceID       uint   `json:"place_id" gorm:"index;not null"`
+	Kind          string `json:"kind" gorm:"size:20;not null"`
+	Name          string `json:"name" gorm:"size:100"`
+	AmountPence   int    `json:"amount_pence"`
+	Currency      string `json:"currency" gorm:"size:3;default:GBP"`
+	BillingPeriod string `json:"billing_period" gorm:"size:10"`
+	Sessions      int    `json:"sessions"`
+	Concession    string `json:"concession" gorm:"size:50"`
+	Notes         string `json:"notes" gorm:"size:255"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PlaceRedirect points the ID of a place that was merged into another at the
+// place that replaced it, so old links keep working.
+type PlaceRedirect struct {
+	FromID    uint      `json:"from_id" gorm:"primaryKey;autoIncrement:false"`
+	ToID      uint      `json:"to_id" gorm:"index;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PlaceSlug is a slug a place used before it was renamed or merged into
+// another place. Requests for it redirect to the place's current slug.
+type PlaceSlug struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	PlaceID   uint      `json:"place_id" gorm:"index;not null"`
+	Slug      string    `json:"slug" gorm:"size:100;uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
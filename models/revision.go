@@ -0,0 +1,117 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	RevisionCreate   = "create"
+	RevisionUpdate   = "update"
+	RevisionRevert   = "revert"
+	RevisionBaseline = "baseline"
+	RevisionImport   = "import"
+	RevisionMerge    = "merge"
+	RevisionDelete   = "delete"
+	RevisionRestore  = "restore"
+)
+
+// PlaceRevision records the state of a place after each change. Baseline
+// revisions capture places that were last saved before history was kept.
+type PlaceRevision struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	PlaceID   uint      `json:"place_id" gorm:"index;not null"`
+	Version   uint      `json:"version" gorm:"not null"`
+	Action    string    `json:"action" gorm:"size:20;not null"`
+	AuthorID  *uint     `json:"author_id"`
+	Author    *User     `json:"-" gorm:"foreignKey:AuthorID"`
+	RevertOf  *uint     `json:"revert_of,omitempty"`
+	Snapshot  string    `json:"-" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PlaceSnapshot holds the editable content of a place as stored in a
+// revision. Ownership is not part of it.
+type PlaceSnapshot struct {
+	Name            string   `json:"name"`
+	Vicinity        string   `json:"vicinity"`
+	City            string   `json:"city"`
+	Postcode        string   `json:"postcode"`
+	Phone           string   `json:"phone"`
+	Email           string   `json:"email"`
+	Website         string   `json:"website"`
+	OpeningHours    string   `json:"opening_hours"`
+	Type            string   `json:"type"`
+	Description     string   `json:"description"`
+	Latitude        float64  `json:"latitude"`
+	Longitude       float64  `json:"longitude"`
+	Logo            string   `json:"logo"`
+	FacilitiesImage string   `json:"facilities_image"`
+	Amenities       []string `json:"amenities"`
+}
+
+// SnapshotOf captures place with the given amenities.
+func SnapshotOf(place Place, amenities []Amenity) PlaceSnapshot {
+	slugs := []string{}
+	for _, amenity := range amenities {
+		slugs = append(slugs, amenity.Slug)
+	}
+	return PlaceSnapshot{
+		Name:            place.Name,
+		Vicinity:        place.Vicinity,
+		City:            place.City,
+		Postcode:        place.Postcode,
+		Phone:           place.Phone,
+		Email:           place.Email,
+		Website:         place.Website,
+		OpeningHours:    place.OpeningHours,
+		Type:            place.Type,
+		Description:     place.Description,
+		Latitude:        place.Latitude,
+		Longitude:       place.Longitude,
+		Logo:            place.Logo,
+		FacilitiesImage: place.FacilitiesImage,
+		Amenities:       slugs,
+	}
+}
+
+// Apply copies the snapshot onto place. Amenities and the owner are not
+// touched.
+func (s PlaceSnapshot) Apply(place *Place) {
+	place.Name = s.Name
+	place.Vicinity = s.Vicinity
+	place.City = s.City
+	place.Postcode = s.Postcode
+	place.Phone = s.Phone
+	place.Email = s.Email
+	place.Website = s.Website
+	place.OpeningHours = s.OpeningHours
+	place.Type = s.Type
+	place.Description = s.Description
+	place.Latitude = s.Latitude
+	place.Longitude = s.Longitude
+	place.Logo = s.Logo
+	place.FacilitiesImage = s.FacilitiesImage
+}
+
+// NewPlaceRevision snapshots place, which must already be saved, with the
+// given amenities.
+func NewPlaceRevision(place Place, amenities []Amenity, action string, authorID *uint) (PlaceRevision, error) {
+	snapshot, err := json.Marshal(SnapshotOf(place, amenities))
+	if err != nil {
+		return PlaceRevision{}, err
+	}
+	return PlaceRevision{
+		PlaceID:  place.ID,
+		Version:  place.Version,
+		Action:   action,
+		AuthorID: authorID,
+		Snapshot: string(snapshot),
+	}, nil
+}
+
+func (r PlaceRevision) Decode() (PlaceSnapshot, error) {
+	var snapshot PlaceSnapshot
+	err := json.Unmarshal([]byte(r.Snapshot), &snapshot)
+	return snapshot, err
+}
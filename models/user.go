@@ -11,4 +11,7 @@ type User struct {
 	Password string  `json:"password" gorm:"not null"`
 	IsAdmin  bool    `json:"is_admin" gorm:"default:false"`
 	Places   []Place `gorm:"foreignKey:UserID"`
+	// CalendarToken authenticates the private bookings feed. It is NULL until
+	// the user enables the feed so the unique index ignores unset rows.
+	CalendarToken *string `json:"-" gorm:"size:64;uniqueIndex"`
 }
@@ -0,0 +1,113 @@
+// Package osm reads OpenStreetMap extracts, either Overpass JSON or the PBF
+// format produced by planet dumps and Geofabrik, and returns the elements
+// whose tags a caller selects. Ways are reduced to the centroid of their
+// nodes; relations are not supported.
+package osm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Element is a tagged node or way.
+type Element struct {
+	Type string
+	ID   int64
+	// Lat and Lon are the node position, or the centroid of a way. They are
+	// only meaningful when HasLocation is set.
+	Lat, Lon    float64
+	HasLocation bool
+	Tags        map[string]string
+}
+
+// Ref returns the element reference used by the OSM website, e.g. "node/42".
+func (e Element) Ref() string {
+	return e.Type + "/" + strconv.FormatInt(e.ID, 10)
+}
+
+// Filter selects the elements to keep by their tags.
+type Filter func(tags map[string]string) bool
+
+type overpassPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type overpassElement struct {
+	Type     string            `json:"type"`
+	ID       int64             `json:"id"`
+	Lat      *float64          `json:"lat"`
+	Lon      *float64          `json:"lon"`
+	Center   *overpassPoint    `json:"center"`
+	Geometry []overpassPoint   `json:"geometry"`
+	Tags     map[string]string `json:"tags"`
+}
+
+// ReadOverpass reads the JSON returned by the Overpass API. Ways need to have
+// been requested with "out center" or "out geom" to have a location. Elements
+// are decoded one at a time, so large extracts are not held in memory.
+func ReadOverpass(r io.Reader, keep Filter) ([]Element, error) {
+	decoder := json.NewDecoder(r)
+	if err := seekElements(decoder); err != nil {
+		return nil, err
+	}
+
+	var elements []Element
+	for decoder.More() {
+		var raw overpassElement
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid Overpass JSON: %w", err)
+		}
+		if raw.Type != "node" && raw.Type != "way" {
+			continue
+		}
+		if len(raw.Tags) == 0 || !keep(raw.Tags) {
+			continue
+		}
+
+		element := Element{Type: raw.Type, ID: raw.ID, Tags: raw.Tags}
+		switch {
+		case raw.Lat != nil && raw.Lon != nil:
+			element.Lat, element.Lon, element.HasLocation = *raw.Lat, *raw.Lon, true
+		case raw.Center != nil:
+			element.Lat, element.Lon, element.HasLocation = raw.Center.Lat, raw.Center.Lon, true
+		case len(raw.Geometry) > 0:
+			for _, point := range raw.Geometry {
+				element.Lat += point.Lat
+				element.Lon += point.Lon
+			}
+			element.Lat /= float64(len(raw.Geometry))
+			element.Lon /= float64(len(raw.Geometry))
+			element.HasLocation = true
+		}
+		elements = append(elements, element)
+	}
+	return elements, nil
+}
+
+// seekElements advances decoder to the first value of the top level
+// "elements" array.
+func seekElements(decoder *json.Decoder) error {
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return fmt.Errorf("invalid Overpass JSON: expected an object")
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return fmt.Errorf("invalid Overpass JSON: %w", err)
+		}
+		if token == "elements" {
+			if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+				return fmt.Errorf("invalid Overpass JSON: elements must be an array")
+			}
+			return nil
+		}
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return fmt.Errorf("invalid Overpass JSON: %w", err)
+		}
+	}
+	return fmt.Errorf("invalid Overpass JSON: no elements")
+}
@@ -0,0 +1,200 @@
+package osm_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/laurawarren88/go_spa_backend.git/osm"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func isGym(tags map[string]string) bool {
+	return tags["leisure"] == "fitness_centre"
+}
+
+func TestReadOverpass(t *testing.T) {
+	elements, err := osm.ReadOverpass(strings.NewReader(`{
+		"version": 0.6,
+		"osm3s": {"copyright": "OpenStreetMap contributors"},
+		"elements": [
+			{"type": "node", "id": 1, "lat": 53.48, "lon": -2.24, "tags": {"leisure": "fitness_centre", "name": "Node Gym"}},
+			{"type": "node", "id": 2, "lat": 53.49, "lon": -2.25, "tags": {"amenity": "cafe"}},
+			{"type": "way", "id": 3, "center": {"lat": 53.5, "lon": -2.3}, "tags": {"leisure": "fitness_centre"}},
+			{"type": "way", "id": 4, "geometry": [{"lat": 1, "lon": 2}, {"lat": 3, "lon": 4}], "tags": {"leisure": "fitness_centre"}},
+			{"type": "way", "id": 5, "nodes": [1, 2], "tags": {"leisure": "fitness_centre"}},
+			{"type": "relation", "id": 6, "tags": {"leisure": "fitness_centre"}}
+		]
+	}`), isGym)
+	assert.NoError(t, err)
+	assert.Len(t, elements, 4)
+	assert.Equal(t, "node/1", elements[0].Ref())
+	assert.Equal(t, 53.48, elements[0].Lat)
+	assert.Equal(t, "Node Gym", elements[0].Tags["name"])
+	assert.Equal(t, -2.3, elements[1].Lon)
+	assert.Equal(t, 2.0, elements[2].Lat)
+	assert.True(t, elements[2].HasLocation)
+	assert.Equal(t, "way/5", elements[3].Ref())
+	assert.False(t, elements[3].HasLocation)
+
+	_, err = osm.ReadOverpass(strings.NewReader(`[]`), isGym)
+	assert.Error(t, err)
+}
+
+// pbfWriter builds a small PBF file by hand.
+type pbfWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *pbfWriter) blob(blobType string, data []byte, compress bool) {
+	var blob []byte
+	if compress {
+		var zipped bytes.Buffer
+		zw := zlib.NewWriter(&zipped)
+		zw.Write(data)
+		zw.Close()
+		blob = protowire.AppendTag(blob, 2, protowire.VarintType)
+		blob = protowire.AppendVarint(blob, uint64(len(data)))
+		blob = protowire.AppendTag(blob, 3, protowire.BytesType)
+		blob = protowire.AppendBytes(blob, zipped.Bytes())
+	} else {
+		blob = protowire.AppendTag(blob, 1, protowire.BytesType)
+		blob = protowire.AppendBytes(blob, data)
+	}
+
+	var header []byte
+	header = protowire.AppendTag(header, 1, protowire.BytesType)
+	header = protowire.AppendString(header, blobType)
+	header = protowire.AppendTag(header, 3, protowire.VarintType)
+	header = protowire.AppendVarint(header, uint64(len(blob)))
+
+	binary.Write(&w.buf, binary.BigEndian, uint32(len(header)))
+	w.buf.Write(header)
+	w.buf.Write(blob)
+}
+
+func packed(values ...int64) []byte {
+	var b []byte
+	for _, v := range values {
+		b = protowire.AppendVarint(b, uint64(v))
+	}
+	return b
+}
+
+func packedZigZag(values ...int64) []byte {
+	var b []byte
+	for _, v := range values {
+		b = protowire.AppendVarint(b, protowire.EncodeZigZag(v))
+	}
+	return b
+}
+
+func bytesField(b []byte, num protowire.Number, value []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+func varintField(b []byte, num protowire.Number, value uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+func degrees(value float64) int64 {
+	return int64(math.Round(value * 1e7))
+}
+
+func TestReadPBF(t *testing.T) {
+	var w pbfWriter
+
+	var header []byte
+	header = bytesField(header, 4, []byte("OsmSchema-V0.6"))
+	header = bytesField(header, 4, []byte("DenseNodes"))
+	w.blob("OSMHeader", header, false)
+
+	// String table: 0 is reserved for the empty string.
+	var stringTable []byte
+	for _, s := range []string{"", "leisure", "fitness_centre", "name", "Dense Gym", "Way Gym", "highway", "bus_stop", "Plain Gym"} {
+		stringTable = bytesField(stringTable, 1, []byte(s))
+	}
+
+	// Three dense nodes with delta coding: a gym, then two corners of a
+	// building with no tags. The 0s separate each node's tags.
+	var dense []byte
+	dense = bytesField(dense, 1, packedZigZag(10, 1, 1))
+	dense = bytesField(dense, 8, packedZigZag(degrees(53.48), degrees(0.01), degrees(0.01)))
+	dense = bytesField(dense, 9, packedZigZag(degrees(-2.24), 0, degrees(-0.02)))
+	dense = bytesField(dense, 10, packed(1, 2, 3, 4, 0, 0, 0))
+
+	// A plain node at a bus stop, which the filter drops.
+	var node []byte
+	node = varintField(node, 1, protowire.EncodeZigZag(20))
+	node = bytesField(node, 2, packed(6))
+	node = bytesField(node, 3, packed(7))
+	node = varintField(node, 8, protowire.EncodeZigZag(degrees(51.5)))
+	node = varintField(node, 9, protowire.EncodeZigZag(degrees(-0.1)))
+
+	// A closed way around nodes 11 and 12.
+	var way []byte
+	way = varintField(way, 1, 30)
+	way = bytesField(way, 2, packed(1, 3))
+	way = bytesField(way, 3, packed(2, 5))
+	way = bytesField(way, 8, packedZigZag(11, 1, -1))
+
+	var group []byte
+	group = bytesField(group, 2, dense)
+	group = bytesField(group, 1, node)
+	var wayGroup []byte
+	wayGroup = bytesField(wayGroup, 3, way)
+
+	// granularity 100 means coordinates are in units of 1e-7 degrees.
+	var block []byte
+	block = bytesField(block, 1, stringTable)
+	block = bytesField(block, 2, group)
+	block = bytesField(block, 2, wayGroup)
+	w.blob("OSMData", block, true)
+
+	// A plain node with its own tags in a second block.
+	var plain []byte
+	plain = varintField(plain, 1, protowire.EncodeZigZag(40))
+	plain = bytesField(plain, 2, packed(1, 3))
+	plain = bytesField(plain, 3, packed(2, 8))
+	plain = varintField(plain, 8, protowire.EncodeZigZag(degrees(53.0)))
+	plain = varintField(plain, 9, protowire.EncodeZigZag(degrees(-2.0)))
+	var plainBlock []byte
+	plainBlock = bytesField(plainBlock, 1, stringTable)
+	plainBlock = bytesField(plainBlock, 2, bytesField(nil, 1, plain))
+	w.blob("OSMData", plainBlock, false)
+
+	elements, err := osm.ReadPBF(bytes.NewReader(w.buf.Bytes()), isGym)
+	assert.NoError(t, err)
+	assert.Len(t, elements, 3)
+
+	assert.Equal(t, "node/10", elements[0].Ref())
+	assert.Equal(t, "Dense Gym", elements[0].Tags["name"])
+	assert.InDelta(t, 53.48, elements[0].Lat, 1e-9)
+	assert.InDelta(t, -2.24, elements[0].Lon, 1e-9)
+
+	assert.Equal(t, "way/30", elements[1].Ref())
+	assert.Equal(t, "Way Gym", elements[1].Tags["name"])
+	assert.True(t, elements[1].HasLocation)
+	assert.InDelta(t, 53.495, elements[1].Lat, 1e-9)
+	assert.InDelta(t, -2.25, elements[1].Lon, 1e-9)
+
+	assert.Equal(t, "node/40", elements[2].Ref())
+	assert.Equal(t, "Plain Gym", elements[2].Tags["name"])
+	assert.InDelta(t, 53.0, elements[2].Lat, 1e-9)
+}
+
+func TestReadPBFRejectsUnsupportedFeatures(t *testing.T) {
+	var w pbfWriter
+	w.blob("OSMHeader", bytesField(nil, 4, []byte("HistoricalInformation")), false)
+	_, err := osm.ReadPBF(bytes.NewReader(w.buf.Bytes()), isGym)
+	assert.ErrorContains(t, err, "HistoricalInformation")
+
+	_, err = osm.ReadPBF(bytes.NewReader([]byte{0, 0, 0, 5, 1}), isGym)
+	assert.Error(t, err)
+}
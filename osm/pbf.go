@@ -0,0 +1,407 @@
+package osm
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Limits from the OSM PBF specification.
+const (
+	maxBlobHeaderSize = 64 << 10
+	maxBlobSize       = 32 << 20
+)
+
+// supportedFeatures are the required_features of an OSMHeader this reader
+// understands.
+var supportedFeatures = map[string]bool{"OsmSchema-V0.6": true, "DenseNodes": true}
+
+// ReadPBF reads an OSM PBF file. Matching nodes are returned with their
+// position; matching ways need a second pass over r to find the positions of
+// their nodes, which is why r must be seekable.
+func ReadPBF(r io.ReadSeeker, keep Filter) ([]Element, error) {
+	var elements []Element
+	wayNodes := map[int][]int64{}
+	needed := map[int64]bool{}
+
+	err := readBlocks(r, func(block *primitiveBlock) {
+		block.nodes(func(id int64, lat, lon float64, tags map[string]string) {
+			if len(tags) > 0 && keep(tags) {
+				elements = append(elements, Element{Type: "node", ID: id, Lat: lat, Lon: lon, HasLocation: true, Tags: tags})
+			}
+		})
+		block.ways(func(id int64, refs []int64, tags map[string]string) {
+			if len(tags) > 0 && keep(tags) {
+				wayNodes[len(elements)] = refs
+				for _, ref := range refs {
+					needed[ref] = true
+				}
+				elements = append(elements, Element{Type: "way", ID: id, Tags: tags})
+			}
+		})
+	})
+	if err != nil || len(wayNodes) == 0 {
+		return elements, err
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	positions := make(map[int64][2]float64, len(needed))
+	err = readBlocks(r, func(block *primitiveBlock) {
+		block.nodes(func(id int64, lat, lon float64, _ map[string]string) {
+			if needed[id] {
+				positions[id] = [2]float64{lat, lon}
+			}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for index, refs := range wayNodes {
+		var lat, lon float64
+		count := 0
+		for i, ref := range refs {
+			// Closed ways repeat their first node at the end.
+			if i == len(refs)-1 && len(refs) > 1 && ref == refs[0] {
+				continue
+			}
+			if position, ok := positions[ref]; ok {
+				lat += position[0]
+				lon += position[1]
+				count++
+			}
+		}
+		if count > 0 {
+			element := &elements[index]
+			element.Lat, element.Lon, element.HasLocation = lat/float64(count), lon/float64(count), true
+		}
+	}
+	return elements, nil
+}
+
+// readBlocks calls fn for every data block in the file.
+func readBlocks(r io.Reader, fn func(*primitiveBlock)) error {
+	var size [4]byte
+	for {
+		if _, err := io.ReadFull(r, size[:]); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("invalid PBF: %w", err)
+		}
+		headerSize := binary.BigEndian.Uint32(size[:])
+		if headerSize > maxBlobHeaderSize {
+			return errors.New("invalid PBF: blob header too large")
+		}
+		header := make([]byte, headerSize)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return fmt.Errorf("invalid PBF: %w", err)
+		}
+
+		var blobType string
+		var blobSize uint64
+		err := fields(header, func(f field) error {
+			switch f.num {
+			case 1:
+				blobType = string(f.bytes)
+			case 3:
+				blobSize = f.varint
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if blobSize > maxBlobSize {
+			return errors.New("invalid PBF: blob too large")
+		}
+		blob := make([]byte, blobSize)
+		if _, err := io.ReadFull(r, blob); err != nil {
+			return fmt.Errorf("invalid PBF: %w", err)
+		}
+		data, err := blobData(blob)
+		if err != nil {
+			return err
+		}
+
+		switch blobType {
+		case "OSMHeader":
+			if err := checkHeader(data); err != nil {
+				return err
+			}
+		case "OSMData":
+			block, err := parseBlock(data)
+			if err != nil {
+				return err
+			}
+			fn(block)
+		}
+	}
+}
+
+func blobData(blob []byte) ([]byte, error) {
+	var raw, compressed []byte
+	var unsupported bool
+	err := fields(blob, func(f field) error {
+		switch f.num {
+		case 1:
+			raw = f.bytes
+		case 3:
+			compressed = f.bytes
+		case 4, 5, 6, 7:
+			unsupported = true
+		}
+		return nil
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case raw != nil:
+		return raw, nil
+	case compressed != nil:
+		reader, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fmt.Errorf("invalid PBF: %w", err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(io.LimitReader(reader, maxBlobSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid PBF: %w", err)
+		}
+		if len(data) > maxBlobSize {
+			return nil, errors.New("invalid PBF: blob too large")
+		}
+		return data, nil
+	case unsupported:
+		return nil, errors.New("PBF blobs must be uncompressed or zlib compressed")
+	}
+	return nil, nil
+}
+
+func checkHeader(data []byte) error {
+	return fields(data, func(f field) error {
+		if f.num == 4 && !supportedFeatures[string(f.bytes)] {
+			return fmt.Errorf("PBF requires unsupported feature %q", f.bytes)
+		}
+		return nil
+	})
+}
+
+type primitiveBlock struct {
+	strings     []string
+	groups      [][]byte
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+func parseBlock(data []byte) (*primitiveBlock, error) {
+	block := &primitiveBlock{granularity: 100}
+	err := fields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			return fields(f.bytes, func(s field) error {
+				if s.num == 1 {
+					block.strings = append(block.strings, string(s.bytes))
+				}
+				return nil
+			})
+		case 2:
+			block.groups = append(block.groups, f.bytes)
+		case 17:
+			block.granularity = int64(f.varint)
+		case 19:
+			block.latOffset = int64(f.varint)
+		case 20:
+			block.lonOffset = int64(f.varint)
+		}
+		return nil
+	})
+	return block, err
+}
+
+func (b *primitiveBlock) coordinate(offset, value int64) float64 {
+	return 1e-9 * float64(offset+b.granularity*value)
+}
+
+func (b *primitiveBlock) tags(keys, values []uint64) map[string]string {
+	if len(keys) == 0 {
+		return nil
+	}
+	tags := make(map[string]string, len(keys))
+	for i, key := range keys {
+		if i < len(values) && int(key) < len(b.strings) && int(values[i]) < len(b.strings) {
+			tags[b.strings[key]] = b.strings[values[i]]
+		}
+	}
+	return tags
+}
+
+// nodes calls fn for every node in the block, dense or not. Malformed
+// groups are skipped.
+func (b *primitiveBlock) nodes(fn func(id int64, lat, lon float64, tags map[string]string)) {
+	for _, group := range b.groups {
+		fields(group, func(f field) error {
+			switch f.num {
+			case 1:
+				var id, lat, lon int64
+				var keys, values []uint64
+				fields(f.bytes, func(n field) error {
+					switch n.num {
+					case 1:
+						id = protowire.DecodeZigZag(n.varint)
+					case 2:
+						keys = append(keys, n.ints()...)
+					case 3:
+						values = append(values, n.ints()...)
+					case 8:
+						lat = protowire.DecodeZigZag(n.varint)
+					case 9:
+						lon = protowire.DecodeZigZag(n.varint)
+					}
+					return nil
+				})
+				fn(id, b.coordinate(b.latOffset, lat), b.coordinate(b.lonOffset, lon), b.tags(keys, values))
+			case 2:
+				b.denseNodes(f.bytes, fn)
+			}
+			return nil
+		})
+	}
+}
+
+func (b *primitiveBlock) denseNodes(data []byte, fn func(id int64, lat, lon float64, tags map[string]string)) {
+	var ids, lats, lons, keysVals []uint64
+	fields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			ids = append(ids, f.ints()...)
+		case 8:
+			lats = append(lats, f.ints()...)
+		case 9:
+			lons = append(lons, f.ints()...)
+		case 10:
+			keysVals = append(keysVals, f.ints()...)
+		}
+		return nil
+	})
+	if len(lats) != len(ids) || len(lons) != len(ids) {
+		return
+	}
+
+	var id, lat, lon int64
+	for i := range ids {
+		id += protowire.DecodeZigZag(ids[i])
+		lat += protowire.DecodeZigZag(lats[i])
+		lon += protowire.DecodeZigZag(lons[i])
+
+		// keys_vals holds key and value string indexes for each node in
+		// turn, with a 0 after each node's tags.
+		var keys, values []uint64
+		for len(keysVals) > 0 && keysVals[0] != 0 {
+			if len(keysVals) < 2 {
+				keysVals = nil
+				break
+			}
+			keys, values = append(keys, keysVals[0]), append(values, keysVals[1])
+			keysVals = keysVals[2:]
+		}
+		if len(keysVals) > 0 {
+			keysVals = keysVals[1:]
+		}
+		fn(id, b.coordinate(b.latOffset, lat), b.coordinate(b.lonOffset, lon), b.tags(keys, values))
+	}
+}
+
+func (b *primitiveBlock) ways(fn func(id int64, refs []int64, tags map[string]string)) {
+	for _, group := range b.groups {
+		fields(group, func(f field) error {
+			if f.num != 3 {
+				return nil
+			}
+			var id int64
+			var keys, values, deltas []uint64
+			fields(f.bytes, func(w field) error {
+				switch w.num {
+				case 1:
+					id = int64(w.varint)
+				case 2:
+					keys = append(keys, w.ints()...)
+				case 3:
+					values = append(values, w.ints()...)
+				case 8:
+					deltas = append(deltas, w.ints()...)
+				}
+				return nil
+			})
+			refs := make([]int64, len(deltas))
+			var ref int64
+			for i, delta := range deltas {
+				ref += protowire.DecodeZigZag(delta)
+				refs[i] = ref
+			}
+			fn(id, refs, b.tags(keys, values))
+			return nil
+		})
+	}
+}
+
+// field is one decoded protobuf field. Only varint and length-delimited
+// values are kept; the PBF format uses no others that this reader needs.
+type field struct {
+	num    protowire.Number
+	typ    protowire.Type
+	varint uint64
+	bytes  []byte
+}
+
+func fields(b []byte, fn func(field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("invalid PBF: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+		f := field{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("invalid PBF: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ints returns the values of a repeated integer field, which encoders may
+// write packed or one field at a time.
+func (f field) ints() []uint64 {
+	if f.typ == protowire.VarintType {
+		return []uint64{f.varint}
+	}
+	var values []uint64
+	for b := f.bytes; len(b) > 0; {
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			break
+		}
+		values = append(values, v)
+		b = b[n:]
+	}
+	return values
+}
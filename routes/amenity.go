@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/middleware"
+)
+
+func RegisterAmenityRoutes(router *gin.Engine, ac *controllers.AmenityController, auth gin.HandlerFunc) {
+	router.GET("/api/amenities", ac.GetAmenities)
+
+	adminRoutes := router.Group("/api/amenities")
+	adminRoutes.Use(auth, middleware.RequireAdmin())
+	{
+		adminRoutes.POST("", ac.CreateAmenity)
+		adminRoutes.PUT("/:id", ac.UpdateAmenity)
+		adminRoutes.DELETE("/:id", ac.DeleteAmenity)
+	}
+}
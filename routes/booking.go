@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+)
+
+func RegisterBookingRoutes(router *gin.Engine, bc *controllers.BookingController, auth gin.HandlerFunc) {
+	protected := router.Group("/api")
+	protected.Use(auth)
+	{
+		protected.POST("/classes/:classId/bookings", bc.CreateBooking)
+		protected.DELETE("/bookings/:id", bc.CancelBooking)
+		protected.GET("/users/me/bookings", bc.GetMyBookings)
+	}
+}
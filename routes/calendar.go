@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/middleware"
+)
+
+func RegisterCalendarRoutes(router *gin.Engine, cc *controllers.CalendarController, auth gin.HandlerFunc) {
+	router.GET("/api/activities/:id/timetable.ics", middleware.ResolveActivity(), cc.GetPlaceCalendar)
+	router.GET("/api/calendar/:token/bookings.ics", cc.GetUserCalendar)
+
+	protected := router.Group("/api/users/me")
+	protected.Use(auth)
+	{
+		protected.POST("/calendar-token", cc.CreateCalendarToken)
+		protected.DELETE("/calendar-token", cc.DeleteCalendarToken)
+	}
+}
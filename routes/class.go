@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/middleware"
+)
+
+func RegisterClassRoutes(router *gin.Engine, cc *controllers.ClassController, auth gin.HandlerFunc) {
+	classRoutes := router.Group("/api/activities")
+	classRoutes.Use(middleware.ResolveActivity())
+	{
+		classRoutes.GET("/:id/classes", cc.GetClasses)
+		classRoutes.GET("/:id/timetable", cc.GetTimetable)
+	}
+
+	ownerRoutes := router.Group("/api/activities")
+	ownerRoutes.Use(auth, middleware.ActivityOwner())
+	{
+		ownerRoutes.POST("/:id/classes", cc.CreateClass)
+		ownerRoutes.PUT("/:id/classes/:classId", cc.UpdateClass)
+		ownerRoutes.DELETE("/:id/classes/:classId", cc.DeleteClass)
+	}
+}
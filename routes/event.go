@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/middleware"
+)
+
+func RegisterEventRoutes(router *gin.Engine, ec *controllers.EventController, auth gin.HandlerFunc) {
+	eventRoutes := router.Group("/api/events")
+	{
+		eventRoutes.GET("", ec.GetEvents)
+		eventRoutes.GET("/:id", ec.GetEventById)
+	}
+
+	protected := router.Group("/api/events")
+	protected.Use(auth)
+	{
+		protected.POST("", ec.CreateEvent)
+	}
+
+	ownerRoutes := router.Group("/api/events")
+	ownerRoutes.Use(auth, middleware.EventOwner())
+	{
+		ownerRoutes.PUT("/:id", ec.UpdateEvent)
+		ownerRoutes.DELETE("/:id", ec.DeleteEvent)
+	}
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/middleware"
+)
+
+func RegisterImportRoutes(router *gin.Engine, ic *controllers.ImportController, auth gin.HandlerFunc) {
+	adminRoutes := router.Group("/api/admin/import")
+	adminRoutes.Use(auth, middleware.RequireAdmin())
+	{
+		adminRoutes.POST("/activities", ic.ImportActivities)
+	}
+}
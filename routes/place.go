@@ -6,27 +6,45 @@ import (
 	"github.com/laurawarren88/go_spa_backend.git/middleware"
 )
 
-func RegisterPlaceRoutes(router *gin.Engine, pc *controllers.PlaceController) {
-	router.GET("/api/activities/:id/check-ownership", middleware.AuthMiddleware(), pc.CheckActivityOwnership)
+func RegisterPlaceRoutes(router *gin.Engine, pc *controllers.PlaceController, auth gin.HandlerFunc) {
+	router.GET("/api/activities/:id/check-ownership", auth, middleware.ResolveActivity(), pc.CheckActivityOwnership)
 
 	placeRoutes := router.Group("/api/activities")
+	placeRoutes.Use(middleware.ResolveActivity())
 	{
 		placeRoutes.GET("/locator", pc.GetPlaceLocator)
+		placeRoutes.GET("/export", pc.ExportActivities)
 		placeRoutes.GET("/:id", pc.GetActivityById)
+		placeRoutes.GET("/:id/prices", pc.GetPrices)
+		placeRoutes.GET("/:id/images", pc.GetImages)
 	}
 
 	protected := router.Group("/api/activities")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(auth)
 	{
 		protected.GET("/new", pc.RenderCreateActivityForm)
 		protected.POST("/new", pc.CreateActivity)
 	}
 	userRoutes := router.Group("/api/activities")
-	userRoutes.Use(middleware.AuthMiddleware(), middleware.ActivityOwner())
+	userRoutes.Use(auth, middleware.ActivityOwner())
 	{
 		userRoutes.GET("/:id/edit", pc.RenderEditActivityForm)
 		userRoutes.PUT("/:id/edit", pc.UpdateActivity)
+		userRoutes.PATCH("/:id", pc.UpdateActivity)
 		userRoutes.GET("/:id/delete", pc.RenderDeleteActivityForm)
 		userRoutes.DELETE("/:id/delete", pc.DeleteActivity)
+		userRoutes.GET("/:id/revisions", pc.GetRevisions)
+		userRoutes.POST("/:id/revisions/:revisionId/revert", pc.RevertActivity)
+		userRoutes.PUT("/:id/prices", pc.UpdatePrices)
+		userRoutes.POST("/:id/images", pc.UploadImages)
+		userRoutes.PUT("/:id/images/order", pc.ReorderImages)
+		userRoutes.PUT("/:id/images/:imageId", pc.UpdateImage)
+		userRoutes.DELETE("/:id/images/:imageId", pc.DeleteImage)
+	}
+
+	adminRoutes := router.Group("/api/admin/activities")
+	adminRoutes.Use(auth, middleware.RequireAdmin())
+	{
+		adminRoutes.POST("/:id/merge", pc.MergeActivity)
 	}
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/middleware"
+)
+
+func RegisterTrashRoutes(router *gin.Engine, tc *controllers.TrashController, auth gin.HandlerFunc) {
+	adminRoutes := router.Group("/api/admin/trash/activities")
+	adminRoutes.Use(auth, middleware.RequireAdmin())
+	{
+		adminRoutes.GET("", tc.GetTrash)
+		adminRoutes.POST("/:id/restore", tc.RestoreActivity)
+		adminRoutes.DELETE("/:id", tc.PurgeActivity)
+	}
+}
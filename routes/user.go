@@ -3,10 +3,9 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/laurawarren88/go_spa_backend.git/controllers"
-	"github.com/laurawarren88/go_spa_backend.git/middleware"
 )
 
-func RegisterUserRoutes(router *gin.Engine, uc *controllers.UserController) {
+func RegisterUserRoutes(router *gin.Engine, uc *controllers.UserController, auth gin.HandlerFunc) {
 	userRoutes := router.Group("/api/users")
 	{
 		userRoutes.GET("/register", uc.GetSignupForm)
@@ -18,7 +17,7 @@ func RegisterUserRoutes(router *gin.Engine, uc *controllers.UserController) {
 	}
 
 	protected := router.Group("/api/users")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(auth)
 	{
 		protected.GET("/profile/:id", uc.GetProfile)
 		protected.POST("/logout", uc.LogoutUser)
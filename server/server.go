@@ -0,0 +1,56 @@
+// Package server builds the API's gin router from the configuration.
+package server
+
+import (
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/laurawarren88/go_spa_backend.git/config"
+	"github.com/laurawarren88/go_spa_backend.git/controllers"
+	"github.com/laurawarren88/go_spa_backend.git/middleware"
+	"github.com/laurawarren88/go_spa_backend.git/routes"
+	"github.com/laurawarren88/go_spa_backend.git/storage"
+	"gorm.io/gorm"
+)
+
+func SetupServer(cfg *config.Config, db *gorm.DB, store storage.BlobStore) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.Default()
+	// Uploads kept on local disk are served by the API itself.
+	if local, ok := store.(*storage.Local); ok {
+		prefix := "/uploads"
+		if u, err := url.Parse(local.BaseURL); err == nil && u.Path != "" {
+			prefix = u.Path
+		}
+		router.Static(prefix, local.Root)
+	}
+	router.Use(middleware.DBMiddleware(db))
+	router.Use(middleware.CORSMiddleware(cfg.CORS))
+	return router
+}
+
+func SetupHandlers(router *gin.Engine, cfg *config.Config, db *gorm.DB, store storage.BlobStore) {
+	homeController := controllers.NewHomeController(db)
+	placeController := controllers.NewPlaceController(db, store)
+	userController := controllers.NewUserController(db, cfg.Auth, cfg.Cookies)
+	classController := controllers.NewClassController(db)
+	bookingController := controllers.NewBookingController(db)
+	calendarController := controllers.NewCalendarController(db)
+	eventController := controllers.NewEventController(db, store)
+	amenityController := controllers.NewAmenityController(db)
+	trashController := controllers.NewTrashController(db, store, cfg.Jobs.PlaceRetention)
+	importController := controllers.NewImportController(db)
+
+	auth := middleware.AuthMiddleware(cfg.Auth, cfg.Cookies)
+
+	routes.RegisterHomeRoutes(router, homeController)
+	routes.RegisterPlaceRoutes(router, placeController, auth)
+	routes.RegisterUserRoutes(router, userController, auth)
+	routes.RegisterClassRoutes(router, classController, auth)
+	routes.RegisterBookingRoutes(router, bookingController, auth)
+	routes.RegisterCalendarRoutes(router, calendarController, auth)
+	routes.RegisterEventRoutes(router, eventController, auth)
+	routes.RegisterAmenityRoutes(router, amenityController, auth)
+	routes.RegisterTrashRoutes(router, trashController, auth)
+	routes.RegisterImportRoutes(router, importController, auth)
+}
@@ -0,0 +1,195 @@
+// Package slug gives places readable, unique URL slugs such as
+// "puregym-manchester-central", and keeps the slugs places used before they
+// were renamed so that old links can be redirected.
+package slug
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+const maxLength = 80
+
+// ErrTaken reports that another place took a slug between Assign choosing it
+// and the place being saved. Trying the change again picks a free slug.
+var ErrTaken = errors.New("another activity has just taken this name, please try again")
+
+// reserved are path segments under /api/activities that a slug must not
+// shadow.
+var reserved = map[string]bool{"new": true, "locator": true, "export": true}
+
+// Make joins parts into a slug: lower case ASCII letters and digits separated
+// by single hyphens, with accents removed. It never returns an empty slug, a
+// reserved word or a number, which would be read as an ID.
+func Make(parts ...string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range norm.NFD.String(strings.ToLower(strings.Join(parts, " "))) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		case r == '\'' || r == '’':
+			// "Jo's Gym" becomes "jos-gym".
+		default:
+			hyphen = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > maxLength {
+		slug = slug[:maxLength]
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+	}
+	switch {
+	case slug == "":
+		return "activity"
+	case reserved[slug] || IsID(slug):
+		return slug + "-activity"
+	}
+	return slug
+}
+
+// IsID reports whether value is a numeric ID rather than a slug.
+func IsID(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Assign sets place.Slug from its name and city, adding a number when another
+// place has used the slug. A place keeps its slug while its name and city
+// still produce it, numbered slugs included as long as the lower numbers are
+// still taken. When a place with an ID changes slug the old one is recorded
+// so it keeps redirecting. The caller saves place.
+func Assign(tx *gorm.DB, place *models.Place) error {
+	base := Make(place.Name, place.City)
+	if place.Slug == base {
+		return nil
+	}
+
+	candidate := base
+	for n := 2; ; n++ {
+		taken, err := taken(tx, candidate, place.ID)
+		if err != nil {
+			return err
+		}
+		if !taken {
+			break
+		}
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+	if candidate == place.Slug {
+		return nil
+	}
+
+	if place.ID != 0 {
+		// Taking back a slug used before removes it from the history.
+		if err := tx.Where("place_id = ? AND slug = ?", place.ID, candidate).Delete(&models.PlaceSlug{}).Error; err != nil {
+			return err
+		}
+		if place.Slug != "" {
+			if err := tx.Create(&models.PlaceSlug{PlaceID: place.ID, Slug: place.Slug}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	place.Slug = candidate
+	return nil
+}
+
+// taken reports whether a place other than placeID uses or used slug.
+func taken(tx *gorm.DB, slug string, placeID uint) (bool, error) {
+	var places, history int64
+	if err := tx.Unscoped().Model(&models.Place{}).Where("slug = ? AND id <> ?", slug, placeID).Count(&places).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&models.PlaceSlug{}).Where("slug = ? AND place_id <> ?", slug, placeID).Count(&history).Error; err != nil {
+		return false, err
+	}
+	return places+history > 0, nil
+}
+
+// Conflict returns ErrTaken when err, from saving a place after Assign, is a
+// unique index violation, and err otherwise.
+func Conflict(tx *gorm.DB, err error) error {
+	if translator, ok := tx.Dialector.(gorm.ErrorTranslator); ok && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+		return ErrTaken
+	}
+	return err
+}
+
+// Backfill assigns slugs to the places saved before slugs existed and returns
+// how many were updated.
+func Backfill(db *gorm.DB) (int, error) {
+	var places []models.Place
+	if err := db.Unscoped().Where("slug = '' OR slug IS NULL").Order("id").Find(&places).Error; err != nil {
+		return 0, err
+	}
+	for i := range places {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := Assign(tx, &places[i]); err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&places[i]).UpdateColumn("slug", places[i].Slug).Error
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(places), nil
+}
+
+// Resolve finds the place a slug or ID refers to. A live place is returned
+// with moved set to false. A slug the place used before a rename, or the ID of
+// a place that was merged into another, returns the place it now refers to
+// with moved set. It returns gorm.ErrRecordNotFound when nothing matches.
+func Resolve(db *gorm.DB, value string) (place models.Place, moved bool, err error) {
+	if IsID(value) {
+		err = db.Limit(1).Find(&place, "id = ?", value).Error
+		if err != nil || place.ID != 0 {
+			return place, false, err
+		}
+		var redirect models.PlaceRedirect
+		if err = db.Limit(1).Find(&redirect, "from_id = ?", value).Error; err != nil {
+			return place, false, err
+		}
+		if redirect.ToID == 0 {
+			return place, false, gorm.ErrRecordNotFound
+		}
+		err = db.First(&place, redirect.ToID).Error
+		return place, true, err
+	}
+
+	err = db.Limit(1).Find(&place, "slug = ?", value).Error
+	if err != nil || place.ID != 0 {
+		return place, false, err
+	}
+	var old models.PlaceSlug
+	if err = db.Limit(1).Find(&old, "slug = ?", value).Error; err != nil {
+		return place, false, err
+	}
+	if old.PlaceID == 0 {
+		return place, false, gorm.ErrRecordNotFound
+	}
+	err = db.First(&place, old.PlaceID).Error
+	return place, true, err
+}
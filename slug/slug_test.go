@@ -0,0 +1,144 @@
+package slug_test
+
+import (
+	"testing"
+
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/laurawarren88/go_spa_backend.git/slug"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMake(t *testing.T) {
+	assert.Equal(t, "puregym-manchester-central", slug.Make("PureGym", "Manchester Central"))
+	assert.Equal(t, "jos-cafe-gym-leeds", slug.Make("  Jo's Café & Gym!", "Leeds"))
+	assert.Equal(t, "activity", slug.Make("???", ""))
+	assert.Equal(t, "new-activity", slug.Make("New"))
+	assert.Equal(t, "24-7", slug.Make("24/7", ""))
+	assert.Equal(t, "24-activity", slug.Make("24", ""))
+	long := slug.Make("a very long name that goes on and on and on well past the limit we allow for slugs")
+	assert.LessOrEqual(t, len(long), 80)
+	assert.NotEqual(t, '-', rune(long[len(long)-1]))
+}
+
+func TestAssignAndResolve(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.Place{}, &models.PlaceSlug{}, &models.PlaceRedirect{}))
+
+	create := func(name, city string) models.Place {
+		place := models.Place{Name: name, City: city}
+		assert.NoError(t, slug.Assign(db, &place))
+		assert.NoError(t, db.Create(&place).Error)
+		return place
+	}
+	first := create("Harbour Gym", "Salford")
+	second := create("Harbour Gym", "Salford")
+	assert.Equal(t, "harbour-gym-salford", first.Slug)
+	assert.Equal(t, "harbour-gym-salford-2", second.Slug)
+
+	// Saving again without a rename keeps the numbered slug.
+	assert.NoError(t, slug.Assign(db, &second))
+	assert.Equal(t, "harbour-gym-salford-2", second.Slug)
+
+	first.Name = "Quay Gym"
+	assert.NoError(t, slug.Assign(db, &first))
+	assert.NoError(t, db.Save(&first).Error)
+	assert.Equal(t, "quay-gym-salford", first.Slug)
+
+	// The old slug stays with the renamed place rather than being reused.
+	third := create("Harbour Gym", "Salford")
+	assert.Equal(t, "harbour-gym-salford-3", third.Slug)
+
+	place, moved, err := slug.Resolve(db, "quay-gym-salford")
+	assert.NoError(t, err)
+	assert.False(t, moved)
+	assert.Equal(t, first.ID, place.ID)
+
+	place, moved, err = slug.Resolve(db, "harbour-gym-salford")
+	assert.NoError(t, err)
+	assert.True(t, moved)
+	assert.Equal(t, "quay-gym-salford", place.Slug)
+
+	// Renaming back takes the old slug out of the history.
+	first.Name = "Harbour Gym"
+	assert.NoError(t, slug.Assign(db, &first))
+	assert.Equal(t, "harbour-gym-salford", first.Slug)
+	var history []string
+	db.Model(&models.PlaceSlug{}).Pluck("slug", &history)
+	assert.Equal(t, []string{"quay-gym-salford"}, history)
+
+	assert.NoError(t, db.Create(&models.PlaceRedirect{FromID: 99, ToID: second.ID}).Error)
+	place, moved, err = slug.Resolve(db, "99")
+	assert.NoError(t, err)
+	assert.True(t, moved)
+	assert.Equal(t, second.ID, place.ID)
+
+	_, _, err = slug.Resolve(db, "nowhere")
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	_, _, err = slug.Resolve(db, "98")
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+}
+
+func TestAssignRenameToNumberedName(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.Place{}, &models.PlaceSlug{}))
+
+	create := func(name string) models.Place {
+		place := models.Place{Name: name}
+		assert.NoError(t, slug.Assign(db, &place))
+		assert.NoError(t, db.Create(&place).Error)
+		return place
+	}
+	rename := func(place *models.Place, name string) {
+		place.Name = name
+		assert.NoError(t, slug.Assign(db, place))
+		assert.NoError(t, db.Save(place).Error)
+	}
+
+	// A name ending in a number is not a numbered copy of the shorter name.
+	studio := create("Studio 5")
+	assert.Equal(t, "studio-5", studio.Slug)
+	rename(&studio, "Studio")
+	assert.Equal(t, "studio", studio.Slug)
+	gym := create("Gym 24")
+	rename(&gym, "Gym")
+	assert.Equal(t, "gym", gym.Slug)
+
+	var history []string
+	db.Model(&models.PlaceSlug{}).Order("id").Pluck("slug", &history)
+	assert.Equal(t, []string{"studio-5", "gym-24"}, history)
+
+	// A numbered slug is kept while every lower one is taken.
+	other := create("Gym")
+	assert.Equal(t, "gym-2", other.Slug)
+	rename(&other, "GYM")
+	assert.Equal(t, "gym-2", other.Slug)
+}
+
+func TestConflict(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.Place{}, &models.PlaceSlug{}))
+	assert.NoError(t, db.Create(&models.Place{Name: "Gym", Slug: "gym"}).Error)
+
+	err = db.Create(&models.Place{Name: "Gym", Slug: "gym"}).Error
+	assert.ErrorIs(t, slug.Conflict(db, err), slug.ErrTaken)
+	assert.Equal(t, gorm.ErrRecordNotFound, slug.Conflict(db, gorm.ErrRecordNotFound))
+}
+
+func TestBackfill(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.Place{}, &models.PlaceSlug{}))
+	assert.NoError(t, db.Create(&[]models.Place{{Name: "Gym"}, {Name: "Gym"}, {Name: "Pool", Slug: "pool"}}).Error)
+
+	count, err := slug.Backfill(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	var slugs []string
+	db.Model(&models.Place{}).Order("id").Pluck("slug", &slugs)
+	assert.Equal(t, []string{"gym", "gym-2", "pool"}, slugs)
+}
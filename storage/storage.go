@@ -0,0 +1,54 @@
+// Package storage abstracts where uploaded media lives. Controllers store and
+// remove blobs by key (for example "gallery/3f2a.jpg") and ask the store for
+// the public URL to hand to clients, so the same code works against the local
+// filesystem or an S3-compatible bucket.
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+type BlobStore interface {
+	// Put stores the contents of r under key, replacing any existing blob.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Delete removes the blob stored under key. Deleting a missing key is not
+	// an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the address clients should use to fetch key.
+	URL(key string) string
+	// List returns every blob whose key starts with prefix.
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// NormalizeKey turns a stored media reference into a store key. Older rows
+// hold filesystem paths such as "./uploads/logos/1700000000_logo.png"; those
+// map to "logos/1700000000_logo.png".
+func NormalizeKey(path string) string {
+	key := strings.TrimPrefix(path, "./")
+	key = strings.TrimPrefix(key, "/")
+	key = strings.TrimPrefix(key, "uploads/")
+	return key
+}
+
+// IsExternal reports whether ref is an absolute URL rather than a key, as
+// happens when clients submit a link to an image hosted elsewhere.
+func IsExternal(ref string) bool {
+	return strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://")
+}
+
+// PublicURL returns the URL for ref, leaving external URLs untouched.
+func PublicURL(store BlobStore, ref string) string {
+	if ref == "" || IsExternal(ref) {
+		return ref
+	}
+	return store.URL(NormalizeKey(ref))
+}
@@ -0,0 +1,160 @@
+package validation
+
+import (
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/laurawarren88/go_spa_backend.git/models"
+)
+
+var (
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "")
+	ukPhonePattern  = regexp.MustCompile(`^(?:\+44|0044|0)([1-9]\d{8,9})$`)
+	postcodePattern = regexp.MustCompile(`^([A-Z]{1,2}\d[A-Z\d]?)\s*(\d[A-Z]{2})$`)
+)
+
+// Field lengths mirror the gorm size tags on models.Place.
+var placeFieldLimits = []struct {
+	field string
+	get   func(*models.Place) *string
+	limit int
+}{
+	{"name", func(p *models.Place) *string { return &p.Name }, 255},
+	{"vicinity", func(p *models.Place) *string { return &p.Vicinity }, 255},
+	{"city", func(p *models.Place) *string { return &p.City }, 100},
+	{"postcode", func(p *models.Place) *string { return &p.Postcode }, 20},
+	{"phone", func(p *models.Place) *string { return &p.Phone }, 15},
+	{"email", func(p *models.Place) *string { return &p.Email }, 100},
+	{"website", func(p *models.Place) *string { return &p.Website }, 255},
+	{"description", func(p *models.Place) *string { return &p.Description }, 255},
+	{"logo", func(p *models.Place) *string { return &p.Logo }, 255},
+	{"facilities_image", func(p *models.Place) *string { return &p.FacilitiesImage }, 255},
+}
+
+// NormalizePhone returns a UK phone number in E.164 form, e.g. "+441610000000".
+func NormalizePhone(phone string) (string, bool) {
+	match := ukPhonePattern.FindStringSubmatch(phoneSeparators.Replace(phone))
+	if match == nil {
+		return phone, false
+	}
+	return "+44" + match[1], true
+}
+
+// NormalizePostcode returns a UK postcode in upper case with a single space
+// before the inward code, e.g. "M1 1AA".
+func NormalizePostcode(postcode string) (string, bool) {
+	match := postcodePattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(postcode)))
+	if match == nil {
+		return postcode, false
+	}
+	return match[1] + " " + match[2], true
+}
+
+// Place normalizes place in place (trimming text, formatting the phone number
+// and postcode) and checks it, returning every problem found.
+func Place(place *models.Place) error {
+	var errs Errors
+
+	for _, f := range placeFieldLimits {
+		value := f.get(place)
+		*value = strings.TrimSpace(*value)
+	}
+
+	if place.Name == "" {
+		errs.Add("name", CodeRequired, "name is required")
+	}
+	if place.Description == "" {
+		errs.Add("description", CodeRequired, "description is required")
+	}
+
+	if place.Phone == "" {
+		errs.Add("phone", CodeRequired, "phone is required")
+	} else if phone, ok := NormalizePhone(place.Phone); ok {
+		place.Phone = phone
+	} else {
+		errs.Add("phone", CodeInvalidPhone, "phone must be a UK phone number")
+	}
+
+	if place.Postcode != "" {
+		if postcode, ok := NormalizePostcode(place.Postcode); ok {
+			place.Postcode = postcode
+		} else {
+			errs.Add("postcode", CodeInvalidPostcode, "postcode must be a UK postcode")
+		}
+	}
+
+	if place.Email != "" {
+		address, err := mail.ParseAddress(place.Email)
+		if err != nil || address.Address != place.Email {
+			errs.Add("email", CodeInvalidEmail, "email must be a valid email address")
+		}
+	}
+
+	if place.Website != "" && !isWebURL(place.Website) {
+		errs.Add("website", CodeInvalidURL, "website must be an http or https URL")
+	}
+
+	if !isFinite(place.Latitude) || place.Latitude < -90 || place.Latitude > 90 {
+		errs.Add("latitude", CodeOutOfRange, "latitude must be between -90 and 90")
+	}
+	if !isFinite(place.Longitude) || place.Longitude < -180 || place.Longitude > 180 {
+		errs.Add("longitude", CodeOutOfRange, "longitude must be between -180 and 180")
+	}
+
+	for _, f := range placeFieldLimits {
+		if utf8.RuneCountInString(*f.get(place)) > f.limit {
+			errs.Add(f.field, CodeTooLong, "%s must be at most %d characters", f.field, f.limit)
+		}
+	}
+
+	return errs.Err()
+}
+
+// ImportedPlace is Place for venues from outside sources such as
+// OpenStreetMap, which often have no phone number or postcode. An unusable
+// phone number or postcode is left blank instead of rejecting the venue.
+func ImportedPlace(place *models.Place) error {
+	errs, _ := Place(place).(Errors)
+	var kept Errors
+	for _, err := range errs {
+		switch err.Field {
+		case "phone":
+			place.Phone = ""
+		case "postcode":
+			place.Postcode = ""
+		default:
+			kept = append(kept, err)
+		}
+	}
+	return kept.Err()
+}
+
+// PlaceChange is Place for edits to original. A place saved without a phone
+// number, such as a venue imported from OpenStreetMap, can be edited without
+// adding one, but a phone number that is set cannot be cleared.
+func PlaceChange(original models.Place, place *models.Place) error {
+	errs, _ := Place(place).(Errors)
+	if original.Phone != "" {
+		return errs.Err()
+	}
+	var kept Errors
+	for _, err := range errs {
+		if err.Field != "phone" || err.Code != CodeRequired {
+			kept = append(kept, err)
+		}
+	}
+	return kept.Err()
+}
+
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+func isWebURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,110 @@
+package validation
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/laurawarren88/go_spa_backend.git/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func codes(err error) map[string]string {
+	var errs Errors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+	found := map[string]string{}
+	for _, e := range errs {
+		found[e.Field] = e.Code
+	}
+	return found
+}
+
+func TestPlaceNormalizesValidInput(t *testing.T) {
+	place := models.Place{
+		Name:        "  Riverside Gym ",
+		Description: "Gym",
+		Phone:       "(0161) 496-0000",
+		Postcode:    "m11aa",
+		Email:       "hello@riverside.example",
+		Website:     "https://riverside.example",
+		Latitude:    0,
+		Longitude:   -0.12,
+	}
+	assert.NoError(t, Place(&place))
+	assert.Equal(t, "Riverside Gym", place.Name)
+	assert.Equal(t, "+441614960000", place.Phone)
+	assert.Equal(t, "M1 1AA", place.Postcode)
+
+	place.Phone = "+44 20 7946 0000"
+	place.Postcode = "SW1A 1AA"
+	assert.NoError(t, Place(&place))
+	assert.Equal(t, "+442079460000", place.Phone)
+}
+
+func TestPlaceReportsFieldErrors(t *testing.T) {
+	place := models.Place{
+		Name:      strings.Repeat("x", 256),
+		Phone:     "1234567890",
+		Postcode:  "12345",
+		Email:     "Gym <gym@example.com>",
+		Website:   "javascript:alert(1)",
+		City:      strings.Repeat("é", 101),
+		Latitude:  91,
+		Longitude: -181,
+	}
+	assert.Equal(t, map[string]string{
+		"name":        CodeTooLong,
+		"description": CodeRequired,
+		"phone":       CodeInvalidPhone,
+		"postcode":    CodeInvalidPostcode,
+		"email":       CodeInvalidEmail,
+		"website":     CodeInvalidURL,
+		"city":        CodeTooLong,
+		"latitude":    CodeOutOfRange,
+		"longitude":   CodeOutOfRange,
+	}, codes(Place(&place)))
+
+	for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		place := models.Place{Name: "Gym", Description: "Gym", Phone: "07700 900000", Latitude: value, Longitude: value}
+		assert.Equal(t, map[string]string{
+			"latitude":  CodeOutOfRange,
+			"longitude": CodeOutOfRange,
+		}, codes(Place(&place)), value)
+	}
+
+	city := models.Place{Name: "Gym", Description: "Gym", Phone: "07700 900000", City: strings.Repeat("é", 100)}
+	assert.NoError(t, Place(&city))
+}
+
+func TestImportedPlaceBlanksPhoneAndPostcode(t *testing.T) {
+	place := models.Place{Name: "Gym", Description: "Gym", Phone: "12345", Postcode: "M3"}
+	assert.NoError(t, ImportedPlace(&place))
+	assert.Equal(t, "", place.Phone)
+	assert.Equal(t, "", place.Postcode)
+
+	place = models.Place{Name: "Gym", Phone: "0161 496 0000", Postcode: "m3 3aa", Website: "ftp://gym.example"}
+	assert.Equal(t, map[string]string{
+		"description": CodeRequired,
+		"website":     CodeInvalidURL,
+	}, codes(ImportedPlace(&place)))
+	assert.Equal(t, "+441614960000", place.Phone)
+	assert.Equal(t, "M3 3AA", place.Postcode)
+}
+
+func TestPlaceChangeKeepsMissingPhone(t *testing.T) {
+	imported := models.Place{Name: "Harbour Gym", Description: "Gym"}
+	edited := imported
+	edited.Name = "Harbour Gym & Spa"
+	assert.NoError(t, PlaceChange(imported, &edited))
+
+	edited.Phone = "not a phone"
+	assert.Equal(t, map[string]string{"phone": CodeInvalidPhone}, codes(PlaceChange(imported, &edited)))
+
+	withPhone := models.Place{Name: "Harbour Gym", Description: "Gym", Phone: "+441614960000"}
+	cleared := withPhone
+	cleared.Phone = ""
+	assert.Equal(t, map[string]string{"phone": CodeRequired}, codes(PlaceChange(withPhone, &cleared)))
+}
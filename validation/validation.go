@@ -0,0 +1,63 @@
+// Package validation checks places before they are written, whether they come
+// from the API, an edit or a bulk import, and reports problems per field so
+// clients can show them next to the right input.
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	CodeRequired        = "required"
+	CodeTooLong         = "too_long"
+	CodeOutOfRange      = "out_of_range"
+	CodeInvalidNumber   = "invalid_number"
+	CodeInvalidPhone    = "invalid_phone"
+	CodeInvalidPostcode = "invalid_postcode"
+	CodeInvalidEmail    = "invalid_email"
+	CodeInvalidURL      = "invalid_url"
+	CodeInvalidType     = "invalid_type"
+	CodeReadOnly        = "read_only"
+	CodeUnknownValue    = "unknown_value"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors collects every problem found rather than stopping at the first.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, err := range e {
+		parts[i] = err.Field + ": " + err.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *Errors) Add(field, code, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Has reports whether a problem has already been recorded for field.
+func (e Errors) Has(field string) bool {
+	for _, err := range e {
+		if err.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns nil when no problems were found, so callers can write
+// `if err := errs.Err(); err != nil`.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}